@@ -0,0 +1,337 @@
+package alerts
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/jsonfile"
+	"aggregator/internal/service"
+	"aggregator/internal/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventPriceBelow is the webhook event sent when the lowest price of an alert drops below its threshold.
+const EventPriceBelow = "price.below"
+
+var (
+	// ErrAlertNotFound is returned when no alert has the given id.
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrInvalidAlert is returned when an alert is incomplete or malformed.
+	ErrInvalidAlert = errors.New("invalid alert")
+)
+
+// Input is the body of a request creating or replacing an alert.
+type Input struct {
+	Route    string  `json:"route"`
+	Date     string  `json:"date"`
+	Below    float64 `json:"below"`
+	Currency string  `json:"currency"`
+	URL      string  `json:"url"`
+	Secret   string  `json:"secret"`
+}
+
+// Alert asks for a webhook when the lowest price of a route, optionally on one departure date, drops below a threshold.
+type Alert struct {
+	ID    string `json:"id"`
+	Route string `json:"route"`
+	// Date is the departure date in the local time of the origin; empty means any date.
+	Date     string  `json:"date,omitempty"`
+	Below    float64 `json:"below"`
+	Currency string  `json:"currency"`
+	URL      string  `json:"url"`
+	// Secret signs the webhooks of the alert. It is only shown when the alert is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Triggered is set while the lowest price stays below the threshold, so that a drop is notified once;
+	// the alert is armed again once the price is back at or above it, or when its webhook becomes a dead letter.
+	Triggered       bool       `json:"triggered"`
+	LastPrice       *float64   `json:"lastPrice,omitempty"`
+	LastTriggeredAt *time.Time `json:"lastTriggeredAt,omitempty"`
+	// LastDeliveryID is the webhook delivery of the latest trigger.
+	LastDeliveryID string `json:"lastDeliveryId,omitempty"`
+}
+
+// Redacted returns the alert without its secret.
+func (a Alert) Redacted() Alert {
+	a.Secret = ""
+	return a
+}
+
+// Notification is the body of the webhook of a triggered alert.
+type Notification struct {
+	Event    string                `json:"event"`
+	AlertID  string                `json:"alertId"`
+	Route    string                `json:"route"`
+	Date     string                `json:"date,omitempty"`
+	Below    float64               `json:"below"`
+	Currency string                `json:"currency"`
+	Price    float64               `json:"price"`
+	Flight   domain.FlightSnapshot `json:"flight"`
+	Version  uint64                `json:"version"`
+	At       time.Time             `json:"at"`
+}
+
+// ParseAlert validates an input and returns the alert it describes, without id nor secret.
+// The currency defaults to the base currency.
+func ParseAlert(in Input) (Alert, error) {
+	watch, err := service.ParseWatch(service.Watch{Route: in.Route, Date: in.Date})
+	if err != nil {
+		return Alert{}, fmt.Errorf("%w: %w", ErrInvalidAlert, err)
+	}
+	a := Alert{Route: watch.Route, Date: watch.Date, Below: in.Below, Currency: strings.ToUpper(strings.TrimSpace(in.Currency)),
+		URL: strings.TrimSpace(in.URL), Secret: in.Secret}
+	if a.Currency == "" {
+		a.Currency = service.BaseCurrency
+	}
+	if !service.IsKnownCurrency(a.Currency) {
+		return Alert{}, fmt.Errorf("%w: %w: %s", ErrInvalidAlert, service.ErrUnknownCurrency, a.Currency)
+	}
+	if math.IsNaN(a.Below) || a.Below <= 0 {
+		return Alert{}, fmt.Errorf("%w: below must be a positive price", ErrInvalidAlert)
+	}
+	if err := webhook.CheckURL(a.URL); err != nil {
+		return Alert{}, fmt.Errorf("%w: %w", ErrInvalidAlert, err)
+	}
+	return a, nil
+}
+
+// Store keeps the alerts in memory and, when it has a file, saves them to it as a JSON array after every change.
+type Store struct {
+	mu         sync.Mutex
+	path       string
+	alerts     map[string]Alert
+	dispatcher *webhook.Dispatcher
+}
+
+// Default is the store of the server. It keeps alerts in memory until main opens the alerts file.
+var Default = New(webhook.Default)
+
+// New creates an empty store kept in memory only, delivering its webhooks through the dispatcher.
+func New(d *webhook.Dispatcher) *Store {
+	return &Store{alerts: make(map[string]Alert), dispatcher: d}
+}
+
+// Open loads the alerts of a JSON file, if it exists, and returns a store saving to it.
+func Open(path string, d *webhook.Dispatcher) (*Store, error) {
+	s := New(d)
+	s.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read alerts: %w", err)
+	}
+	var list []Alert
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("decode alerts %s: %w", path, err)
+	}
+	for _, a := range list {
+		s.alerts[a.ID] = a
+	}
+	return s, nil
+}
+
+// Create stores a new alert, generating its id and, when missing, its secret.
+func (s *Store) Create(a Alert) (Alert, error) {
+	a.ID, a.CreatedAt = webhook.NewID(), time.Now().UTC()
+	if a.Secret == "" {
+		a.Secret = webhook.NewSecret()
+	}
+	a.Triggered, a.LastPrice, a.LastTriggeredAt, a.LastDeliveryID = false, nil, nil, ""
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts[a.ID] = a
+	if err := s.save(); err != nil {
+		delete(s.alerts, a.ID)
+		return Alert{}, err
+	}
+	return a, nil
+}
+
+// List returns the alerts, oldest first.
+func (s *Store) List() []Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted()
+}
+
+// sorted returns the alerts by creation time then id. The caller holds the lock.
+func (s *Store) sorted() []Alert {
+	out := make([]Alert, 0, len(s.alerts))
+	for _, a := range s.alerts {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Len returns the number of alerts.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.alerts)
+}
+
+// Get returns the alert with the given id.
+func (s *Store) Get(id string) (Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.alerts[id]
+	if !ok {
+		return Alert{}, fmt.Errorf("%w: %s", ErrAlertNotFound, id)
+	}
+	return a, nil
+}
+
+// Update replaces the criteria and URL of an alert, keeping its secret when none is given, and arms it again.
+func (s *Store) Update(id string, a Alert) (Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.alerts[id]
+	if !ok {
+		return Alert{}, fmt.Errorf("%w: %s", ErrAlertNotFound, id)
+	}
+	a.ID, a.CreatedAt = old.ID, old.CreatedAt
+	if a.Secret == "" {
+		a.Secret = old.Secret
+	}
+	a.Triggered, a.LastPrice, a.LastTriggeredAt, a.LastDeliveryID = false, nil, nil, ""
+	s.alerts[id] = a
+	if err := s.save(); err != nil {
+		s.alerts[id] = old
+		return Alert{}, err
+	}
+	return a, nil
+}
+
+// Delete removes an alert.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.alerts[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAlertNotFound, id)
+	}
+	delete(s.alerts, id)
+	if err := s.save(); err != nil {
+		s.alerts[id] = old
+		return err
+	}
+	return nil
+}
+
+// Record is a catalogue.Listener evaluating every alert against the new version and sending a webhook for each one
+// whose lowest price dropped below its threshold.
+func (s *Store) Record(_, next catalogue.Version) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for _, a := range s.sorted() {
+		price, flight, ok := lowest(a, next.Flights)
+		below := ok && price < a.Below
+		var last *float64
+		if ok {
+			last = &price
+		}
+		if (last == nil) != (a.LastPrice == nil) || last != nil && *last != *a.LastPrice {
+			a.LastPrice, changed = last, true
+		}
+		if below && !a.Triggered {
+			notification := Notification{
+				Event: EventPriceBelow, AlertID: a.ID, Route: a.Route, Date: a.Date, Below: a.Below, Currency: a.Currency,
+				Price: price, Flight: flight.Snapshot(), Version: next.Number, At: next.RefreshedAt,
+			}
+			delivery, err := s.dispatcher.Send(a.ID, EventPriceBelow, a.URL, a.Secret, notification)
+			if err != nil {
+				// the alert stays armed and is evaluated again on the next refresh
+				fmt.Fprintln(os.Stderr, "alerts:", err)
+				below = false
+			} else {
+				at := time.Now().UTC()
+				a.LastTriggeredAt, a.LastDeliveryID, changed = &at, delivery.ID, true
+			}
+		}
+		if a.Triggered != below {
+			a.Triggered, changed = below, true
+		}
+		s.alerts[a.ID] = a
+	}
+	if changed {
+		if err := s.save(); err != nil {
+			fmt.Fprintln(os.Stderr, "alerts:", err)
+		}
+	}
+}
+
+// Rearm is a dead letter listener, subscribed by main to the dispatcher of the store, arming again the alert whose
+// latest webhook failed, so that the drop is notified again on the next refresh where the price is still below the
+// threshold.
+func (s *Store) Rearm(delivery webhook.Delivery) {
+	if delivery.Event != EventPriceBelow {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.alerts[delivery.Owner]
+	if !ok || !a.Triggered || a.LastDeliveryID != delivery.ID {
+		return
+	}
+	a.Triggered = false
+	s.alerts[a.ID] = a
+	if err := s.save(); err != nil {
+		fmt.Fprintln(os.Stderr, "alerts:", err)
+	}
+}
+
+// lowest returns the cheapest booking of an alert, with its price converted into the alert currency and rounded to
+// cents. Equal prices go to the default ordering; bookings in unknown currencies are skipped.
+func lowest(a Alert, flights domain.Flights) (float64, domain.Flight, bool) {
+	watch := service.Watch{Route: a.Route, Date: a.Date}
+	matched := make(domain.Flights, 0)
+	for _, f := range flights {
+		if watch.Match(f) {
+			matched = append(matched, f)
+		}
+	}
+	service.DefaultOrdering.Sort(matched)
+
+	var best domain.Flight
+	price, found := 0.0, false
+	for _, f := range matched {
+		amount, err := service.ConvertAmount(f.Total().Amount(), f.Total().Currency(), a.Currency)
+		if err != nil {
+			continue
+		}
+		amount = math.Round(amount*100) / 100
+		if !found || amount < price {
+			price, best, found = amount, f, true
+		}
+	}
+	return price, best, found
+}
+
+// save writes the alerts to the file, if any. The caller holds the lock.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := jsonfile.Write(s.path, s.sorted()); err != nil {
+		return fmt.Errorf("save alerts: %w", err)
+	}
+	return nil
+}
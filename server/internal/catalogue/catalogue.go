@@ -0,0 +1,122 @@
+package catalogue
+
+import (
+	"aggregator/internal/domain"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Version is an immutable state of the aggregated catalogue.
+// Number increases by one every time the aggregated flights change.
+type Version struct {
+	Number      uint64
+	Digest      string
+	Flights     domain.Flights
+	RefreshedAt time.Time
+}
+
+// Listener is notified with the previous and the new version every time the catalogue changes.
+type Listener func(prev, next Version)
+
+// RetainedVersions is the number of versions, the current one included, a catalogue keeps so that clients paging
+// through a version can finish reading it after the catalogue moved on.
+const RetainedVersions = 8
+
+// Catalogue keeps the latest aggregated flights and versions them.
+type Catalogue struct {
+	// updateMu serializes updates so that listeners observe versions in order.
+	updateMu sync.Mutex
+	mu       sync.RWMutex
+	current  Version
+	// retained holds the latest versions, oldest first, the current one last.
+	retained  []Version
+	listeners []Listener
+}
+
+// Default is the catalogue shared by the HTTP handlers.
+var Default = New()
+
+// New creates an empty catalogue at version 0.
+func New() *Catalogue {
+	return &Catalogue{}
+}
+
+// Current returns the latest version of the catalogue.
+func (c *Catalogue) Current() Version {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+// Version returns a retained version by number, and false when it is unknown or was dropped.
+func (c *Catalogue) Version(number uint64) (Version, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, v := range c.retained {
+		if v.Number == number {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+// Subscribe registers a listener called synchronously, in registration order, after every change.
+func (c *Catalogue) Subscribe(l Listener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, l)
+}
+
+// Update records the flights of a refresh. The version number only increases when their content changed,
+// in which case listeners are notified. Returns the resulting current version.
+func (c *Catalogue) Update(flights domain.Flights) Version {
+	digest := Digest(flights)
+
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	c.mu.Lock()
+	if c.current.Number > 0 && c.current.Digest == digest {
+		c.current.RefreshedAt = time.Now().UTC()
+		current := c.current
+		c.mu.Unlock()
+		return current
+	}
+	prev := c.current
+	c.current = Version{
+		Number:      prev.Number + 1,
+		Digest:      digest,
+		Flights:     append(domain.Flights(nil), flights...),
+		RefreshedAt: time.Now().UTC(),
+	}
+	c.retained = append(c.retained, c.current)
+	if len(c.retained) > RetainedVersions {
+		c.retained = append([]Version(nil), c.retained[len(c.retained)-RetainedVersions:]...)
+	}
+	next := c.current
+	listeners := append([]Listener(nil), c.listeners...)
+	c.mu.Unlock()
+
+	for _, l := range listeners {
+		l(prev, next)
+	}
+	return next
+}
+
+// Digest returns a hash of the flights that does not depend on the order in which providers returned them.
+func Digest(flights domain.Flights) string {
+	snapshot := flights.ToSnapshot()
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Source != snapshot[j].Source {
+			return snapshot[i].Source < snapshot[j].Source
+		}
+		return snapshot[i].ID < snapshot[j].ID
+	})
+	b, _ := json.Marshal(snapshot)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,248 @@
+package changes
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of changes kept by Default.
+const DefaultCapacity = 100
+
+// ErrVersionGone is returned when the changes following a version have already left the ring buffer, or when the
+// version was issued by another run of the server.
+var ErrVersionGone = errors.New("version no longer available")
+
+// BookingRef identifies a booking across providers.
+type BookingRef struct {
+	Source string `json:"source"`
+	ID     string `json:"id"`
+}
+
+// FieldChange is the old and new value of one field of a booking, named by its JSON path such as "total.amount"
+// or "segments[0].depart". A field that appeared has no old value, one that disappeared no new value.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old,omitempty"`
+	New   any    `json:"new,omitempty"`
+}
+
+// Modification lists the fields that changed on a booking present in both versions, and the booking as it now is.
+type Modification struct {
+	BookingRef
+	Fields []FieldChange         `json:"fields"`
+	Flight domain.FlightSnapshot `json:"flight"`
+}
+
+// Change is the diff between two consecutive versions of the catalogue.
+type Change struct {
+	Version     uint64                  `json:"version"`
+	PrevVersion uint64                  `json:"prevVersion"`
+	At          time.Time               `json:"at"`
+	Added       []domain.FlightSnapshot `json:"added"`
+	Removed     []BookingRef            `json:"removed"`
+	Modified    []Modification          `json:"modified"`
+}
+
+// Feed keeps the latest changes of the catalogue in a ring buffer.
+type Feed struct {
+	mu       sync.RWMutex
+	capacity int
+	ring     []Change
+	// start is the position of the oldest change in ring once it is full.
+	start   int
+	current uint64
+	// epoch identifies the run of the server: catalogue versions restart at 1 with the process, so a version is only
+	// meaningful along with the epoch it was issued in.
+	epoch string
+	// notify is closed, then replaced, every time a change is recorded.
+	notify    chan struct{}
+	listeners []Listener
+}
+
+// Listener is called with every change recorded by a feed.
+type Listener func(Change)
+
+// Default is the feed following catalogue.Default.
+var Default = New(DefaultCapacity)
+
+// New creates an empty feed keeping at most capacity changes; capacities below 1 keep one.
+func New(capacity int) *Feed {
+	return &Feed{capacity: max(capacity, 1), notify: make(chan struct{}), epoch: strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+// Epoch returns the identifier of the run of the server the versions of the feed belong to.
+func (f *Feed) Epoch() string {
+	return f.epoch
+}
+
+// Notify returns a channel closed when the next change is recorded. Callers get the channel before reading
+// the feed with Since, so that no change recorded in between is missed.
+func (f *Feed) Notify() <-chan struct{} {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.notify
+}
+
+// Subscribe registers a listener called synchronously, in registration order, with every recorded change, so that
+// consumers of the diff do not compute it again.
+func (f *Feed) Subscribe(l Listener) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listeners = append(f.listeners, l)
+}
+
+// Record is a catalogue.Listener computing the diff between the two versions and keeping it, dropping the oldest
+// change once the buffer is full, then passing it to the listeners of the feed.
+func (f *Feed) Record(prev, next catalogue.Version) {
+	change := Diff(prev.Flights, next.Flights)
+	change.Version, change.PrevVersion, change.At = next.Number, prev.Number, next.RefreshedAt
+
+	f.mu.Lock()
+	f.current = next.Number
+	close(f.notify)
+	f.notify = make(chan struct{})
+	if len(f.ring) < f.capacity {
+		f.ring = append(f.ring, change)
+	} else {
+		f.ring[f.start] = change
+		f.start = (f.start + 1) % f.capacity
+	}
+	listeners := append([]Listener(nil), f.listeners...)
+	f.mu.Unlock()
+
+	for _, l := range listeners {
+		l(change)
+	}
+}
+
+// Since returns the changes made after the given version, oldest first, and the current version.
+// It returns ErrVersionGone when some of those changes were already dropped, in which case a client must resync.
+func (f *Feed) Since(version uint64) ([]Change, uint64, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if version > f.current {
+		return nil, f.current, fmt.Errorf("%w: version %d is ahead of the current version %d, it was issued before a restart",
+			ErrVersionGone, version, f.current)
+	}
+
+	out := make([]Change, 0, len(f.ring))
+	for i := range f.ring {
+		c := f.ring[(f.start+i)%len(f.ring)]
+		if c.Version > version {
+			out = append(out, c)
+		}
+	}
+	if len(out) > 0 && out[0].PrevVersion > version {
+		return nil, f.current, fmt.Errorf("%w: changes after version %d were dropped, the oldest kept follows version %d",
+			ErrVersionGone, version, out[0].PrevVersion)
+	}
+	return out, f.current, nil
+}
+
+// Diff compares two sets of bookings, identified by source and id, and returns the added, removed and modified ones,
+// each sorted by source then id.
+func Diff(prev, next domain.Flights) Change {
+	before := make(map[BookingRef]domain.FlightSnapshot, len(prev))
+	for _, f := range prev {
+		before[BookingRef{Source: f.Source(), ID: f.ID()}] = f.Snapshot()
+	}
+
+	change := Change{Added: []domain.FlightSnapshot{}, Removed: []BookingRef{}, Modified: []Modification{}}
+	seen := make(map[BookingRef]bool, len(next))
+	for _, f := range next {
+		ref := BookingRef{Source: f.Source(), ID: f.ID()}
+		seen[ref] = true
+		snapshot := f.Snapshot()
+		old, ok := before[ref]
+		if !ok {
+			change.Added = append(change.Added, snapshot)
+			continue
+		}
+		if fields := diffFields(old, snapshot); len(fields) > 0 {
+			change.Modified = append(change.Modified, Modification{BookingRef: ref, Fields: fields, Flight: snapshot})
+		}
+	}
+	for ref := range before {
+		if !seen[ref] {
+			change.Removed = append(change.Removed, ref)
+		}
+	}
+
+	sort.Slice(change.Added, func(i, j int) bool {
+		return refLess(BookingRef{change.Added[i].Source, change.Added[i].ID}, BookingRef{change.Added[j].Source, change.Added[j].ID})
+	})
+	sort.Slice(change.Removed, func(i, j int) bool { return refLess(change.Removed[i], change.Removed[j]) })
+	sort.Slice(change.Modified, func(i, j int) bool { return refLess(change.Modified[i].BookingRef, change.Modified[j].BookingRef) })
+	return change
+}
+
+// refLess orders bookings by source then id.
+func refLess(a, b BookingRef) bool {
+	if a.Source != b.Source {
+		return a.Source < b.Source
+	}
+	return a.ID < b.ID
+}
+
+// diffFields compares the JSON forms of two snapshots leaf by leaf, in path order.
+func diffFields(old, next domain.FlightSnapshot) []FieldChange {
+	before, after := flatten(old), flatten(next)
+	paths := make([]string, 0, len(before)+len(after))
+	for p := range before {
+		paths = append(paths, p)
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var out []FieldChange
+	for _, p := range paths {
+		o, n := before[p], after[p]
+		if !reflect.DeepEqual(o, n) {
+			out = append(out, FieldChange{Field: p, Old: o, New: n})
+		}
+	}
+	return out
+}
+
+// flatten returns the leaves of the JSON form of a snapshot by path.
+func flatten(s domain.FlightSnapshot) map[string]any {
+	b, _ := json.Marshal(s)
+	var v any
+	_ = json.Unmarshal(b, &v)
+	out := make(map[string]any)
+	flattenValue("", v, out)
+	return out
+}
+
+// flattenValue walks a decoded JSON value, naming object members "a.b" and array items "a[0]".
+func flattenValue(path string, v any, out map[string]any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			flattenValue(p, item, out)
+		}
+	case []any:
+		for i, item := range v {
+			flattenValue(path+"["+strconv.Itoa(i)+"]", item, out)
+		}
+	default:
+		out[path] = v
+	}
+}
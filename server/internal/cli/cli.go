@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+var errUsage = errors.New("usage: aggregator <command> [flags]\n\ncommands:\n  reconcile   compare the data of all providers for the same bookings")
+
+// Run executes the command named by the first argument and writes its output to stdout.
+func Run(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "reconcile":
+		return runReconcile(ctx, args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown command %q\n%w", args[0], errUsage)
+	}
+}
+
+// runReconcile fetches all providers and prints the reconciliation report as JSON or CSV.
+func runReconcile(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	format := fs.String("format", "json", "output format: json or csv")
+	output := fs.String("o", "", "write the report to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("invalid format %q", *format)
+	}
+
+	multi, err := repo.LoadConfigured(ctx)
+	if err != nil {
+		return err
+	}
+	report, err := service.ReconcileFlights(ctx, multi)
+	if err != nil {
+		return fmt.Errorf("reconcile flights: %w", err)
+	}
+
+	out := stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("create output: %w", err)
+		}
+		defer func(file *os.File) {
+			err := file.Close()
+			if err != nil {
+				return
+			}
+		}(file)
+		out = file
+	}
+
+	if *format == "csv" {
+		return report.WriteCSV(out)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -10,6 +13,36 @@ var (
 	SERVER1_URL string
 	SERVER2_URL string
 	SERVER_PORT string
+
+	DEDUP_POLICY      string
+	PROVIDER_PRIORITY []string
+
+	// HISTORY_FILE is the JSON lines file price history is appended to; empty keeps it in memory only.
+	HISTORY_FILE string
+	// HISTORY_RETENTION is how long price history is kept; 0 keeps it forever.
+	HISTORY_RETENTION time.Duration
+	// CHANGES_CAPACITY is the number of catalogue diffs /changes keeps.
+	CHANGES_CAPACITY int
+	// REFRESH_INTERVAL is how often the catalogue is refreshed in the background; 0 only loads it on the first request.
+	REFRESH_INTERVAL time.Duration
+	// WS_MAX_SUBSCRIPTIONS is the number of subscriptions a /watch connection may hold.
+	WS_MAX_SUBSCRIPTIONS int
+	// WS_ALLOWED_ORIGINS are the origins of the browser pages, other than the server's own, that may open /watch.
+	WS_ALLOWED_ORIGINS []string
+	// ALERTS_FILE is the JSON file price alerts are saved to; empty keeps them in memory only.
+	ALERTS_FILE string
+	// WEBHOOKS_FILE is the JSON file outbound webhook endpoints are saved to; empty keeps them in memory only.
+	WEBHOOKS_FILE string
+	// SCHEDULE_FILE is the JSON file the schedule tracker is saved to; empty keeps it in memory only.
+	SCHEDULE_FILE string
+	// WEBHOOK_MAX_ATTEMPTS is the number of attempts of a webhook delivery before it becomes a dead letter.
+	WEBHOOK_MAX_ATTEMPTS int
+	// DEAD_LETTERS_FILE is the JSON file failed webhook deliveries are saved to; empty keeps them in memory only.
+	DEAD_LETTERS_FILE string
+	// WEBHOOK_ALLOW_PRIVATE_NETWORKS lets webhooks reach loopback, private and link-local addresses.
+	WEBHOOK_ALLOW_PRIVATE_NETWORKS bool
+	// API_TOKEN is the bearer token required to create, change or delete alerts and webhooks; empty disables them.
+	API_TOKEN string
 )
 
 // Load initializes configuration by reading from a .env file and environment variables, setting relevant global variables.
@@ -25,6 +58,33 @@ func Load() {
 
 	// Load the values
 	SERVER_PORT = viper.GetString("SERVER_PORT")
+	viper.SetDefault("DEDUP_POLICY", "cheapest")
+	viper.SetDefault("PROVIDER_PRIORITY", "flights,flight_to_book")
+	DEDUP_POLICY = viper.GetString("DEDUP_POLICY")
+	PROVIDER_PRIORITY = splitList(viper.GetString("PROVIDER_PRIORITY"))
+	viper.SetDefault("HISTORY_FILE", "price_history.jsonl")
+	HISTORY_FILE = strings.TrimSpace(viper.GetString("HISTORY_FILE"))
+	viper.SetDefault("HISTORY_RETENTION", "2160h")
+	HISTORY_RETENTION = viper.GetDuration("HISTORY_RETENTION")
+	viper.SetDefault("CHANGES_CAPACITY", 100)
+	CHANGES_CAPACITY = viper.GetInt("CHANGES_CAPACITY")
+	viper.SetDefault("REFRESH_INTERVAL", "30s")
+	REFRESH_INTERVAL = viper.GetDuration("REFRESH_INTERVAL")
+	viper.SetDefault("WS_MAX_SUBSCRIPTIONS", 20)
+	WS_MAX_SUBSCRIPTIONS = viper.GetInt("WS_MAX_SUBSCRIPTIONS")
+	WS_ALLOWED_ORIGINS = splitList(viper.GetString("WS_ALLOWED_ORIGINS"))
+	viper.SetDefault("ALERTS_FILE", "alerts.json")
+	ALERTS_FILE = strings.TrimSpace(viper.GetString("ALERTS_FILE"))
+	viper.SetDefault("WEBHOOKS_FILE", "webhooks.json")
+	WEBHOOKS_FILE = strings.TrimSpace(viper.GetString("WEBHOOKS_FILE"))
+	viper.SetDefault("SCHEDULE_FILE", "schedule.json")
+	SCHEDULE_FILE = strings.TrimSpace(viper.GetString("SCHEDULE_FILE"))
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 5)
+	WEBHOOK_MAX_ATTEMPTS = viper.GetInt("WEBHOOK_MAX_ATTEMPTS")
+	viper.SetDefault("DEAD_LETTERS_FILE", "dead_letters.json")
+	DEAD_LETTERS_FILE = strings.TrimSpace(viper.GetString("DEAD_LETTERS_FILE"))
+	WEBHOOK_ALLOW_PRIVATE_NETWORKS = viper.GetBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS")
+	API_TOKEN = strings.TrimSpace(viper.GetString("API_TOKEN"))
 	j1Name := viper.GetString("JSERVER1_NAME")
 	j1Port := viper.GetString("JSERVER1_PORT")
 	j2Name := viper.GetString("JSERVER2_NAME")
@@ -36,6 +96,18 @@ func Load() {
 		SERVER2_URL = fmt.Sprintf("http://%s:%s/", j2Name, j2Port)
 	}
 
-	fmt.Printf("SERVER1_URL: '%s'\n", SERVER1_URL)
-	fmt.Printf("SERVER2_URL: '%s'\n", SERVER2_URL)
+	// Diagnostics go to stderr so that CLI commands can write their output to stdout
+	fmt.Fprintf(os.Stderr, "SERVER1_URL: '%s'\n", SERVER1_URL)
+	fmt.Fprintf(os.Stderr, "SERVER2_URL: '%s'\n", SERVER2_URL)
+}
+
+// splitList splits a comma separated configuration value into its trimmed, non-empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
 }
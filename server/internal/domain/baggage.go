@@ -0,0 +1,121 @@
+package domain
+
+import "strings"
+
+// Allowance is a number of bags and, when the airline sets one, the maximum weight of each in kilograms.
+type Allowance struct {
+	pieces   int
+	weightKg float64
+}
+
+// Baggage is the carry-on and checked allowance of a segment or a booking. The zero value means the feed did not say,
+// whereas a known baggage with no checked allowance means the fare does not include a checked bag.
+type Baggage struct {
+	carryOn Allowance
+	checked Allowance
+	known   bool
+}
+
+// AncillaryKind is the kind of service sold on top of a fare.
+type AncillaryKind string
+
+const (
+	AncillarySeat       AncillaryKind = "seat"
+	AncillaryMeal       AncillaryKind = "meal"
+	AncillaryCheckedBag AncillaryKind = "checkedBag"
+)
+
+// ancillaryAliases maps the spellings found in feeds to ancillary kinds.
+var ancillaryAliases = map[string]AncillaryKind{
+	"seat":        AncillarySeat,
+	"meal":        AncillaryMeal,
+	"checkedbag":  AncillaryCheckedBag,
+	"checked_bag": AncillaryCheckedBag,
+	"checked bag": AncillaryCheckedBag,
+	"bag":         AncillaryCheckedBag,
+	"baggage":     AncillaryCheckedBag,
+}
+
+// Ancillary is an optional service, such as a seat or a meal, and the price it is sold at.
+type Ancillary struct {
+	kind        AncillaryKind
+	description string
+	price       Total
+}
+
+func (a Allowance) Pieces() int         { return a.pieces }
+func (a Allowance) WeightKg() float64   { return a.weightKg }
+func (b Baggage) CarryOn() Allowance    { return b.carryOn }
+func (b Baggage) Checked() Allowance    { return b.checked }
+func (b Baggage) Known() bool           { return b.known }
+func (a Ancillary) Kind() AncillaryKind { return a.kind }
+func (a Ancillary) Description() string { return a.description }
+func (a Ancillary) Price() Total        { return a.price }
+
+// IsZero reports whether the allowance includes no bag.
+func (a Allowance) IsZero() bool { return a.pieces <= 0 && a.weightKg <= 0 }
+
+// IncludesChecked reports whether the baggage is known to include at least one checked bag.
+// A weight without a number of pieces counts as one bag, as in the weight concept used by some airlines.
+func (b Baggage) IncludesChecked() bool { return b.known && !b.checked.IsZero() }
+
+// ParseAncillaryKind returns the kind designated by s, case-insensitively. Unknown kinds are kept as given, in lower case.
+func ParseAncillaryKind(s string) AncillaryKind {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if kind, ok := ancillaryAliases[s]; ok {
+		return kind
+	}
+	return AncillaryKind(s)
+}
+
+// Baggage returns the allowance of the segment, which may be unknown.
+func (s Segment) Baggage() Baggage { return s.baggage }
+
+// WithBaggage returns a copy of the segment with the given allowance.
+func (s Segment) WithBaggage(b Baggage) Segment {
+	s.baggage = b
+	return s
+}
+
+// Baggage returns the allowance given for the whole booking, which may be unknown even when segments have one.
+func (f Flight) Baggage() Baggage { return f.baggage }
+
+func (f Flight) Ancillaries() []Ancillary { return append([]Ancillary(nil), f.ancillaries...) }
+
+// WithExtras returns a copy of the flight with the given booking allowance and ancillaries.
+func (f Flight) WithExtras(baggage Baggage, ancillaries []Ancillary) Flight {
+	f.baggage = baggage
+	f.ancillaries = append([]Ancillary(nil), ancillaries...)
+	return f
+}
+
+// IncludesCheckedBag reports whether a checked bag is included: in the booking allowance, or else in the allowance of every segment.
+func (f Flight) IncludesCheckedBag() bool {
+	if f.baggage.Known() {
+		return f.baggage.IncludesChecked()
+	}
+	if len(f.segments) == 0 {
+		return false
+	}
+	for _, s := range f.segments {
+		if !s.baggage.IncludesChecked() {
+			return false
+		}
+	}
+	return true
+}
+
+// NewAllowance (unitTest) creates an allowance of the given number of bags, each weighing at most weightKg (0 if unlimited).
+func NewAllowance(pieces int, weightKg float64) Allowance {
+	return Allowance{pieces: max(pieces, 0), weightKg: max(weightKg, 0)}
+}
+
+// NewBaggage (unitTest) creates a known baggage allowance.
+func NewBaggage(carryOn, checked Allowance) Baggage {
+	return Baggage{carryOn: carryOn, checked: checked, known: true}
+}
+
+// NewAncillary (unitTest) creates an ancillary of the given kind, description and price.
+func NewAncillary(kind AncillaryKind, description string, price Total) Ancillary {
+	return Ancillary{kind: kind, description: strings.TrimSpace(description), price: price}
+}
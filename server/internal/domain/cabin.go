@@ -0,0 +1,42 @@
+package domain
+
+import "strings"
+
+// Cabin is the class of service a segment is booked in. The zero value means the feed did not say.
+type Cabin string
+
+const (
+	CabinEconomy  Cabin = "economy"
+	CabinPremium  Cabin = "premium"
+	CabinBusiness Cabin = "business"
+	CabinFirst    Cabin = "first"
+)
+
+// cabinAliases maps the spellings found in feeds and queries to cabins. Single letters are left out: they are
+// booking classes (RBD), which airlines map to cabins each their own way.
+var cabinAliases = map[string]Cabin{
+	"economy":         CabinEconomy,
+	"eco":             CabinEconomy,
+	"coach":           CabinEconomy,
+	"premium":         CabinPremium,
+	"premiumeconomy":  CabinPremium,
+	"premium economy": CabinPremium,
+	"premium_economy": CabinPremium,
+	"business":        CabinBusiness,
+	"first":           CabinFirst,
+}
+
+// ParseCabin returns the cabin designated by s, case-insensitively. It reports false for unknown cabins.
+func ParseCabin(s string) (Cabin, bool) {
+	c, ok := cabinAliases[strings.ToLower(strings.TrimSpace(s))]
+	return c, ok
+}
+
+// WithFare returns a copy of the segment with the given cabin, booking class (RBD, e.g. "Y" or "J")
+// and fare basis code. Unknown cabins and empty values are left unset.
+func (s Segment) WithFare(cabin, bookingClass, fareBasis string) Segment {
+	s.cabin, _ = ParseCabin(cabin)
+	s.bookingClass = strings.ToUpper(strings.TrimSpace(bookingClass))
+	s.fareBasis = strings.ToUpper(strings.TrimSpace(fareBasis))
+	return s
+}
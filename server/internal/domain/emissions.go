@@ -0,0 +1,48 @@
+package domain
+
+import "aggregator/internal/reference"
+
+// DistanceKm returns the great-circle distance between the airports of the segment, and false when either is unknown.
+func (s Segment) DistanceKm() (float64, bool) {
+	return reference.GreatCircleKm(s.departure, s.arrival)
+}
+
+// CO2Kg estimates the CO2-equivalent emitted for one passenger on the segment, in kilograms: the great-circle
+// distance, uplifted by reference.DistanceUplift, times the factor of the segment's haul and cabin.
+// It reports false when the distance is unknown.
+func (s Segment) CO2Kg() (float64, bool) {
+	km, ok := s.DistanceKm()
+	if !ok {
+		return 0, false
+	}
+	haul, _ := reference.FlightHaul(s.departure, s.arrival, km)
+	return km * reference.DistanceUplift * reference.EmissionFactor(haul, string(s.cabin)), true
+}
+
+// DistanceKm returns the total great-circle distance of the itinerary, and false when it has no segment
+// or the distance of one of them is unknown.
+func (f Flight) DistanceKm() (float64, bool) {
+	return f.sum(Segment.DistanceKm)
+}
+
+// CO2Kg returns the estimated emissions of the itinerary for one passenger, and false when it has no segment
+// or the estimate of one of them is unknown.
+func (f Flight) CO2Kg() (float64, bool) {
+	return f.sum(Segment.CO2Kg)
+}
+
+// sum adds a value over every segment, reporting false as soon as one segment has none.
+func (f Flight) sum(value func(Segment) (float64, bool)) (float64, bool) {
+	if len(f.segments) == 0 {
+		return 0, false
+	}
+	total := 0.0
+	for _, s := range f.segments {
+		v, ok := value(s)
+		if !ok {
+			return 0, false
+		}
+		total += v
+	}
+	return total, true
+}
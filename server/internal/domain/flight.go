@@ -1,8 +1,10 @@
 package domain
 
 import (
+	"aggregator/internal/reference"
 	"context"
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -17,6 +19,15 @@ type Segment struct {
 	arrival      string
 	departTime   time.Time
 	arriveTime   time.Time
+	// operatingCarrier and operatingFlightNumber are set on codeshare segments, sold under flightNumber
+	// but flown by another airline under its own number. Either may be missing from the feed.
+	operatingCarrier      string
+	operatingFlightNumber string
+	// cabin, bookingClass and fareBasis describe the fare the segment is booked on, when the feed gives them.
+	cabin        Cabin
+	bookingClass string
+	fareBasis    string
+	baggage      Baggage
 }
 
 type Flight struct {
@@ -26,6 +37,9 @@ type Flight struct {
 	segments      []Segment
 	total         Total
 	source        string
+	// baggage and ancillaries are optional and describe the booking as a whole.
+	baggage     Baggage
+	ancillaries []Ancillary
 }
 
 type Flights []Flight
@@ -39,6 +53,65 @@ func (s Segment) Departure() string     { return s.departure }
 func (s Segment) Arrival() string       { return s.arrival }
 func (s Segment) DepartTime() time.Time { return s.departTime }
 func (s Segment) ArriveTime() time.Time { return s.arriveTime }
+func (s Segment) Cabin() Cabin          { return s.cabin }
+func (s Segment) BookingClass() string  { return s.bookingClass }
+func (s Segment) FareBasis() string     { return s.fareBasis }
+
+// Designator returns the parsed flight number of the segment, and false when it is not a valid designator.
+func (s Segment) Designator() (FlightDesignator, bool) { return ParseFlightNumber(s.flightNumber) }
+
+// Carrier returns the IATA code of the airline marketing the segment, read from its flight number.
+// ICAO codes of known airlines are converted to IATA; unparsable flight numbers yield their first two characters.
+func (s Segment) Carrier() string {
+	d, ok := s.Designator()
+	if !ok {
+		number := cleanFlightNumber(s.flightNumber)
+		return number[:min(2, len(number))]
+	}
+	if len(d.Carrier) == 3 {
+		if a, ok := reference.LookupAirline(d.Carrier); ok {
+			return a.Code
+		}
+	}
+	return d.Carrier
+}
+
+// OperatingCarrier returns the IATA code of the airline flying the segment: the operating carrier given by the feed,
+// else the carrier of the operating flight number, else the marketing carrier.
+func (s Segment) OperatingCarrier() string {
+	if s.operatingCarrier != "" {
+		if a, ok := reference.LookupAirline(s.operatingCarrier); ok {
+			return a.Code
+		}
+		return strings.ToUpper(s.operatingCarrier)
+	}
+	if s.operatingFlightNumber != "" {
+		return Segment{flightNumber: s.operatingFlightNumber}.Carrier()
+	}
+	return s.Carrier()
+}
+
+// OperatingFlightNumber returns the number the segment is flown under, which is the marketing one
+// unless the feed gave an operating flight number.
+func (s Segment) OperatingFlightNumber() string {
+	if s.operatingFlightNumber != "" {
+		return s.operatingFlightNumber
+	}
+	return s.flightNumber
+}
+
+// IsCodeshare reports whether the segment is flown by another airline or under another number than it is sold.
+func (s Segment) IsCodeshare() bool {
+	return s.OperatingCarrier() != s.Carrier() ||
+		NormalizeFlightNumber(s.OperatingFlightNumber()) != NormalizeFlightNumber(s.flightNumber)
+}
+
+// WithOperating returns a copy of the segment with the given operating carrier and flight number; empty values are left unset.
+func (s Segment) WithOperating(carrier, flightNumber string) Segment {
+	s.operatingCarrier = strings.TrimSpace(carrier)
+	s.operatingFlightNumber = strings.TrimSpace(flightNumber)
+	return s
+}
 
 func (f Flight) ID() string            { return f.id }
 func (f Flight) Status() string        { return f.status }
@@ -63,13 +136,35 @@ func (t TotalSnapshot) ToDomain() Total {
 	return Total{t.Amount, t.Currency}
 }
 
+func (a AllowanceSnapshot) ToDomain() Allowance {
+	return NewAllowance(a.Pieces, a.WeightKg)
+}
+
+// ToDomain returns an unknown baggage for a nil snapshot.
+func (b *BaggageSnapshot) ToDomain() Baggage {
+	if b == nil {
+		return Baggage{}
+	}
+	return NewBaggage(b.CarryOn.ToDomain(), b.Checked.ToDomain())
+}
+
+func (a AncillarySnapshot) ToDomain() Ancillary {
+	return NewAncillary(a.Type, a.Description, a.Price.ToDomain())
+}
+
 func (s SegmentSnapshot) ToDomain() Segment {
 	return Segment{
-		s.FlightNumber,
-		s.Departure,
-		s.Arrival,
-		s.DepartTime,
-		s.ArriveTime,
+		flightNumber:          s.FlightNumber,
+		departure:             s.Departure,
+		arrival:               s.Arrival,
+		departTime:            s.DepartTime,
+		arriveTime:            s.ArriveTime,
+		operatingCarrier:      s.OperatingCarrier,
+		operatingFlightNumber: s.OperatingFlightNumber,
+		cabin:                 s.Cabin,
+		bookingClass:          s.BookingClass,
+		fareBasis:             s.FareBasis,
+		baggage:               s.Baggage.ToDomain(),
 	}
 }
 
@@ -78,7 +173,15 @@ func (f FlightSnapshot) ToDomain() *Flight {
 	for i, s := range f.Segments {
 		segs[i] = s.ToDomain()
 	}
-	return NewFlight(f.ID, f.Status, f.PassengerName, segs, f.Total.ToDomain(), f.Source)
+	flight := NewFlight(f.ID, f.Status, f.PassengerName, segs, f.Total.ToDomain(), f.Source)
+	if f.Baggage != nil || len(f.Ancillaries) > 0 {
+		ancillaries := make([]Ancillary, len(f.Ancillaries))
+		for i, a := range f.Ancillaries {
+			ancillaries[i] = a.ToDomain()
+		}
+		*flight = flight.WithExtras(f.Baggage.ToDomain(), ancillaries)
+	}
+	return flight
 }
 
 func (fs FlightsSnapshot) ToDomain() Flights {
@@ -97,12 +200,19 @@ type FlightsRepository interface {
 	FindById(ctx context.Context, id string) (Flight, error)
 	// FindByNumber retrieves a specific flight from the repository based on the provided flight number.
 	FindByNumber(ctx context.Context, number string) (Flight, error)
+	// FindAllByNumber retrieves every flight with a segment on the given flight number.
+	FindAllByNumber(ctx context.Context, number string) (Flights, error)
 	// FindByPassenger retrieves flights associated with a specific passenger's first and last name (passengerName) from the repository.
 	FindByPassenger(ctx context.Context, passengerName string) (Flights, error)
 	// FindByDestination retrieves flights that match the specified departure and arrival locations from the repository.
 	FindByDestination(ctx context.Context, departure, arrival string) (Flights, error)
 	// FindByPrice retrieves flights from the repository that match the specified price.
 	FindByPrice(ctx context.Context, price float64) (Flights, error)
+	// FindByPriceRange retrieves flights whose total is in the currency and whose amount lies between min and max,
+	// both inclusive.
+	FindByPriceRange(ctx context.Context, currency string, min, max float64) (Flights, error)
+	// FindByDepartureRange retrieves flights whose first segment departs in [after, before); a zero bound is open.
+	FindByDepartureRange(ctx context.Context, after, before time.Time) (Flights, error)
 }
 
 // NewTotal (unitTest) creates a new Total instance with the specified amount and currency.
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FlightDesignator is a flight number split into its parts: "AF0276" is carrier AF, number 276.
+type FlightDesignator struct {
+	// Carrier is the airline code as written in the designator: two character IATA or three letter ICAO.
+	Carrier string
+	Number  int
+	// Suffix is the optional operational suffix letter.
+	Suffix string
+}
+
+// String returns the canonical form of the designator, the numeric part written on at least three digits.
+func (d FlightDesignator) String() string {
+	return fmt.Sprintf("%s%03d%s", d.Carrier, d.Number, d.Suffix)
+}
+
+// ParseFlightNumber splits a flight number such as "AF 276", "af0276" or "AFR276" into its parts.
+// It reports false when the text is not a flight designator.
+func ParseFlightNumber(s string) (FlightDesignator, bool) {
+	carrier, number, suffix, ok := parseFlightNumber(s)
+	if !ok {
+		return FlightDesignator{}, false
+	}
+	return FlightDesignator{Carrier: carrier, Number: number, Suffix: suffix}, true
+}
+
+// NormalizeFlightNumber returns the canonical form of a flight designator: upper case, without separators and with
+// the numeric part written on at least three digits, so that "AF 276", "af-276" and "AF0276" all become "AF276"
+// and "JL46" becomes "JL046".
+// Designators that cannot be parsed are only upper-cased and stripped of separators.
+func NormalizeFlightNumber(s string) string {
+	d, ok := ParseFlightNumber(s)
+	if !ok {
+		return cleanFlightNumber(s)
+	}
+	return d.String()
+}
+
+// cleanFlightNumber upper-cases a designator and removes spaces and hyphens.
+func cleanFlightNumber(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '-' {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+// parseFlightNumber splits a designator into its airline code, its number and an optional one-letter operational suffix.
+// The airline code is either a two character IATA code, letters or a letter and a digit, or a three letter ICAO code;
+// the number has one to four significant digits.
+func parseFlightNumber(s string) (carrier string, number int, suffix string, ok bool) {
+	s = cleanFlightNumber(s)
+	if len(s) < 3 {
+		return "", 0, "", false
+	}
+
+	codeLen := 2
+	if len(s) > 3 && isLetter(s[0]) && isLetter(s[1]) && isLetter(s[2]) {
+		codeLen = 3
+	}
+	carrier, rest := s[:codeLen], s[codeLen:]
+	if codeLen == 2 && !(isLetter(carrier[0]) || isLetter(carrier[1])) {
+		return "", 0, "", false
+	}
+	for i := 0; i < codeLen; i++ {
+		if !isLetter(carrier[i]) && !isDigit(carrier[i]) {
+			return "", 0, "", false
+		}
+	}
+
+	if n := len(rest); n > 1 && isLetter(rest[n-1]) {
+		rest, suffix = rest[:n-1], rest[n-1:]
+	}
+	if len(rest) == 0 || len(strings.TrimLeft(rest, "0")) > 4 {
+		return "", 0, "", false
+	}
+	for i := 0; i < len(rest); i++ {
+		if !isDigit(rest[i]) {
+			return "", 0, "", false
+		}
+	}
+	number, _ = strconv.Atoi(rest)
+	return carrier, number, suffix, true
+}
+
+// isLetter reports whether b is an upper-case ASCII letter.
+func isLetter(b byte) bool { return b >= 'A' && b <= 'Z' }
+
+// isDigit reports whether b is an ASCII digit.
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
@@ -0,0 +1,32 @@
+package domain
+
+// ProviderRef identifies a booking as it is known by one upstream provider.
+type ProviderRef struct {
+	source string
+	id     string
+}
+
+// MergedFlight is the canonical record built from the bookings several providers hold for the same trip.
+type MergedFlight struct {
+	flight    Flight
+	providers []ProviderRef
+}
+
+func (p ProviderRef) Source() string { return p.source }
+func (p ProviderRef) ID() string     { return p.id }
+
+func (m MergedFlight) Flight() Flight           { return m.flight }
+func (m MergedFlight) Providers() []ProviderRef { return append([]ProviderRef(nil), m.providers...) }
+
+// NewProviderRef creates a ProviderRef for the booking id known by the given source.
+func NewProviderRef(source, id string) ProviderRef {
+	return ProviderRef{source: source, id: id}
+}
+
+// NewMergedFlight creates a MergedFlight from the canonical flight and the providers that contributed to it.
+func NewMergedFlight(flight Flight, providers []ProviderRef) MergedFlight {
+	return MergedFlight{
+		flight:    flight,
+		providers: append([]ProviderRef(nil), providers...),
+	}
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"aggregator/internal/reference"
+	"math"
+	"time"
+)
 
 type TotalSnapshot struct {
 	Amount   float64 `json:"amount"`
@@ -8,24 +12,56 @@ type TotalSnapshot struct {
 }
 
 type SegmentSnapshot struct {
-	FlightNumber string    `json:"flightNumber"`
-	Departure    string    `json:"from"`
-	Arrival      string    `json:"to"`
-	DepartTime   time.Time `json:"depart"`
-	ArriveTime   time.Time `json:"arrive"`
+	FlightNumber string `json:"flightNumber"`
+	Carrier      string `json:"carrier,omitempty"`
+	CarrierName  string `json:"carrierName,omitempty"`
+	// The operating fields are only set on codeshare segments.
+	OperatingCarrier      string           `json:"operatingCarrier,omitempty"`
+	OperatingFlightNumber string           `json:"operatingFlightNumber,omitempty"`
+	Cabin                 Cabin            `json:"cabin,omitempty"`
+	BookingClass          string           `json:"bookingClass,omitempty"`
+	FareBasis             string           `json:"fareBasis,omitempty"`
+	Baggage               *BaggageSnapshot `json:"baggage,omitempty"`
+	// DistanceKm and CO2Kg are computed, and left out when an airport is unknown.
+	DistanceKm float64   `json:"distanceKm,omitempty"`
+	CO2Kg      float64   `json:"co2Kg,omitempty"`
+	Departure  string    `json:"from"`
+	Arrival    string    `json:"to"`
+	DepartTime time.Time `json:"depart"`
+	ArriveTime time.Time `json:"arrive"`
 }
 
 type FlightSnapshot struct {
-	ID            string            `json:"id"`
-	Status        string            `json:"status"`
-	PassengerName string            `json:"passengerName"`
-	Segments      []SegmentSnapshot `json:"segments"`
-	Total         TotalSnapshot     `json:"total"`
-	Source        string            `json:"source"`
+	ID            string              `json:"id"`
+	Status        string              `json:"status"`
+	PassengerName string              `json:"passengerName"`
+	Segments      []SegmentSnapshot   `json:"segments"`
+	Total         TotalSnapshot       `json:"total"`
+	Source        string              `json:"source"`
+	Baggage       *BaggageSnapshot    `json:"baggage,omitempty"`
+	Ancillaries   []AncillarySnapshot `json:"ancillaries,omitempty"`
+	DistanceKm    float64             `json:"distanceKm,omitempty"`
+	CO2Kg         float64             `json:"co2Kg,omitempty"`
 }
 
 type FlightsSnapshot []FlightSnapshot
 
+type AllowanceSnapshot struct {
+	Pieces   int     `json:"pieces"`
+	WeightKg float64 `json:"weightKg,omitempty"`
+}
+
+type BaggageSnapshot struct {
+	CarryOn AllowanceSnapshot `json:"carryOn"`
+	Checked AllowanceSnapshot `json:"checked"`
+}
+
+type AncillarySnapshot struct {
+	Type        AncillaryKind `json:"type"`
+	Description string        `json:"description,omitempty"`
+	Price       TotalSnapshot `json:"price"`
+}
+
 func (t Total) Snapshot() TotalSnapshot {
 	return TotalSnapshot{
 		Amount:   t.amount,
@@ -33,14 +69,56 @@ func (t Total) Snapshot() TotalSnapshot {
 	}
 }
 
+func (a Allowance) Snapshot() AllowanceSnapshot {
+	return AllowanceSnapshot{
+		Pieces:   a.pieces,
+		WeightKg: a.weightKg,
+	}
+}
+
+// Snapshot returns nil when the allowance is unknown, so that it is left out of the JSON.
+func (b Baggage) Snapshot() *BaggageSnapshot {
+	if !b.known {
+		return nil
+	}
+	return &BaggageSnapshot{
+		CarryOn: b.carryOn.Snapshot(),
+		Checked: b.checked.Snapshot(),
+	}
+}
+
+func (a Ancillary) Snapshot() AncillarySnapshot {
+	return AncillarySnapshot{
+		Type:        a.kind,
+		Description: a.description,
+		Price:       a.price.Snapshot(),
+	}
+}
+
+// Snapshot also exposes the marketing carrier of the segment, its name when the airline is known,
+// and the operating carrier and flight number of codeshare segments.
 func (s Segment) Snapshot() SegmentSnapshot {
-	return SegmentSnapshot{
+	snapshot := SegmentSnapshot{
 		FlightNumber: s.flightNumber,
+		Carrier:      s.Carrier(),
 		Departure:    s.departure,
 		Arrival:      s.arrival,
 		DepartTime:   s.departTime,
 		ArriveTime:   s.arriveTime,
+		Cabin:        s.cabin,
+		BookingClass: s.bookingClass,
+		FareBasis:    s.fareBasis,
+	}
+	if a, ok := reference.LookupAirline(snapshot.Carrier); ok {
+		snapshot.CarrierName = a.Name
 	}
+	snapshot.Baggage = s.baggage.Snapshot()
+	snapshot.DistanceKm, snapshot.CO2Kg = roundedFootprint(s.DistanceKm, s.CO2Kg)
+	if s.IsCodeshare() {
+		snapshot.OperatingCarrier = s.OperatingCarrier()
+		snapshot.OperatingFlightNumber = s.OperatingFlightNumber()
+	}
+	return snapshot
 }
 
 func (f Flight) Snapshot() FlightSnapshot {
@@ -48,14 +126,32 @@ func (f Flight) Snapshot() FlightSnapshot {
 	for i, s := range f.segments {
 		segs[i] = s.Snapshot()
 	}
-	return FlightSnapshot{
+	var ancillaries []AncillarySnapshot
+	for _, a := range f.ancillaries {
+		ancillaries = append(ancillaries, a.Snapshot())
+	}
+	snapshot := FlightSnapshot{
 		ID:            f.id,
 		Status:        f.status,
 		PassengerName: f.passengerName,
 		Segments:      segs,
 		Total:         f.total.Snapshot(),
 		Source:        f.source,
+		Baggage:       f.baggage.Snapshot(),
+		Ancillaries:   ancillaries,
+	}
+	snapshot.DistanceKm, snapshot.CO2Kg = roundedFootprint(f.DistanceKm, f.CO2Kg)
+	return snapshot
+}
+
+// roundedFootprint returns the distance rounded to the kilometre and the emissions rounded to 100 g, or zeros when unknown.
+func roundedFootprint(distance, co2 func() (float64, bool)) (float64, float64) {
+	km, ok := distance()
+	if !ok {
+		return 0, 0
 	}
+	kg, _ := co2()
+	return math.Round(km), math.Round(kg*10) / 10
 }
 
 func (fs Flights) ToSnapshot() FlightsSnapshot {
@@ -65,3 +161,31 @@ func (fs Flights) ToSnapshot() FlightsSnapshot {
 	}
 	return out
 }
+
+type ProviderRefSnapshot struct {
+	Source string `json:"source"`
+	ID     string `json:"id"`
+}
+
+type MergedFlightSnapshot struct {
+	FlightSnapshot
+	Providers []ProviderRefSnapshot `json:"providers"`
+}
+
+func (p ProviderRef) Snapshot() ProviderRefSnapshot {
+	return ProviderRefSnapshot{
+		Source: p.source,
+		ID:     p.id,
+	}
+}
+
+func (m MergedFlight) Snapshot() MergedFlightSnapshot {
+	providers := make([]ProviderRefSnapshot, len(m.providers))
+	for i, p := range m.providers {
+		providers[i] = p.Snapshot()
+	}
+	return MergedFlightSnapshot{
+		FlightSnapshot: m.flight.Snapshot(),
+		Providers:      providers,
+	}
+}
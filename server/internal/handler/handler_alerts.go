@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"aggregator/internal/alerts"
+	"aggregator/internal/catalogue"
+	"aggregator/internal/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HandleAlerts handles the price alert endpoints:
+// "GET /alerts" lists the alerts, "POST /alerts" creates one, "GET|PUT|DELETE /alerts/{id}" reads, replaces or
+// deletes one, and "GET /alerts/dead-letters" lists the alert webhooks whose every attempt failed.
+// Secrets are only returned when an alert is created. Alerts are evaluated when created or replaced, then on every
+// catalogue change. Every request requires the API_TOKEN, reads included, since alerts hold receiver URLs and their
+// notifications.
+func HandleAlerts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Println("["+r.Method+"]", r.URL.Path, time.Now().Format("2006-01-02 15:04:05"))
+
+	if !authorized(w, r) {
+		return
+	}
+
+	var parts = strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		list := alerts.Default.List()
+		for i := range list {
+			list[i] = list[i].Redacted()
+		}
+		writeJSON(w, http.StatusOK, list)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		alert, ok := decodeAlert(w, r)
+		if !ok {
+			return
+		}
+		created, err := alerts.Default.Create(alert)
+		if err != nil {
+			http.Error(w, "create alert: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		evaluateAlerts()
+		w.Header().Set("Location", "/alerts/"+created.ID)
+		writeJSON(w, http.StatusCreated, created)
+	case len(parts) == 2 && parts[1] == "dead-letters" && r.Method == http.MethodGet:
+		var dead []webhook.Delivery
+		for _, d := range webhook.Default.DeadLetters() {
+			if d.Event == alerts.EventPriceBelow {
+				dead = append(dead, d)
+			}
+		}
+		if dead == nil {
+			dead = []webhook.Delivery{}
+		}
+		writeJSON(w, http.StatusOK, dead)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		alert, err := alerts.Default.Get(parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, alert.Redacted())
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		alert, ok := decodeAlert(w, r)
+		if !ok {
+			return
+		}
+		updated, err := alerts.Default.Update(parts[1], alert)
+		if errors.Is(err, alerts.ErrAlertNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "update alert: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		evaluateAlerts()
+		writeJSON(w, http.StatusOK, updated.Redacted())
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		err := alerts.Default.Delete(parts[1])
+		if errors.Is(err, alerts.ErrAlertNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "delete alert: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) <= 2:
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// evaluateAlerts evaluates the alerts against the current catalogue, so that a new or replaced alert whose price is
+// already below its threshold is notified without waiting for the catalogue to change.
+func evaluateAlerts() {
+	if current := catalogue.Default.Current(); current.Number > 0 {
+		alerts.Default.Record(catalogue.Version{}, current)
+	}
+}
+
+// decodeAlert reads and validates the alert of a request body, writing a 400 when it is invalid.
+func decodeAlert(w http.ResponseWriter, r *http.Request) (alerts.Alert, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	var in alerts.Input
+	if err := dec.Decode(&in); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return alerts.Alert{}, false
+	}
+	alert, err := alerts.ParseAlert(in)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return alerts.Alert{}, false
+	}
+	return alert, true
+}
+
+// writeJSON writes a response body as JSON with the given status.
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"aggregator/internal/config"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// authorized reports whether a request on alerts or webhooks carries the API_TOKEN as a bearer token, writing a 401
+// when it does not, and a 403 when no token is configured, which disables those endpoints.
+// They make the server call URLs of the caller's choosing and expose those URLs, which may embed credentials, and the
+// bodies sent to them, so anonymous pages, allowed by the wildcard CORS policy, must not reach them.
+func authorized(w http.ResponseWriter, r *http.Request) bool {
+	if config.API_TOKEN == "" {
+		http.Error(w, "forbidden: set API_TOKEN to manage alerts and webhooks", http.StatusForbidden)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(config.API_TOKEN)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="aggregator"`)
+		http.Error(w, "unauthorized: a valid bearer token is required", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// snapshot caches the repository built over the latest catalogue version read by a request.
+var snapshot struct {
+	sync.Mutex
+	number uint64
+	multi  *repo.Multi
+}
+
+// GetMultiRepo returns a repository over the catalogue version a request reads, and the number of that version:
+// the version of its "cursor" while it is retained, so that every page of a list comes from the same version,
+// and the current version otherwise. The catalogue is refreshed in the background by RefreshPeriodically; it is
+// only loaded here, from both providers, while it has no version yet.
+// On failure it writes the error response (502 for upstream failures, 410 for a cursor whose version was dropped,
+// 500 otherwise) and returns nil.
+func GetMultiRepo(w http.ResponseWriter, r *http.Request) (*repo.Multi, uint64) {
+	current, ok := GetCatalogue(w, r)
+	if !ok {
+		return nil, 0
+	}
+
+	// malformed cursors are left to the pagination, which rejects them with a 400
+	if c, err := service.DecodeCursor(r.URL.Query().Get("cursor")); err == nil && c.Version != current.Number {
+		version, ok := catalogue.Default.Version(c.Version)
+		if !ok {
+			http.Error(w, fmt.Sprintf("%v: catalogue version %d is no longer available, start again from the first page",
+				service.ErrCursorExpired, c.Version), http.StatusGone)
+			return nil, 0
+		}
+		return repo.FromFlights(version.Flights), version.Number
+	}
+
+	snapshot.Lock()
+	defer snapshot.Unlock()
+	if snapshot.multi == nil || snapshot.number != current.Number {
+		snapshot.number, snapshot.multi = current.Number, repo.FromFlights(current.Flights)
+	}
+	return snapshot.multi, current.Number
+}
+
+// GetCatalogue returns the current catalogue version, loading it from both providers while there is none yet.
+// On failure it writes the error response (502 for upstream failures, 500 otherwise) and reports false.
+// Handlers reading state that follows the catalogue, such as the change feed, the search index or the schedule
+// tracker, call it first: before the first version, the catalogue is only loaded there.
+func GetCatalogue(w http.ResponseWriter, r *http.Request) (catalogue.Version, bool) {
+	if current := catalogue.Default.Current(); current.Number != 0 {
+		return current, true
+	}
+	if err := RefreshCatalogue(r.Context()); err != nil {
+		status := http.StatusInternalServerError
+		var upstream *repo.UpstreamError
+		if errors.As(err, &upstream) {
+			status = http.StatusBadGateway
+		}
+		http.Error(w, err.Error(), status)
+		return catalogue.Version{}, false
+	}
+	return catalogue.Default.Current(), true
+}
+
+// RefreshPeriodically refreshes the catalogue at every interval, so that requests, streams, alerts, webhooks and the
+// schedule tracker follow the changes made upstream without a request waiting for the providers.
+// It refreshes once right away and returns when the context is done.
+func RefreshPeriodically(ctx context.Context, interval time.Duration) {
+	if err := RefreshCatalogue(ctx); err != nil {
+		fmt.Println("refresh catalogue:", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := RefreshCatalogue(ctx); err != nil {
+				fmt.Println("refresh catalogue:", err)
+			}
+		}
+	}
+}
+
+// RefreshCatalogue fetches both providers and updates the shared catalogue, notifying its listeners.
+func RefreshCatalogue(ctx context.Context) error {
+	multi, err := repo.LoadConfigured(ctx)
+	if err != nil {
+		return err
+	}
+	flights, err := multi.List(ctx)
+	if err != nil {
+		return err
+	}
+	catalogue.Default.Update(flights)
+	return nil
+}
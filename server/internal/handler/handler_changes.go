@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"aggregator/internal/changes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ChangesResponse is the body of /changes: the current catalogue version, the run of the server it belongs to, and the
+// changes leading to it.
+type ChangesResponse struct {
+	Epoch   string           `json:"epoch"`
+	Version uint64           `json:"version"`
+	Changes []changes.Change `json:"changes"`
+}
+
+// GetChanges handles HTTP GET requests on "/changes?since={version}&epoch={epoch}" returning, oldest first, the diff of every
+// catalogue version after "since" (0, the default, meaning from the start). Clients store the returned version and
+// epoch and pass them as "since" and "epoch" on their next call. Responds 400 on an invalid version and 410 when the
+// changes following it have left the buffer or the server restarted since, in which case the client must resync from
+// /flights.
+func GetChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	var since uint64
+	if s := r.URL.Query().Get("since"); s != "" {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			http.Error(w, "since must be a catalogue version", http.StatusBadRequest)
+			return
+		}
+		since = v
+	}
+	fmt.Println("[GET] /changes?since=", since, time.Now().Format("2006-01-02 15:04:05"))
+
+	if _, ok := GetCatalogue(w, r); !ok {
+		return
+	}
+
+	epoch := r.URL.Query().Get("epoch")
+	if epoch != "" && epoch != changes.Default.Epoch() {
+		http.Error(w, fmt.Sprintf("%v: epoch %q is from before a restart, the current epoch is %q",
+			changes.ErrVersionGone, epoch, changes.Default.Epoch()), http.StatusGone)
+		return
+	}
+
+	list, version, err := changes.Default.Since(since)
+	if errors.Is(err, changes.ErrVersionGone) {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(w, "changes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(ChangesResponse{Epoch: changes.Default.Epoch(), Version: version, Changes: list}); err != nil {
+		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"aggregator/internal/config"
+	"aggregator/internal/domain"
+	"aggregator/internal/service"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GetFlightsMerged handles HTTP GET requests returning the aggregated flights with cross-provider duplicates merged.
+// The conflict policy defaults to the configured one and can be overridden with the "policy" and "priority" query params.
+// Like the other list endpoints, it accepts "limit" and "cursor" to return one page of merged records.
+// Responds with JSON on success, 400 on an unknown policy, or an error status when fetching or merging fails.
+func GetFlightsMerged(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	policyName := query.Get("policy")
+	if policyName == "" {
+		policyName = config.DEDUP_POLICY
+	}
+	policy, err := service.ParseMergePolicy(policyName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	priority := config.PROVIDER_PRIORITY
+	if p := query.Get("priority"); p != "" {
+		priority = strings.Split(p, ",")
+	}
+
+	fmt.Println("[GET] /flights/merged?policy=", policy, time.Now().Format("2006-01-02 15:04:05"))
+
+	multi, version := GetMultiRepo(w, r)
+	if multi == nil {
+		return
+	}
+
+	merged, err := service.MergeFlights(ctx, multi, service.MergeOptions{Policy: policy, Priority: priority})
+	if err != nil {
+		http.Error(w, "merge flights: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// records are paginated by their canonical booking, then written with the providers they merge
+	byFlight := make(map[[2]string]domain.MergedFlight, len(merged))
+	flights := make(domain.Flights, len(merged))
+	for i, m := range merged {
+		flights[i] = m.Flight()
+		byFlight[[2]string{flights[i].Source(), flights[i].ID()}] = m
+	}
+	writeFlightsWith(w, r, flights, service.DefaultOrdering, version, func(fs domain.Flights) any {
+		snapshots := make([]domain.MergedFlightSnapshot, len(fs))
+		for i, f := range fs {
+			snapshots[i] = byFlight[[2]string{f.Source(), f.ID()}].Snapshot()
+		}
+		return snapshots
+	})
+}
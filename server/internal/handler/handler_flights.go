@@ -1,13 +1,8 @@
 package handler
 
 import (
-	"aggregator/internal/api"
-	"aggregator/internal/config"
 	"aggregator/internal/domain"
-	"aggregator/internal/repo"
 	"aggregator/internal/service"
-	"bytes"
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,6 +15,9 @@ import (
 var errNotAllowed = errors.New("method not allowed")
 
 // GetFlights is an HTTP handler that retrieves and returns a list of flights in JSON format for GET requests.
+// The filter params, listed by service.ParseQuery, are combined into a service.Query applied in one pass; invalid
+// filters yield a 400. An optional "sort" param orders the result like /flights/sorted, and "cursor" and "limit"
+// page it.
 // Responds with an error if the method is not GET or if any issues occur during the processing.
 func GetFlights(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -29,26 +27,54 @@ func GetFlights(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
-	fmt.Println("[GET] /flights", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Println("[GET] /flights", r.URL.RawQuery, time.Now().Format("2006-01-02 15:04:05"))
 
-	multi := GetMultiRepo(ctx, w)
+	query, ok := parseFilters(w, r)
+	if !ok {
+		return
+	}
+	ordering := service.DefaultOrdering
+	if s := r.URL.Query().Get("sort"); s != "" {
+		spec, err := service.ParseSort(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ordering = spec.Ordering()
+	}
+
+	multi, version := GetMultiRepo(w, r)
+	if multi == nil {
+		return
+	}
 
-	flights, err := multi.List(ctx)
+	flights, err := service.FilterFlights(ctx, multi, query)
 	if err != nil {
 		http.Error(w, "list flights: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	snapshot := flights.ToSnapshot()
+	if ordering.Name != service.DefaultOrdering.Name {
+		ordering.Sort(flights)
+	}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
-		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	writeFlights(w, r, flights, ordering, version)
+}
+
+// parseFilters parses the filters of /flights, which every endpoint listing flights applies to its result, writing a
+// 400 when they are invalid.
+func parseFilters(w http.ResponseWriter, r *http.Request) (service.Query, bool) {
+	query, err := service.ParseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return service.Query{}, false
 	}
+	return query, true
 }
 
 // GetFlightById handles HTTP GET requests to retrieve a flight by its unique ID from the endpoints repository system.
 // It validates the HTTP method, processes the request context, and fetches flight data for a given ID.
 // Returns the flight details in JSON format or an appropriate HTTP error status in case of failure.
+// "/flights/id/{id}/history" returns the price history of the booking instead.
 func GetFlightById(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
@@ -65,10 +91,16 @@ func GetFlightById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var id = parts[3]
+	if len(parts) > 4 && parts[4] == "history" {
+		GetFlightHistory(w, r, id)
+		return
+	}
 	fmt.Println("[GET] /flights/id/", id, time.Now().Format("2006-01-02 15:04:05"))
 
-	multi := GetMultiRepo(ctx, w)
-
+	multi, _ := GetMultiRepo(w, r)
+	if multi == nil {
+		return
+	}
 	var flight, err = multi.FindByID(ctx, id)
 	if err != nil {
 		http.Error(w, "flights/id/:id: "+err.Error(), http.StatusNotFound)
@@ -85,7 +117,9 @@ func GetFlightById(w http.ResponseWriter, r *http.Request) {
 
 // GetFlightByNumber handles HTTP GET requests to retrieve flight details by its number from multiple repositories.
 // Returns flight details in JSON format or an appropriate HTTP error response if the flight is not found.
-// Expects the flight number as part of the URL path in the format "/flights/number/{flightNumber}".
+// Expects the flight number as part of the URL path in the format "/flights/number/{flightNumber}"; "AF 276" and "AF0276"
+// both designate AF276. With "all=true" every booking on the flight is returned, optionally scoped by "date" (YYYY-MM-DD)
+// and narrowed by the filters of /flights.
 func GetFlightByNumber(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
@@ -102,10 +136,40 @@ func GetFlightByNumber(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var number = parts[3]
+	query, all, err := service.ParseFlightBookingsQuery(number, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	fmt.Println("[GET] /flights/number/", number, time.Now().Format("2006-01-02 15:04:05"))
 
-	multi := GetMultiRepo(ctx, w)
-	var flight, err = multi.FindByNumber(ctx, number)
+	var filters service.Query
+	if all {
+		var ok bool
+		if filters, ok = parseFilters(w, r); !ok {
+			return
+		}
+	}
+
+	multi, version := GetMultiRepo(w, r)
+	if multi == nil {
+		return
+	}
+
+	if all {
+		flights, err := service.FindFlightBookings(ctx, multi, query)
+		if err == nil {
+			flights, err = applyFilters(filters, flights)
+		}
+		if err != nil {
+			http.Error(w, "flights/number/:number: "+err.Error(), http.StatusNotFound)
+			return
+		}
+		writeFlights(w, r, flights, service.DefaultOrdering, version)
+		return
+	}
+
+	flight, err := multi.FindByNumber(ctx, number)
 	if err != nil {
 		http.Error(w, "flights/number/:number: "+err.Error(), http.StatusNotFound)
 		return
@@ -121,7 +185,8 @@ func GetFlightByNumber(w http.ResponseWriter, r *http.Request) {
 
 // GetFlightsByPassenger handles retrieving flights based on a given passenger's name from multiple repositories.
 // It accepts only GET requests and expects the passenger's name in the URL path as the fourth segment.
-// Returns a JSON response with a list of flights or an error message in case of failure.
+// The filters of /flights narrow the matches. Returns a JSON response with a list of flights or an error message in
+// case of failure.
 func GetFlightsByPassenger(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
@@ -138,28 +203,44 @@ func GetFlightsByPassenger(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var passengerName = parts[3]
+	search, err := service.ParsePassengerSearch(passengerName, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	fmt.Println("[GET] /flights/passengerName/", passengerName, time.Now().Format("2006-01-02 15:04:05"))
+	filters, ok := parseFilters(w, r)
+	if !ok {
+		return
+	}
 
-	multi := GetMultiRepo(ctx, w)
-	var flights, err = multi.FindByPassenger(ctx, passengerName)
+	multi, version := GetMultiRepo(w, r)
+	if multi == nil {
+		return
+	}
+	matches, err := service.SearchPassengers(ctx, multi, search)
+	var flights domain.Flights
+	if err == nil {
+		flights, err = applyFilters(filters, matches.Flights())
+	}
 	if err != nil {
 		http.Error(w, "flights/passengerName/:passengerName: "+err.Error(), http.StatusNotFound)
 		return
 	}
 
-	snapshot := flights.ToSnapshot()
-
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
-		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	if !search.Fuzzy {
+		writeFlights(w, r, flights, service.DefaultOrdering, version)
+		return
 	}
+	writeFlightsWith(w, r, flights, matches.Ordering(), version, func(fs domain.Flights) any { return matches.Snapshot(fs) })
 }
 
 // GetFlightsByDestination handles GET requests to retrieve flights based on departure and arrival destinations.
 // It expects a JSON payload containing "departure" and "arrival" fields and returns matching flights in JSON format.
 // If the method is not GET, it responds with a "method not allowed" error.
 // The function limits the request body size to 1MB and ensures only valid JSON is processed.
-// It uses a multi-repository to search for flights and returns an error if no matches are found or on processing failures.
+// It uses a multi-repository to search for flights, narrowed by the filters of /flights given as query params, and
+// returns an error if no matches are found or on processing failures.
 func GetFlightsByDestination(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
@@ -170,6 +251,10 @@ func GetFlightsByDestination(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	fmt.Println("[GET] /flights/destination", time.Now().Format("2006-01-02 15:04:05"))
+	filters, ok := parseFilters(w, r)
+	if !ok {
+		return
+	}
 	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
 
 	dec := json.NewDecoder(r.Body)
@@ -186,25 +271,27 @@ func GetFlightsByDestination(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	multi := GetMultiRepo(ctx, w)
-
+	multi, version := GetMultiRepo(w, r)
+	if multi == nil {
+		return
+	}
 	var flights, err = multi.FindByDestination(ctx, req.Departure, req.Arrival)
+	if err == nil {
+		flights, err = applyFilters(filters, flights)
+	}
 	if err != nil {
 		http.Error(w, "flights/destination "+err.Error(), http.StatusNotFound)
 		return
 	}
 
-	snapshot := flights.ToSnapshot()
-
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
-		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
-	}
+	writeFlights(w, r, flights, service.DefaultOrdering, version)
 }
 
-// GetFlightsByPrice handles HTTP GET requests to fetch flights filtered by a specified price.
-// It extracts the price from the URL path, queries multiple repositories, and returns matching flights in JSON format.
-// Responds with appropriate HTTP status codes for errors like bad requests, method not allowed, or data not found.
+// GetFlightsByPrice handles HTTP GET requests to fetch flights filtered by price.
+// "/flights/price/{price}" matches the price exactly, or within ± the "tolerance" query param (percent) when given.
+// "/flights/price?min=&max=" matches a range. An optional "currency" param converts every total into that currency
+// before comparison. The filters of /flights narrow the result. Responds with 400 on an invalid number or filter, 404
+// when nothing matches, and JSON otherwise.
 func GetFlightsByPrice(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
@@ -214,36 +301,68 @@ func GetFlightsByPrice(w http.ResponseWriter, r *http.Request) {
 	var ctx = r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
-	var parts = strings.Split(r.URL.Path, "/")
-	if len(parts) < 4 {
-		http.Error(w, "PassengerName is not provided", http.StatusBadRequest)
-		return
+	var priceStr string
+	if parts := strings.Split(r.URL.Path, "/"); len(parts) >= 4 {
+		priceStr = parts[3]
 	}
+	fmt.Println("[GET] /flights/price/", priceStr, r.URL.RawQuery, time.Now().Format("2006-01-02 15:04:05"))
 
-	var priceStr = parts[3]
-	fmt.Println("[GET] /flights/price/", priceStr, time.Now().Format("2006-01-02 15:04:05"))
-
-	var price, _ = strconv.ParseFloat(priceStr, 64)
-
-	multi := GetMultiRepo(ctx, w)
-
-	var flights, err = multi.FindByPrice(ctx, price)
+	var (
+		priceRange service.PriceRange
+		err        error
+		query      = r.URL.Query()
+	)
+	if priceStr != "" {
+		var price, tolerance float64
+		if price, err = service.ParsePrice(priceStr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if s := query.Get("tolerance"); s != "" {
+			if tolerance, err = strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64); err != nil {
+				http.Error(w, "invalid tolerance: "+s, http.StatusBadRequest)
+				return
+			}
+		}
+		priceRange, err = service.AroundPrice(price, tolerance, query.Get("currency"))
+	} else {
+		priceRange, err = service.ParsePriceRange(query)
+	}
 	if err != nil {
-		http.Error(w, "flights/price/:price: "+err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters, ok := parseFilters(w, r)
+	if !ok {
 		return
 	}
 
-	snapshot := flights.ToSnapshot()
+	multi, version := GetMultiRepo(w, r)
+	if multi == nil {
+		return
+	}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
-		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	flights, err := service.FindByPriceRange(ctx, multi, priceRange)
+	if err == nil {
+		flights, err = applyFilters(filters, flights)
+	}
+	if errors.Is(err, domain.ErrFlightsNotFound) {
+		http.Error(w, "flights/price/:price: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "find flights by price: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
+
+	writeFlights(w, r, flights, service.DefaultOrdering, version)
 }
 
-// GetFlightsSorted handles HTTP GET requests to return a list of flights sorted by a specified type (e.g., price, time).
-// It validates the method, parses the query parameter for sorting type, fetches flight data, and sorts accordingly.
-// Supported sorting types include "price", "time", and "departure". Responds with JSON on success or an error message on failure.
+// GetFlightsSorted handles HTTP GET requests to return a list of flights sorted on one or several keys.
+// The "sort" query param takes a specification such as "price:asc,departure:desc"; the legacy "type" param
+// ("price", "time", "departure", ...) sorts ascending on a single key. Ties are broken by source then id,
+// and flights without segments are placed last. The filters of /flights narrow the result. Responds with JSON on success
+// or an error message on failure.
 func GetFlightsSorted(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
@@ -253,74 +372,52 @@ func GetFlightsSorted(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
-	sortType := strings.ToLower(r.URL.Query().Get("type"))
-
-	if sortType == "" {
-		http.Error(w, "missing query param: type", http.StatusBadRequest)
+	query := r.URL.Query()
+	sortParam := query.Get("sort")
+	if sortParam == "" {
+		sortParam = query.Get("type")
+	}
+	if sortParam == "" {
+		http.Error(w, "missing query param: sort or type", http.StatusBadRequest)
 		return
 	}
 
-	fmt.Println("[GET] /flights/sorted?type=",
-		sortType, time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Println("[GET] /flights/sorted?sort=",
+		sortParam, time.Now().Format("2006-01-02 15:04:05"))
 
-	multi := GetMultiRepo(ctx, w)
-	if multi == nil {
+	spec, err := service.ParseSort(sortParam)
+	if err != nil {
+		http.Error(w, "invalid sort: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters, ok := parseFilters(w, r)
+	if !ok {
 		return
 	}
-	var (
-		flights domain.Flights
-		err     error
-	)
 
-	switch sortType {
-	case "price":
-		flights, err = service.SortByPrice(ctx, multi)
-	case "time", "timetravel", "duration":
-		flights, err = service.SortByTimeTravel(ctx, multi)
-	case "departure", "depart", "departure_date":
-		flights, err = service.SortByDepartureDate(ctx, multi)
-	default:
-		http.Error(w, "invalid sort type: "+sortType, http.StatusBadRequest)
+	multi, version := GetMultiRepo(w, r)
+	if multi == nil {
 		return
 	}
+
+	flights, err := service.SortFlights(ctx, multi, spec)
 	if err != nil {
 		http.Error(w, "sort flights: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	flights = filters.Apply(flights)
 
-	snapshot := flights.ToSnapshot()
-
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
-		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
-	}
+	writeFlights(w, r, flights, spec.Ordering(), version)
 }
 
-func GetMultiRepo(ctx context.Context, w http.ResponseWriter) *repo.Multi {
-	b1, err := api.GetDataFromApi(ctx, config.SERVER1_URL+"flights")
-	if err != nil {
-		http.Error(w, "fetch flights: "+err.Error(), http.StatusBadGateway)
-		return nil
-	}
-
-	b2, err := api.GetDataFromApi(ctx, config.SERVER2_URL+"flight_to_book")
-	if err != nil {
-		http.Error(w, "fetch flight_to_book: "+err.Error(), http.StatusBadGateway)
-		return nil
+// applyFilters returns the flights found by an endpoint that match the filters, and domain.ErrFlightsNotFound when
+// none do, so that the endpoint answers 404 as when it finds nothing.
+func applyFilters(filters service.Query, flights domain.Flights) (domain.Flights, error) {
+	flights = filters.Apply(flights)
+	if len(flights) == 0 {
+		return nil, domain.ErrFlightsNotFound
 	}
-
-	rA, err := repo.NewRepoFlightsFromReader(bytes.NewReader(b1))
-	if err != nil {
-		http.Error(w, "decode flights: "+err.Error(), http.StatusInternalServerError)
-		return nil
-	}
-	rB, err := repo.NewRepoFlightToBookFromReader(bytes.NewReader(b2))
-	if err != nil {
-		http.Error(w, "decode flight_to_book: "+err.Error(), http.StatusInternalServerError)
-		return nil
-	}
-
-	return repo.NewMulti(rA, rB)
+	return flights, nil
 }
 
 // FlightDestinationRequest represents a request for searching flights based on departure and arrival locations.
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"aggregator/internal/history"
+	"aggregator/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// GetFlightHistory writes the prices recorded for a booking id across catalogue refreshes, oldest first.
+// The history is recorded as the catalogue is refreshed in the background, so it is served even while the providers are
+// down. Responds 404 when none was recorded.
+func GetFlightHistory(w http.ResponseWriter, r *http.Request, id string) {
+	fmt.Println("[GET] /flights/id/", id, "/history", time.Now().Format("2006-01-02 15:04:05"))
+
+	entries := history.Default.ByID(id)
+	if len(entries) == 0 {
+		http.Error(w, "flights/id/:id/history: no history for "+id, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(service.BuildFlightHistory(id, entries)); err != nil {
+		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetRouteHistory handles "/routes/{from}-{to}/history": for every departure date, or only the "date" query param,
+// how the lowest price of the route, normalized into "priceCurrency" (EUR by default), evolved across refreshes.
+// Like the booking history, it is served from the recorded history without calling the providers.
+func GetRouteHistory(w http.ResponseWriter, r *http.Request, route string) {
+
+	query, err := service.ParseRouteHistoryQuery(route, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fmt.Println("[GET] /routes/", route, "/history", r.URL.RawQuery, time.Now().Format("2006-01-02 15:04:05"))
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(service.BuildRouteHistory(history.Default.ByRoute(query.Route), query)); err != nil {
+		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
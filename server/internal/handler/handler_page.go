@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// FlightsPage is the response body of list endpoints when a page is requested with "limit" or "cursor".
+type FlightsPage struct {
+	Data    any    `json:"data"`
+	Total   int    `json:"total"`
+	Limit   int    `json:"limit"`
+	Version uint64 `json:"version"`
+	Next    string `json:"next,omitempty"`
+	Prev    string `json:"prev,omitempty"`
+}
+
+// writeFlights writes a list of flights, read from a catalogue version, as JSON. Without pagination params the whole
+// list is written as an array; with "limit" or "cursor" the list is sorted by the ordering and one FlightsPage is
+// written instead.
+func writeFlights(w http.ResponseWriter, r *http.Request, flights domain.Flights, ordering service.Ordering, version uint64) {
+	writeFlightsWith(w, r, flights, ordering, version, func(fs domain.Flights) any { return fs.ToSnapshot() })
+}
+
+// writeFlightsWith is writeFlights with a custom JSON representation of the written flights.
+func writeFlightsWith(w http.ResponseWriter, r *http.Request, flights domain.Flights, ordering service.Ordering, version uint64,
+	render func(domain.Flights) any) {
+	values := r.URL.Query()
+	if !service.IsPaginated(values) {
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(render(flights)); err != nil {
+			http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	req, err := service.ParsePageRequest(r.URL.Path, values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ordering.Sort(flights)
+	page, err := service.Paginate(flights, ordering, req, version)
+	if errors.Is(err, service.ErrCursorExpired) {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body := FlightsPage{
+		Data:    render(page.Flights),
+		Total:   page.Total,
+		Limit:   req.Limit,
+		Version: version,
+	}
+	if page.Next != nil {
+		body.Next = pageLink(r, values, page.Next)
+	}
+	if page.Prev != nil {
+		body.Prev = pageLink(r, values, page.Prev)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// pageLink returns the URL of the request with its cursor replaced by the given one.
+func pageLink(r *http.Request, values url.Values, cursor *service.Cursor) string {
+	next := url.Values{}
+	for k, v := range values {
+		next[k] = v
+	}
+	next.Set("cursor", cursor.Encode())
+	return r.URL.EscapedPath() + "?" + next.Encode()
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"aggregator/internal/schedule"
+	"aggregator/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GetReconciliationReport handles HTTP GET requests comparing the data of all providers for the same bookings.
+// The "format" query param selects "json" (default) or "csv" output.
+// Responds with the list of mismatching fields, 400 on an unknown format, or an error status on fetch failures.
+func GetReconciliationReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "invalid format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	fmt.Println("[GET] /reports/reconciliation?format=", format, time.Now().Format("2006-01-02 15:04:05"))
+
+	multi, _ := GetMultiRepo(w, r)
+	if multi == nil {
+		return
+	}
+
+	report, err := service.ReconcileFlights(ctx, multi)
+	if err != nil {
+		http.Error(w, "reconcile flights: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="reconciliation.csv"`)
+		w.WriteHeader(http.StatusOK)
+		if err := report.WriteCSV(w); err != nil {
+			http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetScheduleChangesReport handles HTTP GET requests listing the bookings whose segments were retimed between
+// catalogue refreshes, with the connections now broken or under the minimum connection time and the passengers affected.
+// The "severity" (retimed, under_minimum or broken) and "minDelay" (minutes) query params filter the changes, and
+// "format" selects "json" (default) or "csv" output.
+// Responds with the report, 400 on invalid params, or an error status on fetch failures.
+func GetScheduleChangesReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "invalid format: "+format, http.StatusBadRequest)
+		return
+	}
+	q, err := service.ParseScheduleQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fmt.Println("[GET] /reports/schedule-changes?", r.URL.RawQuery, time.Now().Format("2006-01-02 15:04:05"))
+
+	if _, ok := GetCatalogue(w, r); !ok {
+		return
+	}
+
+	report := service.BuildScheduleReport(schedule.Default.List(), q)
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="schedule-changes.csv"`)
+		w.WriteHeader(http.StatusOK)
+		if err := report.WriteCSV(w); err != nil {
+			http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
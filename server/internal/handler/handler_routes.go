@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"aggregator/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GetRoutes handles HTTP GET requests on "/routes/{from}-{to}/{view}", where view is "calendar" or "history".
+func GetRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	var parts = strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "routes" {
+		http.NotFound(w, r)
+		return
+	}
+	switch parts[2] {
+	case "calendar":
+		GetRouteCalendar(w, r, parts[1])
+	case "history":
+		GetRouteHistory(w, r, parts[1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GetRouteCalendar handles "/routes/{from}-{to}/calendar?month=YYYY-MM" returning, for every day of the month,
+// the lowest price normalized into "priceCurrency" (EUR by default), the number of options and the cheapest booking,
+// across all providers and for direct and connecting journeys alike. /flights filters also apply.
+func GetRouteCalendar(w http.ResponseWriter, r *http.Request, route string) {
+	ctx := r.Context()
+
+	query, err := service.ParseCalendarQuery(route, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fmt.Println("[GET] /routes/", route, "/calendar", r.URL.RawQuery, time.Now().Format("2006-01-02 15:04:05"))
+
+	multi, _ := GetMultiRepo(w, r)
+	if multi == nil {
+		return
+	}
+
+	calendar, err := service.GetFareCalendar(ctx, multi, query)
+	if err != nil {
+		http.Error(w, "routes/:route/calendar: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(calendar); err != nil {
+		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/search"
+	"aggregator/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// GetSearch handles HTTP GET requests for a full-text search over the aggregated flights, e.g. /search?q=Curie HND January.
+// Every word of "q" must match a passenger name, flight number, airline, airport, city, booking id, status or departure date.
+// The filters of /flights (carrier, alliance, from, to, ...) narrow the results down.
+// Responds with the results ranked by score, each with its highlighted fields, or 400 when "q" has no searchable word.
+func GetSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	values := r.URL.Query()
+	q := values.Get("q")
+	filters, err := service.ParseQuery(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fmt.Println("[GET] /search?q=", q, time.Now().Format("2006-01-02 15:04:05"))
+
+	if multi, _ := GetMultiRepo(w, r); multi == nil {
+		return
+	}
+
+	results, err := search.Default.Search(q)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, search.ErrEmptyQuery) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, "search: "+err.Error(), status)
+		return
+	}
+
+	byFlight := make(map[[2]string]search.Result, len(results))
+	flights := make(domain.Flights, 0, len(results))
+	for _, res := range results {
+		if !filters.Match(res.Flight) {
+			continue
+		}
+		byFlight[[2]string{res.Flight.Source(), res.Flight.ID()}] = res
+		flights = append(flights, res.Flight)
+	}
+	lookup := func(f domain.Flight) search.Result { return byFlight[[2]string{f.Source(), f.ID()}] }
+
+	ordering := service.ScoreOrdering("relevance", func(f domain.Flight) float64 { return lookup(f).Score })
+	writeFlightsWith(w, r, flights, ordering, search.Default.Version(), func(fs domain.Flights) any {
+		snapshots := make([]search.ResultSnapshot, len(fs))
+		for i, f := range fs {
+			snapshots[i] = lookup(f).Snapshot()
+		}
+		return snapshots
+	})
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"aggregator/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// GetStats handles HTTP GET requests summarizing the current catalogue version, without calling the providers:
+// counts by source, status, route and carrier, price statistics per route and average duration and layover. It accepts
+// the filters of /flights, plus "priceCurrency" for the currency prices are normalized into (EUR by default).
+// Invalid filters yield a 400.
+func GetStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	fmt.Println("[GET] /stats", r.URL.RawQuery, time.Now().Format("2006-01-02 15:04:05"))
+
+	query, err := service.ParseStatsQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	current, ok := GetCatalogue(w, r)
+	if !ok {
+		return
+	}
+
+	stats := service.GetStats(current, query)
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		http.Error(w, "encode response: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/changes"
+	"aggregator/internal/service"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// heartbeatInterval is how often an idle stream sends a comment line, keeping proxies from closing the connection.
+const heartbeatInterval = 15 * time.Second
+
+// GetFlightsStream handles HTTP GET requests on "/flights/stream", a Server-Sent Events stream of the bookings matching
+// the filters of /flights. It first sends a "snapshot" event with the matching bookings, then "add", "update" and
+// "remove" events as the catalogue changes, and a comment line every 15 seconds. The id of the last event of every
+// catalogue version is "{epoch}-{version}": a client reconnecting with a "Last-Event-ID" header receives the events it
+// missed, or a new snapshot when they have left the change feed or the server restarted since.
+func GetFlightsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+
+	query, err := service.ParseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	fmt.Println("[GET] /flights/stream", r.URL.RawQuery, time.Now().Format("2006-01-02 15:04:05"))
+
+	if multi, _ := GetMultiRepo(w, r); multi == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// the notification channel is taken before reading the feed, so that no change recorded in between is missed
+	notify := changes.Default.Notify()
+	stream := service.NewFlightStream(query)
+	version, events := startStream(stream, r.Header.Get("Last-Event-ID"))
+	if err := writeEvents(w, events); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-notify:
+			notify = changes.Default.Notify()
+			list, current, err := changes.Default.Since(version)
+			if err != nil {
+				// the stream fell behind the change feed: start over from the current catalogue
+				v := catalogue.Default.Current()
+				list, current, events = nil, v.Number, []service.StreamEvent{stream.Snapshot(v.Number, v.Flights)}
+			} else {
+				events = nil
+			}
+			for _, c := range list {
+				events = append(events, stream.Apply(c)...)
+			}
+			version = current
+			if err := writeEvents(w, events); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// startStream returns the version a stream starts at and its first events: the changes following the Last-Event-ID
+// of a reconnecting client, or a snapshot of the current catalogue.
+func startStream(stream *service.FlightStream, lastEventID string) (uint64, []service.StreamEvent) {
+	// an id from another epoch was sent before a restart and cannot be resumed
+	epoch, number, _ := strings.Cut(strings.TrimSpace(lastEventID), "-")
+	if since, err := strconv.ParseUint(number, 10, 64); err == nil && epoch == changes.Default.Epoch() {
+		list, current, err := changes.Default.Since(since)
+		if err == nil {
+			stream.Resume()
+			var events []service.StreamEvent
+			for _, c := range list {
+				events = append(events, stream.Apply(c)...)
+			}
+			return current, events
+		}
+	}
+	v := catalogue.Default.Current()
+	return v.Number, []service.StreamEvent{stream.Snapshot(v.Number, v.Flights)}
+}
+
+// writeEvents writes events in the text/event-stream format. Only the last event of a catalogue version carries an id,
+// so that a client resuming from it has received the whole version.
+func writeEvents(w io.Writer, events []service.StreamEvent) error {
+	for i, e := range events {
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			return fmt.Errorf("encode event: %w", err)
+		}
+		var b strings.Builder
+		b.WriteString("event: " + e.Type + "\n")
+		if i == len(events)-1 || events[i+1].Version != e.Version {
+			b.WriteString("id: " + changes.Default.Epoch() + "-" + strconv.FormatUint(e.Version, 10) + "\n")
+		}
+		b.WriteString("data: " + string(data) + "\n\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/changes"
+	"aggregator/internal/config"
+	"aggregator/internal/domain"
+	"aggregator/internal/service"
+	"aggregator/internal/ws"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// maxWatchMessage is the largest message accepted from a /watch client, in bytes.
+	maxWatchMessage = 4 << 10
+	// watchWriteTimeout is how long a /watch client may take to accept a message before it is disconnected.
+	watchWriteTimeout = 10 * time.Second
+	// watchPingInterval is how often a /watch client is pinged, so that dead connections are noticed.
+	watchPingInterval = 30 * time.Second
+	// watchReadTimeout is how long a /watch client may stay silent, pongs included, before it is disconnected.
+	watchReadTimeout = 2 * watchPingInterval
+)
+
+// WatchRequest is a message sent by a /watch client: {"type": "subscribe", "id": "s1", "route": "CDG-HND",
+// "date": "2026-01-01"}, {"type": "subscribe", "id": "b1", "booking": "A10001"} or {"type": "unsubscribe", "id": "s1"}.
+type WatchRequest struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	service.Watch
+}
+
+// WatchSnapshot is sent when a subscription starts, and again when its client fell too far behind the change feed.
+type WatchSnapshot struct {
+	Type    string                  `json:"type"`
+	ID      string                  `json:"id"`
+	Version uint64                  `json:"version"`
+	Flights []domain.FlightSnapshot `json:"flights"`
+}
+
+// WatchEvent is an "add", "update" or "remove" event of a subscription, with the data of /flights/stream events.
+type WatchEvent struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Version uint64 `json:"version"`
+	Event   string `json:"event"`
+	Data    any    `json:"data"`
+}
+
+// WatchStatus acknowledges an unsubscription, or reports a request that was rejected.
+type WatchStatus struct {
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// GetWatch handles WebSocket connections on "/watch", on which clients subscribe to the bookings of a route, optionally
+// on one departure date, or to one booking. Every subscription starts with a "snapshot" message, followed by "event"
+// messages as the catalogue changes. A client may hold WS_MAX_SUBSCRIPTIONS subscriptions. A slow client is never
+// queued more than one catalogue change: when it falls behind the change feed it receives new snapshots, and it is
+// disconnected when a message cannot be written within 10 seconds. Clients are pinged every 30 seconds and disconnected
+// after 60 seconds without any frame from them. Browser pages may only connect from the server's own origin or one of
+// WS_ALLOWED_ORIGINS.
+func GetWatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Println("[GET] /watch", time.Now().Format("2006-01-02 15:04:05"))
+
+	if _, ok := GetCatalogue(w, r); !ok {
+		return
+	}
+	conn, err := ws.Upgrade(w, r, ws.Options{MaxSize: maxWatchMessage, WriteTimeout: watchWriteTimeout,
+		ReadTimeout: watchReadTimeout, AllowedOrigins: config.WS_ALLOWED_ORIGINS})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	session := &watchSession{conn: conn, limit: config.WS_MAX_SUBSCRIPTIONS, subs: make(map[string]*watchSubscription)}
+	if err := session.run(); err != nil {
+		var closed *ws.CloseError
+		if !errors.As(err, &closed) {
+			fmt.Println("watch:", err)
+		}
+	}
+}
+
+// watchSubscription is one subscription of a client and the catalogue version it was last sent.
+type watchSubscription struct {
+	stream  *service.FlightStream
+	version uint64
+}
+
+// watchSession serves the subscriptions of one connection. Only run writes to the connection.
+type watchSession struct {
+	conn  *ws.Conn
+	limit int
+	subs  map[string]*watchSubscription
+	// version is the latest catalogue version whose changes were sent.
+	version uint64
+}
+
+// run reads the requests of the client in the background and serves them, and the catalogue changes, until the
+// connection ends. Requests are not buffered: a client sending faster than it reads is slowed down by TCP.
+func (s *watchSession) run() error {
+	requests := make(chan []byte)
+	readErr := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		for {
+			_, payload, err := s.conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case requests <- payload:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	// the notification channel is taken before the version, so that no change recorded in between is missed
+	notify := changes.Default.Notify()
+	s.version = catalogue.Default.Current().Number
+	ping := time.NewTicker(watchPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case err = <-readErr:
+			return err
+		case payload := <-requests:
+			err = s.handle(payload)
+		case <-ping.C:
+			err = s.conn.Ping()
+		case <-notify:
+			notify = changes.Default.Notify()
+			err = s.sync()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle serves one request of the client.
+func (s *watchSession) handle(payload []byte) error {
+	var req WatchRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return s.send(WatchStatus{Type: "error", Error: "invalid JSON: " + err.Error()})
+	}
+	if req.ID == "" {
+		return s.send(WatchStatus{Type: "error", Error: "missing subscription id"})
+	}
+	switch req.Type {
+	case "subscribe":
+		if _, ok := s.subs[req.ID]; ok {
+			return s.send(WatchStatus{Type: "error", ID: req.ID, Error: "subscription id already in use"})
+		}
+		if len(s.subs) >= s.limit {
+			return s.send(WatchStatus{Type: "error", ID: req.ID, Error: fmt.Sprintf("at most %d subscriptions per connection", s.limit)})
+		}
+		watch, err := service.ParseWatch(req.Watch)
+		if err != nil {
+			return s.send(WatchStatus{Type: "error", ID: req.ID, Error: err.Error()})
+		}
+		sub := &watchSubscription{stream: service.NewMatchStream(watch.Match)}
+		s.subs[req.ID] = sub
+		return s.snapshot(req.ID, sub, catalogue.Default.Current())
+	case "unsubscribe":
+		if _, ok := s.subs[req.ID]; !ok {
+			return s.send(WatchStatus{Type: "error", ID: req.ID, Error: "unknown subscription"})
+		}
+		delete(s.subs, req.ID)
+		return s.send(WatchStatus{Type: "unsubscribed", ID: req.ID})
+	default:
+		return s.send(WatchStatus{Type: "error", ID: req.ID, Error: fmt.Sprintf("unknown message type %q", req.Type)})
+	}
+}
+
+// sync sends the events of the changes recorded since the last sync, or new snapshots when they left the feed.
+func (s *watchSession) sync() error {
+	list, current, err := changes.Default.Since(s.version)
+	if err != nil {
+		v := catalogue.Default.Current()
+		s.version = v.Number
+		for _, id := range s.ids() {
+			if err := s.snapshot(id, s.subs[id], v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s.version = current
+	for _, c := range list {
+		for _, id := range s.ids() {
+			sub := s.subs[id]
+			// a subscription started after this change already has it in its snapshot
+			if c.Version <= sub.version {
+				continue
+			}
+			sub.version = c.Version
+			for _, e := range sub.stream.Apply(c) {
+				if err := s.send(WatchEvent{Type: "event", ID: id, Version: e.Version, Event: e.Type, Data: e.Data}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// snapshot sends the bookings of a subscription as of a catalogue version.
+func (s *watchSession) snapshot(id string, sub *watchSubscription, v catalogue.Version) error {
+	e := sub.stream.Snapshot(v.Number, v.Flights)
+	sub.version = v.Number
+	data := e.Data.(service.StreamSnapshot)
+	return s.send(WatchSnapshot{Type: "snapshot", ID: id, Version: data.Version, Flights: data.Flights})
+}
+
+// ids returns the subscription ids in order, so that events are sent in a stable order.
+func (s *watchSession) ids() []string {
+	out := make([]string, 0, len(s.subs))
+	for id := range s.subs {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// send writes one message as JSON text.
+func (s *watchSession) send(message any) error {
+	b, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+	return s.conn.WriteText(b)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"aggregator/internal/hooks"
+	"aggregator/internal/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HandleWebhooks handles the outbound webhook endpoints:
+// "GET /webhooks" lists the endpoints, "POST /webhooks" registers one, "GET|PUT|DELETE /webhooks/{id}" reads,
+// replaces or deletes one, "GET /webhooks/deliveries" lists the latest deliveries, optionally by "owner" (an endpoint
+// or alert id) and "status", and "POST /webhooks/deliveries/{id}/redeliver" sends a delivery again.
+// Secrets are only returned when an endpoint is created. Every request requires the API_TOKEN, reads included, since
+// endpoints and deliveries hold receiver URLs and signed bodies.
+func HandleWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Println("["+r.Method+"]", r.URL.Path, r.URL.RawQuery, time.Now().Format("2006-01-02 15:04:05"))
+
+	if !authorized(w, r) {
+		return
+	}
+
+	var parts = strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) >= 2 && parts[1] == "deliveries":
+		handleDeliveries(w, r, parts[2:])
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		list := hooks.Default.List()
+		for i := range list {
+			list[i] = list[i].Redacted()
+		}
+		writeJSON(w, http.StatusOK, list)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		endpoint, ok := decodeEndpoint(w, r)
+		if !ok {
+			return
+		}
+		created, err := hooks.Default.Create(endpoint)
+		if err != nil {
+			http.Error(w, "create webhook: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Location", "/webhooks/"+created.ID)
+		writeJSON(w, http.StatusCreated, created)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		endpoint, err := hooks.Default.Get(parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, endpoint.Redacted())
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		endpoint, ok := decodeEndpoint(w, r)
+		if !ok {
+			return
+		}
+		updated, err := hooks.Default.Update(parts[1], endpoint)
+		if errors.Is(err, hooks.ErrEndpointNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "update webhook: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, updated.Redacted())
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		err := hooks.Default.Delete(parts[1])
+		if errors.Is(err, hooks.ErrEndpointNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "delete webhook: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) <= 2:
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleDeliveries serves "/webhooks/deliveries" and "/webhooks/deliveries/{id}/redeliver"; rest is the path after
+// "deliveries".
+func handleDeliveries(w http.ResponseWriter, r *http.Request, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		owner, status := r.URL.Query().Get("owner"), r.URL.Query().Get("status")
+		list := []webhook.Delivery{}
+		for _, d := range webhook.Default.Deliveries() {
+			if (owner == "" || d.Owner == owner) && (status == "" || d.Status == status) {
+				list = append(list, d)
+			}
+		}
+		writeJSON(w, http.StatusOK, list)
+	case len(rest) == 2 && rest[1] == "redeliver" && r.Method == http.MethodPost:
+		delivery, err := webhook.Default.Redeliver(rest[0])
+		if errors.Is(err, webhook.ErrDeliveryNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "redeliver: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, delivery)
+	case len(rest) == 0 || len(rest) == 2 && rest[1] == "redeliver":
+		http.Error(w, errNotAllowed.Error(), http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// decodeEndpoint reads and validates the endpoint of a request body, writing a 400 when it is invalid.
+func decodeEndpoint(w http.ResponseWriter, r *http.Request) (hooks.Endpoint, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	var in hooks.Input
+	if err := dec.Decode(&in); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return hooks.Endpoint{}, false
+	}
+	endpoint, err := hooks.ParseEndpoint(in)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return hooks.Endpoint{}, false
+	}
+	return endpoint, true
+}
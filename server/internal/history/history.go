@@ -0,0 +1,354 @@
+package history
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Entry is one observation of a booking price. A new entry is only recorded when a booking appears, changes price,
+// route or departure date, or disappears from the catalogue, so consecutive entries of a booking always differ.
+type Entry struct {
+	RecordedAt time.Time `json:"recordedAt"`
+	// Version is the catalogue version the observation was made at. Version numbers restart at 1 with the process,
+	// so entries are told apart and ordered by RecordedAt, the instant of the refresh.
+	Version uint64 `json:"version"`
+	Source  string `json:"source"`
+	ID      string `json:"id"`
+	// Route is the first departure and last arrival airports of the booking, e.g. "CDG-HND".
+	Route string `json:"route"`
+	// Date is the departure date of the booking in the local time of its origin airport.
+	Date     string  `json:"date"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	// Removed marks a booking that is no longer offered; its amount is the last one known.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// key identifies a booking across providers.
+func (e Entry) key() string { return e.Source + "|" + e.ID }
+
+// DefaultRetention is how long Default keeps entries.
+const DefaultRetention = 90 * 24 * time.Hour
+
+// Store keeps the entries in memory, indexed by booking id and by route, and, when it has a file, appends them to it
+// as JSON lines. Entries older than the retention are dropped, except the last entry of a booking still offered, which
+// is its current price.
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	file      *os.File
+	retention time.Duration
+	// byID and byRoute hold the entries of every booking id and route in recording order.
+	byID    map[string][]Entry
+	byRoute map[string][]Entry
+	// latest is the last entry of every booking, used to record changes only.
+	latest map[string]Entry
+	// size is the number of entries kept, and dropped the number of entries dropped from memory but still in the file.
+	size, dropped int
+}
+
+// Default is the store following catalogue.Default. It keeps entries in memory until main opens the history file.
+var Default = New(DefaultRetention)
+
+// New creates an empty store kept in memory only, dropping entries older than the retention; 0 keeps them all.
+func New(retention time.Duration) *Store {
+	return &Store{
+		retention: retention,
+		byID:      make(map[string][]Entry),
+		byRoute:   make(map[string][]Entry),
+		latest:    make(map[string]Entry),
+	}
+}
+
+// Open loads the entries of a JSON lines file, creating it if needed, and returns a store appending to it.
+// Lines that cannot be decoded, such as a line truncated by a crash, are skipped. Entries older than the retention are
+// dropped and the file is rewritten without them.
+func Open(path string, retention time.Duration) (*Store, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open history: %w", err)
+	}
+	s := New(retention)
+	s.path, s.file = path, file
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	line, skipped := 0, 0
+	for scanner.Scan() {
+		line++
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			skipped++
+			continue
+		}
+		s.add(e)
+	}
+	if err := scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("read history line %d: %w", line+1, err)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "history: skipped %d unreadable lines of %s\n", skipped, path)
+	}
+	if err := terminateLine(file); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	s.prune(time.Now())
+	if s.dropped > 0 {
+		if err := s.compact(); err != nil {
+			_ = s.file.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// terminateLine appends a newline to a file whose last line is incomplete, so that new entries start on their own line.
+func terminateLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return fmt.Errorf("read history: %w", err)
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	if _, err := file.Write([]byte{'\n'}); err != nil {
+		return fmt.Errorf("append history: %w", err)
+	}
+	return nil
+}
+
+// Close closes the history file, if any.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// add keeps an entry in memory. The caller holds the lock or owns the store.
+func (s *Store) add(e Entry) {
+	s.byID[e.ID] = append(s.byID[e.ID], e)
+	s.byRoute[e.Route] = append(s.byRoute[e.Route], e)
+	s.latest[e.key()] = e
+	s.size++
+}
+
+// prune drops the entries recorded before the retention, except the last entry of a booking still offered, and
+// forgets the bookings removed before it. The caller holds the lock or owns the store.
+func (s *Store) prune(now time.Time) {
+	if s.retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-s.retention)
+	keep := func(e Entry) bool {
+		if !e.RecordedAt.Before(cutoff) {
+			return true
+		}
+		last := s.latest[e.key()]
+		return !last.Removed && last == e
+	}
+	for id, entries := range s.byID {
+		kept := entries[:0]
+		for _, e := range entries {
+			if keep(e) {
+				kept = append(kept, e)
+			} else {
+				s.size--
+				s.dropped++
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.byID, id)
+		} else {
+			s.byID[id] = kept
+		}
+	}
+	for route, entries := range s.byRoute {
+		kept := entries[:0]
+		for _, e := range entries {
+			if keep(e) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.byRoute, route)
+		} else {
+			s.byRoute[route] = kept
+		}
+	}
+	for key, last := range s.latest {
+		if last.Removed && last.RecordedAt.Before(cutoff) {
+			delete(s.latest, key)
+		}
+	}
+}
+
+// compact rewrites the history file with the entries kept, through a temporary file renamed over it, then appends to
+// the new file. The caller holds the lock or owns the store.
+func (s *Store) compact() error {
+	ids := make([]string, 0, len(s.byID))
+	for id := range s.byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	entries := make([]Entry, 0, s.size)
+	for _, id := range ids {
+		entries = append(entries, s.byID[id]...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].RecordedAt.Before(entries[j].RecordedAt) })
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("compact history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("encode entry: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compact history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("compact history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("compact history: %w", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	_ = s.file.Close()
+	s.file, s.dropped = file, 0
+	return nil
+}
+
+// Record is a catalogue.Listener appending an entry for every booking of the new version that is new or changed price,
+// and for every booking that disappeared. The entries are compared with the store rather than with the previous version,
+// so that a restart does not record unchanged prices again.
+func (s *Store) Record(_, next catalogue.Version) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var batch []Entry
+	seen := make(map[string]bool, len(next.Flights))
+	for _, f := range next.Flights {
+		e := newEntry(f, next)
+		seen[e.key()] = true
+		last, ok := s.latest[e.key()]
+		if ok && !last.Removed && last.Amount == e.Amount && last.Currency == e.Currency &&
+			last.Route == e.Route && last.Date == e.Date {
+			continue
+		}
+		if ok && !last.Removed && last.Route != e.Route {
+			// the booking leaves its former route, whose history must no longer offer it
+			last.RecordedAt, last.Version, last.Removed = next.RefreshedAt, next.Number, true
+			batch = append(batch, last)
+		}
+		batch = append(batch, e)
+	}
+
+	keys := make([]string, 0, len(s.latest))
+	for key := range s.latest {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		last := s.latest[key]
+		if seen[key] || last.Removed {
+			continue
+		}
+		last.RecordedAt, last.Version, last.Removed = next.RefreshedAt, next.Number, true
+		batch = append(batch, last)
+	}
+
+	for _, e := range batch {
+		s.add(e)
+	}
+	if err := s.append(batch); err != nil {
+		fmt.Fprintln(os.Stderr, "history:", err)
+	}
+
+	// the file is rewritten once it holds as many dropped entries as kept ones, so it stays at most twice as large
+	s.prune(next.RefreshedAt)
+	if s.file != nil && s.dropped > 0 && s.dropped >= s.size {
+		if err := s.compact(); err != nil {
+			fmt.Fprintln(os.Stderr, "history:", err)
+		}
+	}
+}
+
+// append writes a batch of entries to the history file, if any.
+func (s *Store) append(batch []Entry) error {
+	if s.file == nil || len(batch) == 0 {
+		return nil
+	}
+	w := bufio.NewWriter(s.file)
+	enc := json.NewEncoder(w)
+	for _, e := range batch {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("encode entry: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("append entries: %w", err)
+	}
+	return nil
+}
+
+// newEntry describes a booking as observed in a catalogue version.
+func newEntry(f domain.Flight, v catalogue.Version) Entry {
+	e := Entry{
+		RecordedAt: v.RefreshedAt,
+		Version:    v.Number,
+		Source:     f.Source(),
+		ID:         f.ID(),
+		Amount:     f.Total().Amount(),
+		Currency:   f.Total().Currency(),
+	}
+	if segs := f.Segments(); len(segs) > 0 {
+		first, last := segs[0], segs[len(segs)-1]
+		e.Route = first.Departure() + "-" + last.Arrival()
+		e.Date = first.DepartTime().In(reference.AirportLocation(first.Departure())).Format(time.DateOnly)
+	}
+	return e
+}
+
+// ByID returns the entries of the bookings with the given id, of any provider, in recording order.
+func (s *Store) ByID(id string) []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Entry(nil), s.byID[id]...)
+}
+
+// ByRoute returns the entries of the bookings on a route, such as "CDG-HND", in recording order.
+func (s *Store) ByRoute(route string) []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Entry(nil), s.byRoute[route]...)
+}
@@ -0,0 +1,294 @@
+package hooks
+
+import (
+	"aggregator/internal/changes"
+	"aggregator/internal/domain"
+	"aggregator/internal/jsonfile"
+	"aggregator/internal/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events sent to endpoints when a booking changes between two catalogue versions, or leaves the catalogue.
+const (
+	EventStatusChanged   = "booking.status_changed"
+	EventScheduleChanged = "booking.schedule_changed"
+	EventPriceChanged    = "booking.price_changed"
+	EventRemoved         = "booking.removed"
+)
+
+// AllEvents lists the events an endpoint may subscribe to.
+var AllEvents = []string{EventStatusChanged, EventScheduleChanged, EventPriceChanged, EventRemoved}
+
+var (
+	// ErrEndpointNotFound is returned when no endpoint has the given id.
+	ErrEndpointNotFound = errors.New("endpoint not found")
+	// ErrInvalidEndpoint is returned when an endpoint is incomplete or malformed.
+	ErrInvalidEndpoint = errors.New("invalid endpoint")
+)
+
+// Input is the body of a request creating or replacing an endpoint.
+type Input struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// Endpoint is a URL receiving the booking change events it subscribed to.
+type Endpoint struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Events are the events sent to the endpoint, every event when empty.
+	Events []string `json:"events"`
+	// Secret signs the webhooks of the endpoint. It is only shown when the endpoint is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Redacted returns the endpoint without its secret.
+func (e Endpoint) Redacted() Endpoint {
+	e.Secret = ""
+	return e
+}
+
+// Wants reports whether the endpoint subscribed to an event.
+func (e Endpoint) Wants(event string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, event)
+}
+
+// Event is the body of a booking change webhook: the changed fields, by JSON path, and the booking as it now is.
+// A removed booking has no changes and no flight.
+type Event struct {
+	Event   string                 `json:"event"`
+	Source  string                 `json:"source"`
+	ID      string                 `json:"id"`
+	Changes []changes.FieldChange  `json:"changes"`
+	Flight  *domain.FlightSnapshot `json:"flight,omitempty"`
+	Version uint64                 `json:"version"`
+	At      time.Time              `json:"at"`
+}
+
+// ParseEndpoint validates an input and returns the endpoint it describes, without id nor secret.
+func ParseEndpoint(in Input) (Endpoint, error) {
+	e := Endpoint{URL: strings.TrimSpace(in.URL), Events: []string{}, Secret: in.Secret}
+	if err := webhook.CheckURL(e.URL); err != nil {
+		return Endpoint{}, fmt.Errorf("%w: %w", ErrInvalidEndpoint, err)
+	}
+	for _, event := range in.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !slices.Contains(AllEvents, event) {
+			return Endpoint{}, fmt.Errorf("%w: unknown event %q, expected one of %s", ErrInvalidEndpoint, event, strings.Join(AllEvents, ", "))
+		}
+		if !slices.Contains(e.Events, event) {
+			e.Events = append(e.Events, event)
+		}
+	}
+	return e, nil
+}
+
+// Classify returns the events of a booking modification, in the order of AllEvents, each with its changed fields:
+// the status, the departure or arrival time of a segment, and the total amount or currency.
+func Classify(m changes.Modification) map[string][]changes.FieldChange {
+	out := make(map[string][]changes.FieldChange)
+	for _, fc := range m.Fields {
+		switch {
+		case fc.Field == "status":
+			out[EventStatusChanged] = append(out[EventStatusChanged], fc)
+		case strings.HasPrefix(fc.Field, "segments[") &&
+			(strings.HasSuffix(fc.Field, "].depart") || strings.HasSuffix(fc.Field, "].arrive")):
+			out[EventScheduleChanged] = append(out[EventScheduleChanged], fc)
+		case fc.Field == "total.amount" || fc.Field == "total.currency":
+			out[EventPriceChanged] = append(out[EventPriceChanged], fc)
+		}
+	}
+	return out
+}
+
+// Store keeps the endpoints in memory and, when it has a file, saves them to it as a JSON array after every change.
+type Store struct {
+	mu         sync.Mutex
+	path       string
+	endpoints  map[string]Endpoint
+	dispatcher *webhook.Dispatcher
+}
+
+// Default is the store of the server. It keeps endpoints in memory until main opens the webhooks file.
+var Default = New(webhook.Default)
+
+// New creates an empty store kept in memory only, delivering its webhooks through the dispatcher.
+func New(d *webhook.Dispatcher) *Store {
+	return &Store{endpoints: make(map[string]Endpoint), dispatcher: d}
+}
+
+// Open loads the endpoints of a JSON file, if it exists, and returns a store saving to it.
+func Open(path string, d *webhook.Dispatcher) (*Store, error) {
+	s := New(d)
+	s.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read webhooks: %w", err)
+	}
+	var list []Endpoint
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("decode webhooks %s: %w", path, err)
+	}
+	for _, e := range list {
+		s.endpoints[e.ID] = e
+	}
+	return s, nil
+}
+
+// Create stores a new endpoint, generating its id and, when missing, its secret.
+func (s *Store) Create(e Endpoint) (Endpoint, error) {
+	e.ID, e.CreatedAt = webhook.NewID(), time.Now().UTC()
+	if e.Secret == "" {
+		e.Secret = webhook.NewSecret()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoints[e.ID] = e
+	if err := s.save(); err != nil {
+		delete(s.endpoints, e.ID)
+		return Endpoint{}, err
+	}
+	return e, nil
+}
+
+// List returns the endpoints, oldest first.
+func (s *Store) List() []Endpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted()
+}
+
+// sorted returns the endpoints by creation time then id. The caller holds the lock.
+func (s *Store) sorted() []Endpoint {
+	out := make([]Endpoint, 0, len(s.endpoints))
+	for _, e := range s.endpoints {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Len returns the number of endpoints.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.endpoints)
+}
+
+// Get returns the endpoint with the given id.
+func (s *Store) Get(id string) (Endpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.endpoints[id]
+	if !ok {
+		return Endpoint{}, fmt.Errorf("%w: %s", ErrEndpointNotFound, id)
+	}
+	return e, nil
+}
+
+// Update replaces the URL and events of an endpoint, keeping its secret when none is given.
+func (s *Store) Update(id string, e Endpoint) (Endpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.endpoints[id]
+	if !ok {
+		return Endpoint{}, fmt.Errorf("%w: %s", ErrEndpointNotFound, id)
+	}
+	e.ID, e.CreatedAt = old.ID, old.CreatedAt
+	if e.Secret == "" {
+		e.Secret = old.Secret
+	}
+	s.endpoints[id] = e
+	if err := s.save(); err != nil {
+		s.endpoints[id] = old
+		return Endpoint{}, err
+	}
+	return e, nil
+}
+
+// Delete removes an endpoint. Its deliveries stay in the log.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.endpoints[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrEndpointNotFound, id)
+	}
+	delete(s.endpoints, id)
+	if err := s.save(); err != nil {
+		s.endpoints[id] = old
+		return err
+	}
+	return nil
+}
+
+// Record is a changes.Listener sending, for every booking modified by a change of the catalogue, its status, schedule
+// and price change events, and for every booking that left the catalogue a removal event, to the endpoints subscribed
+// to them.
+func (s *Store) Record(change changes.Change) {
+	s.mu.Lock()
+	endpoints := s.sorted()
+	s.mu.Unlock()
+	if len(endpoints) == 0 {
+		return
+	}
+
+	for _, m := range change.Modified {
+		events := Classify(m)
+		for _, name := range AllEvents {
+			fields, ok := events[name]
+			if !ok {
+				continue
+			}
+			flight := m.Flight
+			s.send(endpoints, Event{Event: name, Source: m.Source, ID: m.ID, Changes: fields, Flight: &flight,
+				Version: change.Version, At: change.At})
+		}
+	}
+	for _, ref := range change.Removed {
+		s.send(endpoints, Event{Event: EventRemoved, Source: ref.Source, ID: ref.ID, Changes: []changes.FieldChange{},
+			Version: change.Version, At: change.At})
+	}
+}
+
+// send delivers an event to the endpoints subscribed to it.
+func (s *Store) send(endpoints []Endpoint, body Event) {
+	for _, e := range endpoints {
+		if !e.Wants(body.Event) {
+			continue
+		}
+		if _, err := s.dispatcher.Send(e.ID, body.Event, e.URL, e.Secret, body); err != nil {
+			fmt.Fprintln(os.Stderr, "webhooks:", err)
+		}
+	}
+}
+
+// save writes the endpoints to the file, if any. The caller holds the lock.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := jsonfile.Write(s.path, s.sorted()); err != nil {
+		return fmt.Errorf("save webhooks: %w", err)
+	}
+	return nil
+}
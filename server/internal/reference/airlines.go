@@ -0,0 +1,75 @@
+package reference
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+//go:embed airlines.json
+var airlinesJSON []byte
+
+// Airline alliances, as spelled in the airline table.
+const (
+	AllianceOneworld = "oneworld"
+	AllianceSkyTeam  = "SkyTeam"
+	AllianceStar     = "Star Alliance"
+)
+
+// Airline is the reference data known for an airline. Alliance is empty for airlines outside the three alliances.
+type Airline struct {
+	Code     string `json:"code"`
+	ICAO     string `json:"icao"`
+	Name     string `json:"name"`
+	Alliance string `json:"alliance"`
+}
+
+var (
+	airlinesOnce   sync.Once
+	airlines       map[string]Airline
+	airlinesByICAO map[string]Airline
+)
+
+// loadAirlines decodes the embedded airline table once. The table is part of the binary, so a decoding error is a bug.
+func loadAirlines() {
+	airlinesOnce.Do(func() {
+		var list []Airline
+		if err := json.Unmarshal(airlinesJSON, &list); err != nil {
+			panic("reference: decode airlines.json: " + err.Error())
+		}
+		airlines = make(map[string]Airline, len(list))
+		airlinesByICAO = make(map[string]Airline, len(list))
+		for _, a := range list {
+			airlines[a.Code] = a
+			airlinesByICAO[a.ICAO] = a
+		}
+	})
+}
+
+// LookupAirline returns the reference data of an airline from its two character IATA code or its three letter
+// ICAO code, case-insensitively.
+func LookupAirline(code string) (Airline, bool) {
+	loadAirlines()
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if a, ok := airlines[code]; ok {
+		return a, true
+	}
+	a, ok := airlinesByICAO[code]
+	return a, ok
+}
+
+// ParseAlliance returns the canonical name of an alliance, ignoring case and spaces: "skyteam", "Star Alliance",
+// "star" and "ONEWORLD" are all accepted.
+func ParseAlliance(s string) (string, bool) {
+	key := strings.ToLower(strings.Join(strings.Fields(s), ""))
+	switch key {
+	case "oneworld":
+		return AllianceOneworld, true
+	case "skyteam":
+		return AllianceSkyTeam, true
+	case "star", "staralliance":
+		return AllianceStar, true
+	}
+	return "", false
+}
@@ -0,0 +1,82 @@
+package reference
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+	// Embed the IANA time zone database so airport local times work on minimal images.
+	_ "time/tzdata"
+)
+
+//go:embed airports.json
+var airportsJSON []byte
+
+// Airport is the reference data known for an IATA airport code.
+type Airport struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	City     string `json:"city"`
+	Country  string `json:"country"`
+	TimeZone string `json:"timeZone"`
+	// Latitude and Longitude locate the airport reference point, in decimal degrees.
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+
+	location *time.Location
+}
+
+var (
+	airportsOnce sync.Once
+	airports     map[string]Airport
+)
+
+// loadAirports decodes the embedded airport table once. The table is part of the binary, so a decoding error is a bug.
+func loadAirports() {
+	airportsOnce.Do(func() {
+		var list []Airport
+		if err := json.Unmarshal(airportsJSON, &list); err != nil {
+			panic("reference: decode airports.json: " + err.Error())
+		}
+		airports = make(map[string]Airport, len(list))
+		for _, a := range list {
+			loc, err := time.LoadLocation(a.TimeZone)
+			if err != nil {
+				panic("reference: airport " + a.Code + ": " + err.Error())
+			}
+			a.location = loc
+			airports[a.Code] = a
+		}
+	})
+}
+
+// LookupAirport returns the reference data of an IATA airport code, case-insensitively.
+func LookupAirport(code string) (Airport, bool) {
+	loadAirports()
+	a, ok := airports[strings.ToUpper(code)]
+	return a, ok
+}
+
+// Location returns the time zone of the airport, or UTC when it is unknown.
+func (a Airport) Location() *time.Location {
+	if a.location == nil {
+		return time.UTC
+	}
+	return a.location
+}
+
+// LookupLocation returns the time zone of an IATA airport code, and false when the airport or its time zone is unknown.
+func LookupLocation(code string) (*time.Location, bool) {
+	a, ok := LookupAirport(code)
+	if !ok || a.location == nil {
+		return nil, false
+	}
+	return a.location, true
+}
+
+// AirportLocation returns the time zone of an IATA airport code, or UTC when the airport is unknown.
+func AirportLocation(code string) *time.Location {
+	a, _ := LookupAirport(code)
+	return a.Location()
+}
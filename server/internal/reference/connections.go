@@ -0,0 +1,59 @@
+package reference
+
+import (
+	"strings"
+	"time"
+)
+
+// connectionTimes holds the minimum connection time of an airport, in minutes, for domestic and international
+// connections. The values are indicative of the standard airline minimums published for each hub; airports missing
+// from the table use defaultConnectionTimes.
+var connectionTimes = map[string][2]int{
+	"AMS": {40, 50},
+	"ATL": {55, 90},
+	"BKK": {75, 75},
+	"CDG": {60, 90},
+	"DFW": {50, 90},
+	"DOH": {45, 60},
+	"DXB": {60, 75},
+	"FCO": {50, 75},
+	"FRA": {45, 45},
+	"HEL": {35, 40},
+	"HKG": {50, 60},
+	"HND": {60, 90},
+	"ICN": {60, 70},
+	"IST": {60, 75},
+	"JFK": {60, 120},
+	"LAX": {70, 120},
+	"LHR": {60, 90},
+	"MAD": {45, 60},
+	"MUC": {30, 45},
+	"NRT": {60, 90},
+	"ORD": {50, 90},
+	"ORY": {45, 60},
+	"PEK": {90, 120},
+	"SFO": {60, 90},
+	"SIN": {60, 60},
+	"ZRH": {40, 40},
+}
+
+// defaultConnectionTimes is the minimum connection time, in minutes, of airports missing from connectionTimes.
+var defaultConnectionTimes = [2]int{60, 90}
+
+// MinimumConnectionTime returns the minimum time to connect at an airport from a flight coming from one airport to a
+// flight going to another. The connection is domestic when the three airports are in the same country, and
+// international otherwise or when one of them is unknown.
+func MinimumConnectionTime(from, via, to string) time.Duration {
+	times, ok := connectionTimes[strings.ToUpper(via)]
+	if !ok {
+		times = defaultConnectionTimes
+	}
+	minutes := times[1]
+	origin, ok1 := LookupAirport(from)
+	hub, ok2 := LookupAirport(via)
+	destination, ok3 := LookupAirport(to)
+	if ok1 && ok2 && ok3 && origin.Country == hub.Country && hub.Country == destination.Country {
+		minutes = times[0]
+	}
+	return time.Duration(minutes) * time.Minute
+}
@@ -0,0 +1,107 @@
+package reference
+
+import "math"
+
+// earthRadiusKm is the mean radius of the Earth used for great-circle distances.
+const earthRadiusKm = 6371.0088
+
+// Haul classifies a flight by its length, as emission factor tables do.
+type Haul string
+
+const (
+	// HaulDomestic is a flight between two airports of the same country.
+	HaulDomestic Haul = "domestic"
+	// HaulShort is an international flight shorter than shortHaulMaxKm.
+	HaulShort Haul = "short"
+	// HaulLong is an international flight of at least shortHaulMaxKm.
+	HaulLong Haul = "long"
+)
+
+// shortHaulMaxKm is the distance from which an international flight is long-haul.
+const shortHaulMaxKm = 3700
+
+// DistanceUplift is the factor applied to great-circle distances before estimating emissions, to account for
+// routing, stacking and delays: aircraft never fly the great circle exactly.
+const DistanceUplift = 1.08
+
+// emissionFactors holds the CO2-equivalent emitted per passenger and per kilometre, in kilograms, by haul and cabin.
+// Every value comes from one table: UK Government GHG Conversion Factors for Company Reporting 2023 (DESNZ), sheet
+// "Business travel- air", kg CO2e per passenger.km "With RF" (radiative forcing included), rows for flights to and
+// from the UK: "Domestic, to/from UK", "Short-haul, to/from UK" and "Long-haul, to/from UK".
+// Cabins the table does not distinguish on a haul use the closest one it does: premium economy flies at the economy
+// factor and first at the business factor on short-haul, and domestic flights have a single factor. The "" entry is
+// the "Average passenger" row, used when the cabin is unknown.
+var emissionFactors = map[Haul]map[string]float64{
+	HaulDomestic: {
+		"":         0.27258,
+		"economy":  0.27258,
+		"premium":  0.27258,
+		"business": 0.27258,
+		"first":    0.27258,
+	},
+	HaulShort: {
+		"":         0.18592,
+		"economy":  0.18287,
+		"premium":  0.18287,
+		"business": 0.27430,
+		"first":    0.27430,
+	},
+	HaulLong: {
+		"":         0.26128,
+		"economy":  0.20011,
+		"premium":  0.32016,
+		"business": 0.58029,
+		"first":    0.80048,
+	},
+}
+
+// GreatCircleKm returns the great-circle distance between two airports, and false when either is unknown.
+func GreatCircleKm(from, to string) (float64, bool) {
+	a, ok := LookupAirport(from)
+	if !ok {
+		return 0, false
+	}
+	b, ok := LookupAirport(to)
+	if !ok {
+		return 0, false
+	}
+	return haversineKm(a.Latitude, a.Longitude, b.Latitude, b.Longitude), true
+}
+
+// haversineKm returns the great-circle distance between two points given in decimal degrees.
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLon := rad(lat2-lat1), rad(lon2-lon1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// FlightHaul returns the haul of a flight between two airports of the given distance, and false when either is unknown.
+func FlightHaul(from, to string, distanceKm float64) (Haul, bool) {
+	a, ok := LookupAirport(from)
+	if !ok {
+		return "", false
+	}
+	b, ok := LookupAirport(to)
+	if !ok {
+		return "", false
+	}
+	switch {
+	case a.Country == b.Country:
+		return HaulDomestic, true
+	case distanceKm < shortHaulMaxKm:
+		return HaulShort, true
+	default:
+		return HaulLong, true
+	}
+}
+
+// EmissionFactor returns the kilograms of CO2-equivalent per passenger-kilometre for a haul and a cabin
+// ("economy", "premium", "business", "first"). Unknown or empty cabins get the average passenger factor.
+func EmissionFactor(haul Haul, cabin string) float64 {
+	factors := emissionFactors[haul]
+	if f, ok := factors[cabin]; ok {
+		return f
+	}
+	return factors[""]
+}
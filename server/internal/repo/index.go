@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/textnorm"
+	"sort"
+	"strings"
+	"time"
+)
+
+// route is the (from, to) key of a segment.
+type route struct {
+	from, to string
+}
+
+// flightIndex holds the secondary indexes of a repository, built once from its data.
+// Indexes store positions in the data slice; hash indexes keep them in data order, so lookups return flights
+// in the order a scan would.
+type flightIndex struct {
+	byID        map[string]int
+	byNumber    map[string][]int
+	byPassenger map[string][]int
+	byRoute     map[route][]int
+	// byPrice lists, per upper-cased currency of the total, positions sorted by total amount, and byDeparture positions
+	// sorted by first departure, for range queries. Flights without segments are left out of byDeparture.
+	byPrice     map[string][]int
+	byDeparture []int
+}
+
+// newFlightIndex indexes flights on id, normalized flight number, normalized passenger name, route, price and departure time.
+func newFlightIndex(data domain.Flights) *flightIndex {
+	ix := &flightIndex{
+		byID:        make(map[string]int, len(data)),
+		byNumber:    make(map[string][]int),
+		byPassenger: make(map[string][]int),
+		byRoute:     make(map[route][]int),
+		byPrice:     make(map[string][]int),
+		byDeparture: make([]int, 0, len(data)),
+	}
+	for i, f := range data {
+		if _, ok := ix.byID[f.ID()]; !ok {
+			ix.byID[f.ID()] = i
+		}
+		key := textnorm.NameKey(f.PassengerName())
+		ix.byPassenger[key] = append(ix.byPassenger[key], i)
+		currency := strings.ToUpper(f.Total().Currency())
+		ix.byPrice[currency] = append(ix.byPrice[currency], i)
+
+		segs := f.Segments()
+		if len(segs) > 0 {
+			ix.byDeparture = append(ix.byDeparture, i)
+		}
+		numbers := make(map[string]bool, len(segs))
+		routes := make(map[route]bool, len(segs))
+		for _, s := range segs {
+			// codeshare segments are found under their marketing and their operating number
+			for _, n := range []string{s.FlightNumber(), s.OperatingFlightNumber()} {
+				number := domain.NormalizeFlightNumber(n)
+				if !numbers[number] {
+					numbers[number] = true
+					ix.byNumber[number] = append(ix.byNumber[number], i)
+				}
+			}
+			rt := route{from: s.Departure(), to: s.Arrival()}
+			if !routes[rt] {
+				routes[rt] = true
+				ix.byRoute[rt] = append(ix.byRoute[rt], i)
+			}
+		}
+	}
+	for _, positions := range ix.byPrice {
+		sort.SliceStable(positions, func(a, b int) bool {
+			return data[positions[a]].Total().Amount() < data[positions[b]].Total().Amount()
+		})
+	}
+	sort.SliceStable(ix.byDeparture, func(a, b int) bool {
+		return departure(data[ix.byDeparture[a]]).Before(departure(data[ix.byDeparture[b]]))
+	})
+	return ix
+}
+
+// departure returns the departure time of the first segment of a flight that has one.
+func departure(f domain.Flight) time.Time {
+	return f.Segments()[0].DepartTime()
+}
+
+// priceRange returns the positions of the flights whose total is in the currency and whose amount lies between min
+// and max, both inclusive, by increasing amount.
+func (ix *flightIndex) priceRange(data domain.Flights, currency string, min, max float64) []int {
+	positions := ix.byPrice[strings.ToUpper(currency)]
+	start := sort.Search(len(positions), func(i int) bool { return data[positions[i]].Total().Amount() >= min })
+	end := sort.Search(len(positions), func(i int) bool { return data[positions[i]].Total().Amount() > max })
+	if start >= end {
+		return nil
+	}
+	return positions[start:end]
+}
+
+// priceEqual returns the positions of the flights whose total amount is exactly price, whatever its currency, by
+// currency.
+func (ix *flightIndex) priceEqual(data domain.Flights, price float64) []int {
+	currencies := make([]string, 0, len(ix.byPrice))
+	for currency := range ix.byPrice {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	var out []int
+	for _, currency := range currencies {
+		out = append(out, ix.priceRange(data, currency, price, price)...)
+	}
+	return out
+}
+
+// departureRange returns the positions of the flights first departing in [after, before).
+// A zero bound leaves that side of the range open.
+func (ix *flightIndex) departureRange(data domain.Flights, after, before time.Time) []int {
+	start := 0
+	if !after.IsZero() {
+		start = sort.Search(len(ix.byDeparture), func(i int) bool { return !departure(data[ix.byDeparture[i]]).Before(after) })
+	}
+	end := len(ix.byDeparture)
+	if !before.IsZero() {
+		end = sort.Search(len(ix.byDeparture), func(i int) bool { return !departure(data[ix.byDeparture[i]]).Before(before) })
+	}
+	if start >= end {
+		return nil
+	}
+	return ix.byDeparture[start:end]
+}
+
+// flightsAt returns the flights at the given positions, in that order.
+func flightsAt(data domain.Flights, positions []int) domain.Flights {
+	var flights domain.Flights
+	for _, i := range positions {
+		flights = append(flights, data[i])
+	}
+	return flights
+}
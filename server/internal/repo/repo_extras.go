@@ -0,0 +1,31 @@
+package repo
+
+import "aggregator/internal/domain"
+
+// rawAllowance is a baggage allowance as both providers send it.
+type rawAllowance struct {
+	Pieces   int     `json:"pieces"`
+	WeightKg float64 `json:"weightKg"`
+}
+
+// rawBaggage is an optional baggage allowance; a nil *rawBaggage means the provider did not send one.
+type rawBaggage struct {
+	CarryOn rawAllowance `json:"carryOn"`
+	Checked rawAllowance `json:"checked"`
+}
+
+// toDomain returns an unknown baggage when the provider sent none.
+func (b *rawBaggage) toDomain() domain.Baggage {
+	if b == nil {
+		return domain.Baggage{}
+	}
+	return domain.NewBaggage(
+		domain.NewAllowance(b.CarryOn.Pieces, b.CarryOn.WeightKg),
+		domain.NewAllowance(b.Checked.Pieces, b.Checked.WeightKg),
+	)
+}
+
+// newAncillary builds an ancillary from the fields both providers send, whatever their nesting.
+func newAncillary(kind, description string, amount float64, currency string) domain.Ancillary {
+	return domain.NewAncillary(domain.ParseAncillaryKind(kind), description, domain.NewTotal(amount, currency))
+}
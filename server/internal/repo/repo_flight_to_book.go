@@ -2,16 +2,17 @@ package repo
 
 import (
 	"aggregator/internal/domain"
+	"aggregator/internal/textnorm"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"strings"
 	"time"
 )
 
 type RepoFlightToBook struct {
-	data domain.Flights
+	data  domain.Flights
+	index *flightIndex
 }
 
 // NewRepoFlightToBookFromReader creates a RepoFlightToBook by reading and decoding flight data from the provided io.Reader.
@@ -31,12 +32,32 @@ func NewRepoFlightToBookFromReader(r io.Reader) (*RepoFlightToBook, error) {
 					To     string `json:"to"`
 					Depart string `json:"depart"`
 					Arrive string `json:"arrive"`
+					// OperatedBy is only present on codeshare flights.
+					OperatedBy struct {
+						Carrier string `json:"carrier"`
+						Number  string `json:"number"`
+					} `json:"operatedBy"`
+					// The fare fields are optional.
+					Cabin        string `json:"cabin"`
+					BookingClass string `json:"bookingClass"`
+					FareBasis    string `json:"fareBasis"`
 				} `json:"flight"`
+				Baggage *rawBaggage `json:"baggage"`
 			} `json:"segments"`
 			Total struct {
 				Amount   float64 `json:"amount"`
 				Currency string  `json:"currency"`
 			} `json:"total"`
+			// Baggage applies to the whole booking; segments may also carry their own.
+			Baggage     *rawBaggage `json:"baggage"`
+			Ancillaries []struct {
+				Type        string `json:"type"`
+				Description string `json:"description"`
+				Price       struct {
+					Amount   float64 `json:"amount"`
+					Currency string  `json:"currency"`
+				} `json:"price"`
+			} `json:"ancillaries"`
 		} `json:"flight_to_book"`
 	}
 
@@ -65,7 +86,9 @@ func NewRepoFlightToBookFromReader(r io.Reader) (*RepoFlightToBook, error) {
 				s.Flight.To,
 				dep,
 				arr,
-			)
+			).WithOperating(s.Flight.OperatedBy.Carrier, s.Flight.OperatedBy.Number).
+				WithFare(s.Flight.Cabin, s.Flight.BookingClass, s.Flight.FareBasis).
+				WithBaggage(s.Baggage.toDomain())
 
 			segs = append(segs, segment)
 		}
@@ -85,9 +108,14 @@ func NewRepoFlightToBookFromReader(r io.Reader) (*RepoFlightToBook, error) {
 			total,
 			"flight_to_book",
 		)
-		out = append(out, *flight)
+
+		ancillaries := make([]domain.Ancillary, len(f.Ancillaries))
+		for i, a := range f.Ancillaries {
+			ancillaries[i] = newAncillary(a.Type, a.Description, a.Price.Amount, a.Price.Currency)
+		}
+		out = append(out, flight.WithExtras(f.Baggage.toDomain(), ancillaries))
 	}
-	return &RepoFlightToBook{data: out}, nil
+	return &RepoFlightToBook{data: out, index: newFlightIndex(out)}, nil
 }
 
 // List retrieves all available flights stored in the repository. It returns a slice of flights or an error if any occurs.
@@ -110,16 +138,22 @@ func (r *RepoFlightToBook) FindByNumber(ctx context.Context, number string) (dom
 		return domain.Flight{}, ctx.Err()
 	default:
 	}
-	for _, f := range r.data {
-		for _, s := range f.Segments() {
-			if strings.Compare(s.FlightNumber(), number) == 0 {
-				return f, nil
-			}
-		}
+	if positions := r.index.byNumber[domain.NormalizeFlightNumber(number)]; len(positions) > 0 {
+		return r.data[positions[0]], nil
 	}
 	return domain.Flight{}, nil
 }
 
+// FindAllByNumber retrieves every flight with a segment on the given flight number, written in any form ("AF 276", "AF0276").
+func (r *RepoFlightToBook) FindAllByNumber(ctx context.Context, number string) (domain.Flights, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	return flightsAt(r.data, r.index.byNumber[domain.NormalizeFlightNumber(number)]), nil
+}
+
 // FindById retrieves a flight by its ID from the repository. Returns the matching flight or an empty flight if not found.
 func (r *RepoFlightToBook) FindById(ctx context.Context, id string) (domain.Flight, error) {
 	select {
@@ -127,28 +161,21 @@ func (r *RepoFlightToBook) FindById(ctx context.Context, id string) (domain.Flig
 		return domain.Flight{}, ctx.Err()
 	default:
 	}
-	for _, f := range r.data {
-		if strings.Compare(f.ID(), id) == 0 {
-			return f, nil
-		}
+	if i, ok := r.index.byID[id]; ok {
+		return r.data[i], nil
 	}
 	return domain.Flight{}, nil
 }
 
-// FindByPassenger retrieves flights matching the specified passenger's name from the repository. Returns flights or an error.
+// FindByPassenger retrieves flights matching the specified passenger's name from the repository, ignoring case, diacritics,
+// spacing and word order. Returns flights or an error.
 func (r *RepoFlightToBook) FindByPassenger(ctx context.Context, passengerName string) (domain.Flights, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
-	var flights domain.Flights
-	for _, f := range r.data {
-		if strings.Compare(f.PassengerName(), passengerName) == 0 {
-			flights = append(flights, f)
-		}
-	}
-	return flights, nil
+	return flightsAt(r.data, r.index.byPassenger[textnorm.NameKey(passengerName)]), nil
 }
 
 // FindByDestination searches for flights matching the specified departure and arrival locations and returns the results.
@@ -158,16 +185,7 @@ func (r *RepoFlightToBook) FindByDestination(ctx context.Context, departure, arr
 		return nil, ctx.Err()
 	default:
 	}
-	var flights domain.Flights
-	for _, f := range r.data {
-		for _, seg := range f.Segments() {
-			if strings.Compare(seg.Departure(), departure) == 0 &&
-				strings.Compare(seg.Arrival(), arrival) == 0 {
-				flights = append(flights, f)
-			}
-		}
-	}
-	return flights, nil
+	return flightsAt(r.data, r.index.byRoute[route{from: departure, to: arrival}]), nil
 }
 
 // FindByPrice filters flights in the repository by the specified price and returns a slice of matching flights or an error.
@@ -177,11 +195,26 @@ func (r *RepoFlightToBook) FindByPrice(ctx context.Context, price float64) (doma
 		return nil, ctx.Err()
 	default:
 	}
-	var flights []domain.Flight
-	for _, f := range r.data {
-		if f.Total().Amount() == price {
-			flights = append(flights, f)
-		}
+	return flightsAt(r.data, r.index.priceEqual(r.data, price)), nil
+}
+
+// FindByPriceRange retrieves the flights whose total is in the currency and whose amount lies between min and max,
+// both inclusive, by increasing amount.
+func (r *RepoFlightToBook) FindByPriceRange(ctx context.Context, currency string, min, max float64) (domain.Flights, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	return flightsAt(r.data, r.index.priceRange(r.data, currency, min, max)), nil
+}
+
+// FindByDepartureRange retrieves the flights first departing in [after, before), by departure time. A zero bound is open.
+func (r *RepoFlightToBook) FindByDepartureRange(ctx context.Context, after, before time.Time) (domain.Flights, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
 	}
-	return flights, nil
+	return flightsAt(r.data, r.index.departureRange(r.data, after, before)), nil
 }
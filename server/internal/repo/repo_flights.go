@@ -2,16 +2,17 @@ package repo
 
 import (
 	"aggregator/internal/domain"
+	"aggregator/internal/textnorm"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"strings"
 	"time"
 )
 
 type RepoFlights struct {
-	data domain.Flights
+	data  domain.Flights
+	index *flightIndex
 }
 
 // NewRepoFlightsFromReader parses flight data from an io.Reader and returns a RepoFlights instance or an error.
@@ -28,6 +29,21 @@ func NewRepoFlightsFromReader(r io.Reader) (*RepoFlights, error) {
 			ArrivalTime      string  `json:"arrivalTime"`
 			Price            float64 `json:"price"`
 			Currency         string  `json:"currency"`
+			// The operating fields are only present on codeshare flights.
+			OperatingCarrier      string `json:"operatingCarrier"`
+			OperatingFlightNumber string `json:"operatingFlightNumber"`
+			// The fare fields are optional.
+			Cabin        string `json:"cabin"`
+			BookingClass string `json:"bookingClass"`
+			FareBasis    string `json:"fareBasis"`
+			// Baggage and ancillaries are optional and apply to the booking.
+			Baggage     *rawBaggage `json:"baggage"`
+			Ancillaries []struct {
+				Type        string  `json:"type"`
+				Description string  `json:"description"`
+				Price       float64 `json:"price"`
+				Currency    string  `json:"currency"`
+			} `json:"ancillaries"`
 		} `json:"flights"`
 	}
 
@@ -55,7 +71,8 @@ func NewRepoFlightsFromReader(r io.Reader) (*RepoFlights, error) {
 			f.ArrivalAirport,
 			dep,
 			arr,
-		)
+		).WithOperating(f.OperatingCarrier, f.OperatingFlightNumber).
+			WithFare(f.Cabin, f.BookingClass, f.FareBasis)
 
 		total := domain.NewTotal(
 			f.Price,
@@ -70,9 +87,14 @@ func NewRepoFlightsFromReader(r io.Reader) (*RepoFlights, error) {
 			total,
 			"flights",
 		)
-		out = append(out, *flight)
+
+		ancillaries := make([]domain.Ancillary, len(f.Ancillaries))
+		for i, a := range f.Ancillaries {
+			ancillaries[i] = newAncillary(a.Type, a.Description, a.Price, a.Currency)
+		}
+		out = append(out, flight.WithExtras(f.Baggage.toDomain(), ancillaries))
 	}
-	return &RepoFlights{out}, nil
+	return &RepoFlights{data: out, index: newFlightIndex(out)}, nil
 }
 
 // List retrieves all flights currently stored in the repository as a domain.Flights collection.
@@ -92,10 +114,8 @@ func (r *RepoFlights) FindById(ctx context.Context, id string) (domain.Flight, e
 		return domain.Flight{}, ctx.Err()
 	default:
 	}
-	for _, f := range r.data {
-		if strings.Compare(f.ID(), id) == 0 {
-			return f, nil
-		}
+	if i, ok := r.index.byID[id]; ok {
+		return r.data[i], nil
 	}
 	return domain.Flight{}, nil
 }
@@ -107,30 +127,31 @@ func (r *RepoFlights) FindByNumber(ctx context.Context, number string) (domain.F
 		return domain.Flight{}, ctx.Err()
 	default:
 	}
-	for _, f := range r.data {
-		for _, s := range f.Segments() {
-			if strings.Compare(s.FlightNumber(), number) == 0 {
-				return f, nil
-			}
-		}
+	if positions := r.index.byNumber[domain.NormalizeFlightNumber(number)]; len(positions) > 0 {
+		return r.data[positions[0]], nil
 	}
 	return domain.Flight{}, nil
 }
 
-// FindByPassenger retrieves all flights that match the specified passenger name from the repository. Returns the flights or an empty collection.
-func (r *RepoFlights) FindByPassenger(ctx context.Context, passengerName string) (domain.Flights, error) {
+// FindAllByNumber retrieves every flight with a segment on the given flight number, written in any form ("AF 276", "AF0276").
+func (r *RepoFlights) FindAllByNumber(ctx context.Context, number string) (domain.Flights, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
-	var flights []domain.Flight
-	for _, f := range r.data {
-		if strings.Compare(f.PassengerName(), passengerName) == 0 {
-			flights = append(flights, f)
-		}
+	return flightsAt(r.data, r.index.byNumber[domain.NormalizeFlightNumber(number)]), nil
+}
+
+// FindByPassenger retrieves all flights that match the specified passenger name from the repository, ignoring case, diacritics,
+// spacing and word order. Returns the flights or an empty collection.
+func (r *RepoFlights) FindByPassenger(ctx context.Context, passengerName string) (domain.Flights, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
 	}
-	return flights, nil
+	return flightsAt(r.data, r.index.byPassenger[textnorm.NameKey(passengerName)]), nil
 }
 
 // FindByDestination retrieves flights that match the specified departure and arrival locations from the repository.
@@ -140,16 +161,7 @@ func (r *RepoFlights) FindByDestination(ctx context.Context, departure, arrival
 		return nil, ctx.Err()
 	default:
 	}
-	var flights []domain.Flight
-	for _, f := range r.data {
-		for _, seg := range f.Segments() {
-			if strings.Compare(seg.Departure(), departure) == 0 &&
-				strings.Compare(seg.Arrival(), arrival) == 0 {
-				flights = append(flights, f)
-			}
-		}
-	}
-	return flights, nil
+	return flightsAt(r.data, r.index.byRoute[route{from: departure, to: arrival}]), nil
 }
 
 // FindByPrice retrieves all flights whose total price matches the specified amount. Returns the flights or an empty collection.
@@ -159,11 +171,26 @@ func (r *RepoFlights) FindByPrice(ctx context.Context, price float64) (domain.Fl
 		return nil, ctx.Err()
 	default:
 	}
-	var flights []domain.Flight
-	for _, f := range r.data {
-		if f.Total().Amount() == price {
-			flights = append(flights, f)
-		}
+	return flightsAt(r.data, r.index.priceEqual(r.data, price)), nil
+}
+
+// FindByPriceRange retrieves the flights whose total is in the currency and whose amount lies between min and max,
+// both inclusive, by increasing amount.
+func (r *RepoFlights) FindByPriceRange(ctx context.Context, currency string, min, max float64) (domain.Flights, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	return flightsAt(r.data, r.index.priceRange(r.data, currency, min, max)), nil
+}
+
+// FindByDepartureRange retrieves the flights first departing in [after, before), by departure time. A zero bound is open.
+func (r *RepoFlights) FindByDepartureRange(ctx context.Context, after, before time.Time) (domain.Flights, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
 	}
-	return flights, nil
+	return flightsAt(r.data, r.index.departureRange(r.data, after, before)), nil
 }
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/config"
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// UpstreamError reports that a provider could not be reached or answered with an unexpected status.
+type UpstreamError struct {
+	Op  string
+	Err error
+}
+
+func (e *UpstreamError) Error() string { return e.Op + ": " + e.Err.Error() }
+func (e *UpstreamError) Unwrap() error { return e.Err }
+
+// Load fetches the payloads of both providers and returns a Multi composed of their repositories.
+// Fetch failures are reported as *UpstreamError, decoding failures as plain errors.
+func Load(ctx context.Context, flightsURL, flightToBookURL string) (*Multi, error) {
+	b1, err := api.GetDataFromApi(ctx, flightsURL)
+	if err != nil {
+		return nil, &UpstreamError{Op: "fetch flights", Err: err}
+	}
+
+	b2, err := api.GetDataFromApi(ctx, flightToBookURL)
+	if err != nil {
+		return nil, &UpstreamError{Op: "fetch flight_to_book", Err: err}
+	}
+
+	rA, err := NewRepoFlightsFromReader(bytes.NewReader(b1))
+	if err != nil {
+		return nil, fmt.Errorf("decode flights: %w", err)
+	}
+	rB, err := NewRepoFlightToBookFromReader(bytes.NewReader(b2))
+	if err != nil {
+		return nil, fmt.Errorf("decode flight_to_book: %w", err)
+	}
+
+	return NewMulti(rA, rB), nil
+}
+
+// LoadConfigured fetches both providers at their configured URLs and returns the aggregating repository or an error.
+func LoadConfigured(ctx context.Context) (*Multi, error) {
+	return Load(ctx, config.SERVER1_URL+"flights", config.SERVER2_URL+"flight_to_book")
+}
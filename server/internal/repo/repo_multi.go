@@ -4,6 +4,7 @@ import (
 	"aggregator/internal/domain"
 	"context"
 	"errors"
+	"time"
 )
 
 type Multi struct {
@@ -15,6 +16,25 @@ func NewMulti(repos ...domain.FlightsRepository) *Multi {
 	return &Multi{repos: repos}
 }
 
+// FromFlights returns a Multi over flights that were already aggregated, such as a catalogue version, with one
+// in-memory repository per source in the order sources first appear. Flights listed by a Multi are grouped by source,
+// so it lists them back in their given order.
+func FromFlights(flights domain.Flights) *Multi {
+	var order []string
+	groups := make(map[string]domain.Flights)
+	for _, f := range flights {
+		if _, ok := groups[f.Source()]; !ok {
+			order = append(order, f.Source())
+		}
+		groups[f.Source()] = append(groups[f.Source()], f)
+	}
+	repos := make([]domain.FlightsRepository, len(order))
+	for i, source := range order {
+		repos[i] = &RepoFlights{data: groups[source], index: newFlightIndex(groups[source])}
+	}
+	return NewMulti(repos...)
+}
+
 // List retrieves all flights from multiple repositories and returns them as a combined collection or an error.
 func (m *Multi) List(ctx context.Context) (domain.Flights, error) {
 	select {
@@ -34,6 +54,21 @@ func (m *Multi) List(ctx context.Context) (domain.Flights, error) {
 	return all, nil
 }
 
+// Filter retrieves the flights of every repository and keeps the ones for which match returns true.
+func (m *Multi) Filter(ctx context.Context, match func(domain.Flight) bool) (domain.Flights, error) {
+	all, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(domain.Flights, 0, len(all))
+	for _, f := range all {
+		if match(f) {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
 // FindByID searches for a flight by ID across multiple repositories and returns the flight or an error if not found.
 func (m *Multi) FindByID(ctx context.Context, id string) (domain.Flight, error) {
 	select {
@@ -94,6 +129,33 @@ func (m *Multi) FindByNumber(ctx context.Context, id string) (domain.Flight, err
 	return domain.Flight{}, lastErr
 }
 
+// FindAllByNumber retrieves every flight on the given flight number across all repositories, unlike FindByNumber
+// which stops at the first match. Returns a combined collection of flights or an error if none are found.
+func (m *Multi) FindAllByNumber(ctx context.Context, number string) (domain.Flights, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var flights domain.Flights
+
+	for _, r := range m.repos {
+		f, err := r.FindAllByNumber(ctx, number)
+		if err != nil {
+			if errors.Is(err, domain.ErrFlightNotFound) {
+				continue
+			}
+			return domain.Flights{}, err
+		}
+		flights = append(flights, f...)
+	}
+	if len(flights) == 0 {
+		return nil, domain.ErrFlightsNotFound
+	}
+	return flights, nil
+}
+
 // FindByPassenger searches for flights associated with a specific passenger name across multiple repositories.
 // Returns a combined collection of flights or an error if none are found.
 func (m *Multi) FindByPassenger(ctx context.Context, passengerName string) (domain.Flights, error) {
@@ -174,3 +236,57 @@ func (m *Multi) FindByPrice(ctx context.Context, price float64) (domain.Flights,
 	}
 	return flights, nil
 }
+
+// FindByPriceRange retrieves flights whose total is in the currency and whose amount lies between min and max, both
+// inclusive, across multiple repositories. Returns a combined collection of flights or an error if none are found.
+func (m *Multi) FindByPriceRange(ctx context.Context, currency string, min, max float64) (domain.Flights, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var flights domain.Flights
+
+	for _, r := range m.repos {
+		f, err := r.FindByPriceRange(ctx, currency, min, max)
+		if err != nil {
+			if errors.Is(err, domain.ErrFlightNotFound) {
+				continue
+			}
+			return domain.Flights{}, err
+		}
+		flights = append(flights, f...)
+	}
+	if len(flights) == 0 {
+		return nil, domain.ErrFlightsNotFound
+	}
+	return flights, nil
+}
+
+// FindByDepartureRange retrieves flights first departing in [after, before) across multiple repositories.
+// Returns a combined collection of flights or an error if none are found.
+func (m *Multi) FindByDepartureRange(ctx context.Context, after, before time.Time) (domain.Flights, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var flights domain.Flights
+
+	for _, r := range m.repos {
+		f, err := r.FindByDepartureRange(ctx, after, before)
+		if err != nil {
+			if errors.Is(err, domain.ErrFlightNotFound) {
+				continue
+			}
+			return domain.Flights{}, err
+		}
+		flights = append(flights, f...)
+	}
+	if len(flights) == 0 {
+		return nil, domain.ErrFlightsNotFound
+	}
+	return flights, nil
+}
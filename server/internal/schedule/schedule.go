@@ -0,0 +1,186 @@
+package schedule
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/jsonfile"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Tracked is a booking whose schedule changed: its segments as before the first change detected, and as they now are.
+type Tracked struct {
+	Original domain.Flight
+	Current  domain.Flight
+	// DetectedAt is when the first change was detected, UpdatedAt when the latest one was.
+	DetectedAt time.Time
+	UpdatedAt  time.Time
+	// Version is the catalogue version of the latest change.
+	Version uint64
+}
+
+// Tracker follows the catalogue and keeps the bookings that currently fly at other times than originally.
+// A booking leaves the tracker when it is back on its original schedule, is rerouted or disappears.
+type Tracker struct {
+	mu      sync.RWMutex
+	path    string
+	tracked map[string]*Tracked
+	// last holds the bookings of the latest catalogue version seen, which the next version is compared to.
+	last map[string]domain.Flight
+}
+
+// Default is the tracker following catalogue.Default. It keeps its state in memory until main opens the schedule file.
+var Default = New()
+
+// New creates an empty tracker kept in memory only.
+func New() *Tracker {
+	return &Tracker{tracked: make(map[string]*Tracked), last: make(map[string]domain.Flight)}
+}
+
+// storedTracked is a Tracked booking as saved to the file of a tracker.
+type storedTracked struct {
+	Original   domain.FlightSnapshot `json:"original"`
+	Current    domain.FlightSnapshot `json:"current"`
+	DetectedAt time.Time             `json:"detectedAt"`
+	UpdatedAt  time.Time             `json:"updatedAt"`
+	Version    uint64                `json:"version"`
+}
+
+// state is the content of the file of a tracker: the tracked bookings and the bookings last seen.
+type state struct {
+	Tracked []storedTracked        `json:"tracked"`
+	Last    domain.FlightsSnapshot `json:"last"`
+}
+
+// Open loads the state of a JSON file, if it exists, and returns a tracker saving to it after every catalogue
+// version, so that the first version after a restart is compared to the last one seen before.
+func Open(path string) (*Tracker, error) {
+	t := New()
+	t.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read schedule: %w", err)
+	}
+	var st state
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("decode schedule %s: %w", path, err)
+	}
+	for _, s := range st.Tracked {
+		tr := &Tracked{Original: *s.Original.ToDomain(), Current: *s.Current.ToDomain(), DetectedAt: s.DetectedAt,
+			UpdatedAt: s.UpdatedAt, Version: s.Version}
+		t.tracked[key(tr.Current)] = tr
+	}
+	for _, s := range st.Last {
+		f := *s.ToDomain()
+		t.last[key(f)] = f
+	}
+	return t, nil
+}
+
+// key identifies a booking across providers.
+func key(f domain.Flight) string { return f.Source() + "|" + f.ID() }
+
+// Record is a catalogue.Listener comparing the segment times of every booking in the new version to the bookings last
+// seen. These are those of prev, unless the tracker was reopened after a restart.
+func (t *Tracker) Record(_, next catalogue.Version) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	before := t.last
+	t.last = make(map[string]domain.Flight, len(next.Flights))
+	for _, f := range next.Flights {
+		k := key(f)
+		t.last[k] = f
+		if tr, ok := t.tracked[k]; ok {
+			if !Comparable(tr.Original, f) || !Retimed(tr.Original, f) {
+				delete(t.tracked, k)
+				continue
+			}
+			if Retimed(tr.Current, f) {
+				tr.UpdatedAt, tr.Version = next.RefreshedAt, next.Number
+			}
+			tr.Current = f
+			continue
+		}
+		if old, ok := before[k]; ok && Comparable(old, f) && Retimed(old, f) {
+			t.tracked[k] = &Tracked{Original: old, Current: f, DetectedAt: next.RefreshedAt, UpdatedAt: next.RefreshedAt, Version: next.Number}
+		}
+	}
+	for k := range t.tracked {
+		if _, ok := t.last[k]; !ok {
+			delete(t.tracked, k)
+		}
+	}
+	if err := t.save(); err != nil {
+		fmt.Fprintln(os.Stderr, "schedule:", err)
+	}
+}
+
+// save writes the state to the file of the tracker, if any. The caller holds the lock.
+func (t *Tracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+	st := state{Tracked: make([]storedTracked, 0, len(t.tracked)), Last: make(domain.FlightsSnapshot, 0, len(t.last))}
+	for _, tr := range t.tracked {
+		st.Tracked = append(st.Tracked, storedTracked{Original: tr.Original.Snapshot(), Current: tr.Current.Snapshot(),
+			DetectedAt: tr.DetectedAt, UpdatedAt: tr.UpdatedAt, Version: tr.Version})
+	}
+	for _, f := range t.last {
+		st.Last = append(st.Last, f.Snapshot())
+	}
+	if err := jsonfile.Write(t.path, st); err != nil {
+		return fmt.Errorf("save schedule: %w", err)
+	}
+	return nil
+}
+
+// List returns the tracked bookings by source then id.
+func (t *Tracker) List() []Tracked {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	out := make([]Tracked, 0, len(t.tracked))
+	for _, tr := range t.tracked {
+		out = append(out, *tr)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Current.Source() != out[j].Current.Source() {
+			return out[i].Current.Source() < out[j].Current.Source()
+		}
+		return out[i].Current.ID() < out[j].Current.ID()
+	})
+	return out
+}
+
+// Comparable reports whether two versions of a booking fly the same legs, airport by airport, so that their times
+// can be compared. A booking moved onto other legs is rerouted rather than retimed.
+func Comparable(a, b domain.Flight) bool {
+	sa, sb := a.Segments(), b.Segments()
+	if len(sa) != len(sb) || len(sa) == 0 {
+		return false
+	}
+	for i := range sa {
+		if sa[i].Departure() != sb[i].Departure() || sa[i].Arrival() != sb[i].Arrival() {
+			return false
+		}
+	}
+	return true
+}
+
+// Retimed reports whether two comparable versions of a booking differ in any departure or arrival time.
+func Retimed(a, b domain.Flight) bool {
+	sa, sb := a.Segments(), b.Segments()
+	for i := range sa {
+		if !sa[i].DepartTime().Equal(sb[i].DepartTime()) || !sa[i].ArriveTime().Equal(sb[i].ArriveTime()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package search
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"aggregator/internal/textnorm"
+	"errors"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrEmptyQuery is returned when a query contains no searchable word.
+var ErrEmptyQuery = errors.New("empty search query")
+
+// Field names a searchable part of a flight; highlights are keyed by field.
+type Field string
+
+const (
+	FieldID           Field = "id"
+	FieldPassenger    Field = "passengerName"
+	FieldFlightNumber Field = "flightNumber"
+	FieldCarrier      Field = "carrier"
+	FieldAirport      Field = "airport"
+	FieldCity         Field = "city"
+	FieldStatus       Field = "status"
+	FieldCabin        Field = "cabin"
+	FieldDeparture    Field = "departure"
+)
+
+// fieldWeights rates how telling a match on each field is. A term matching several fields counts its best one.
+var fieldWeights = map[Field]float64{
+	FieldID:           5,
+	FieldFlightNumber: 4,
+	FieldPassenger:    3,
+	FieldAirport:      2.5,
+	FieldCarrier:      2,
+	FieldCity:         2,
+	FieldDeparture:    1.5,
+	FieldStatus:       1,
+	FieldCabin:        1,
+}
+
+// prefixWeight scales matches where a query word is only the beginning of an indexed term.
+const prefixWeight = 0.5
+
+// minPrefixLength is the shortest query word matched as a prefix; shorter words must match a whole term.
+const minPrefixLength = 2
+
+// posting records that a term appears in a field of a document.
+type posting struct {
+	doc   int
+	field Field
+}
+
+// document is an indexed flight with the displayed value of each of its fields.
+type document struct {
+	flight domain.Flight
+	values map[Field]string
+}
+
+// Index is an in-memory inverted index over the flights of a catalogue version.
+type Index struct {
+	mu       sync.RWMutex
+	version  uint64
+	docs     []document
+	postings map[string][]posting
+	// terms lists the keys of postings in order, for prefix lookups.
+	terms []string
+}
+
+// Default is the index kept up to date with catalogue.Default.
+var Default = New()
+
+// New creates an empty index.
+func New() *Index {
+	return &Index{postings: make(map[string][]posting)}
+}
+
+// Rebuild replaces the content of the index by the flights of the next version. Its signature makes it a catalogue.Listener.
+func (ix *Index) Rebuild(_, next catalogue.Version) {
+	docs := make([]document, 0, len(next.Flights))
+	postings := make(map[string][]posting)
+	for i, f := range next.Flights {
+		doc := newDocument(f)
+		docs = append(docs, doc)
+		for field, terms := range doc.terms() {
+			for _, term := range terms {
+				postings[term] = append(postings[term], posting{doc: i, field: field})
+			}
+		}
+	}
+	terms := make([]string, 0, len(postings))
+	for term := range postings {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.version = next.Number
+	ix.docs = docs
+	ix.postings = postings
+	ix.terms = terms
+}
+
+// Version returns the catalogue version the index was built from.
+func (ix *Index) Version() uint64 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.version
+}
+
+// newDocument extracts the searchable fields of a flight.
+func newDocument(f domain.Flight) document {
+	values := map[Field]string{
+		FieldID:        f.ID(),
+		FieldPassenger: f.PassengerName(),
+		FieldStatus:    f.Status(),
+	}
+	segs := f.Segments()
+	if len(segs) > 0 {
+		var numbers, carriers, airports, cities, cabins []string
+		seenCabins, seenCarriers := make(map[string]bool), make(map[string]bool)
+		airports = append(airports, segs[0].Departure())
+		for _, s := range segs {
+			numbers = append(numbers, s.FlightNumber())
+			if s.IsCodeshare() {
+				numbers = append(numbers, s.OperatingFlightNumber())
+			}
+			airports = append(airports, s.Arrival())
+			if c := string(s.Cabin()); c != "" && !seenCabins[c] {
+				seenCabins[c] = true
+				cabins = append(cabins, c)
+			}
+			if a, ok := reference.LookupAirline(s.Carrier()); ok && !seenCarriers[a.Code] {
+				seenCarriers[a.Code] = true
+				carriers = append(carriers, a.Name)
+			}
+		}
+		for _, code := range airports {
+			if a, ok := reference.LookupAirport(code); ok {
+				cities = append(cities, a.City)
+			}
+		}
+		values[FieldFlightNumber] = strings.Join(numbers, " ")
+		values[FieldCarrier] = strings.Join(carriers, ", ")
+		values[FieldCabin] = strings.Join(cabins, " ")
+		values[FieldAirport] = strings.Join(airports, " ")
+		values[FieldCity] = strings.Join(cities, " ")
+		values[FieldDeparture] = segs[0].DepartTime().UTC().Format(time.DateOnly)
+	}
+	return document{flight: f, values: values}
+}
+
+// terms returns the indexed terms of every field. Departure dates are indexed as the full date, the month, the year
+// and the English month name, so that "2026-01-01", "2026-01", "2026", "january" and "jan" all find the flight.
+func (d document) terms() map[Field][]string {
+	terms := make(map[Field][]string, len(d.values))
+	for field, value := range d.values {
+		if field != FieldDeparture {
+			terms[field] = textnorm.Tokens(value)
+			continue
+		}
+		day, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			continue
+		}
+		month := strings.ToLower(day.Month().String())
+		terms[field] = []string{value, day.Format("2006-01"), day.Format("2006"), month, month[:3]}
+	}
+	return terms
+}
+
+// queryTerms splits a query into folded words. Dates such as "2026-01-05" or "2026-01" are kept whole.
+func queryTerms(q string) []string {
+	var terms []string
+	for _, word := range strings.Fields(q) {
+		if isDate(word) {
+			terms = append(terms, word)
+			continue
+		}
+		terms = append(terms, textnorm.Tokens(word)...)
+	}
+	return terms
+}
+
+// isDate reports whether a query word is a date or a month in ISO format.
+func isDate(word string) bool {
+	for _, layout := range []string{time.DateOnly, "2006-01"} {
+		if _, err := time.Parse(layout, word); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is a flight matching a search, with its relevance and the matched fields highlighted.
+type Result struct {
+	Flight domain.Flight
+	Score  float64
+	// Highlights maps each matched field to its value with the matched words wrapped in <em> tags.
+	Highlights map[Field]string
+}
+
+// ResultSnapshot is the JSON representation of a Result.
+type ResultSnapshot struct {
+	domain.FlightSnapshot
+	Score      float64          `json:"score"`
+	Highlights map[Field]string `json:"highlights"`
+}
+
+// Snapshot returns the JSON representation of the result.
+func (r Result) Snapshot() ResultSnapshot {
+	return ResultSnapshot{FlightSnapshot: r.Flight.Snapshot(), Score: r.Score, Highlights: r.Highlights}
+}
+
+// Search returns the flights matching every word of the query, by decreasing score then source and booking id.
+// A word matches a term equal to it, or starting with it at a lower weight. Returns ErrEmptyQuery when the query
+// has no searchable word.
+func (ix *Index) Search(q string) ([]Result, error) {
+	words := queryTerms(q)
+	if len(words) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	scores := make(map[int]float64)
+	matched := make(map[int]map[Field]map[string]bool)
+	for i, word := range words {
+		best := make(map[int]float64)
+		for term, weight := range ix.lookup(word) {
+			for _, p := range ix.postings[term] {
+				if i > 0 {
+					if _, ok := scores[p.doc]; !ok {
+						continue
+					}
+				}
+				best[p.doc] = max(best[p.doc], weight*fieldWeights[p.field])
+				if matched[p.doc] == nil {
+					matched[p.doc] = make(map[Field]map[string]bool)
+				}
+				if matched[p.doc][p.field] == nil {
+					matched[p.doc][p.field] = make(map[string]bool)
+				}
+				matched[p.doc][p.field][term] = true
+			}
+		}
+		// every word must match: documents missed by this word are dropped
+		next := make(map[int]float64, len(best))
+		for doc, s := range best {
+			next[doc] = scores[doc] + s
+		}
+		scores = next
+		if len(scores) == 0 {
+			return nil, nil
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for doc, score := range scores {
+		d := ix.docs[doc]
+		highlights := make(map[Field]string, len(matched[doc]))
+		for field, terms := range matched[doc] {
+			highlights[field] = highlight(d.values[field], terms)
+		}
+		results = append(results, Result{Flight: d.flight, Score: score, Highlights: highlights})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Flight.Source() != b.Flight.Source() {
+			return a.Flight.Source() < b.Flight.Source()
+		}
+		return a.Flight.ID() < b.Flight.ID()
+	})
+	return results, nil
+}
+
+// lookup returns the indexed terms matched by a query word with their weight: 1 for the term equal to the word,
+// prefixWeight for longer terms starting with it.
+func (ix *Index) lookup(word string) map[string]float64 {
+	found := make(map[string]float64)
+	if _, ok := ix.postings[word]; ok {
+		found[word] = 1
+	}
+	if len([]rune(word)) < minPrefixLength {
+		return found
+	}
+	for i := sort.SearchStrings(ix.terms, word); i < len(ix.terms) && strings.HasPrefix(ix.terms[i], word); i++ {
+		if ix.terms[i] != word {
+			found[ix.terms[i]] = prefixWeight
+		}
+	}
+	return found
+}
+
+// highlight wraps in <em> tags the words of value whose folded form is one of the matched terms.
+// When no word is found, as for a date matched by its month name, the whole value is wrapped.
+// Everything but the tags is HTML-escaped: values come from the providers and highlights are rendered as HTML.
+func highlight(value string, terms map[string]bool) string {
+	var b strings.Builder
+	found := false
+	runes := []rune(value)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			b.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if terms[textnorm.Fold(word)] {
+			found = true
+			b.WriteString("<em>" + html.EscapeString(word) + "</em>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		i = j
+	}
+	if !found {
+		return "<em>" + html.EscapeString(value) + "</em>"
+	}
+	return b.String()
+}
+
+// isWordRune reports whether r belongs to a word, as textnorm splits them.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"math"
+)
+
+// CheckedBagFee returns the cheapest checked bag sold with the booking, converted into the currency of its total.
+// It returns 0 when a checked bag is already included, and false when none is included and no known fee applies.
+// Bag fees in a currency missing from the rate table are ignored.
+func CheckedBagFee(f domain.Flight) (float64, bool) {
+	if f.IncludesCheckedBag() {
+		return 0, true
+	}
+	fee, found := math.Inf(1), false
+	for _, a := range f.Ancillaries() {
+		if a.Kind() != domain.AncillaryCheckedBag {
+			continue
+		}
+		amount, err := ConvertAmount(a.Price().Amount(), a.Price().Currency(), f.Total().Currency())
+		if err != nil {
+			continue
+		}
+		fee, found = min(fee, amount), true
+	}
+	if !found {
+		return 0, false
+	}
+	return fee, true
+}
+
+// PriceWithCheckedBag returns the total of the booking plus the fee of a checked bag, converted into the given
+// currency so that bookings priced in different currencies compare. It returns false when the booking neither
+// includes a checked bag nor sells one at a known fee, or when its total is in a currency missing from the rate table.
+func PriceWithCheckedBag(f domain.Flight, currency string) (float64, bool) {
+	fee, ok := CheckedBagFee(f)
+	if !ok {
+		return 0, false
+	}
+	price, err := ConvertAmount(f.Total().Amount()+fee, f.Total().Currency(), currency)
+	if err != nil {
+		return 0, false
+	}
+	return price, true
+}
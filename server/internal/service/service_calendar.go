@@ -0,0 +1,147 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"aggregator/internal/repo"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CalendarQuery selects the journeys of a route departing in one month, and the currency prices are compared in.
+type CalendarQuery struct {
+	// Filters holds the route as From and To, and any other /flights filter.
+	Filters Query
+	// Month is the first day of the month, at midnight UTC.
+	Month time.Time
+	// PriceCurrency is the currency prices are normalized into; BaseCurrency by default.
+	PriceCurrency string
+}
+
+// CalendarDay is the cheapest journey departing on one day. Price and Flight are nil when no option has a price
+// in a known currency, including days without any option.
+type CalendarDay struct {
+	Date    string                 `json:"date"`
+	Options int                    `json:"options"`
+	Price   *float64               `json:"lowestPrice"`
+	Flight  *domain.FlightSnapshot `json:"flight"`
+}
+
+// FareCalendar lists every day of the month, in order.
+type FareCalendar struct {
+	Route    string        `json:"route"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Month    string        `json:"month"`
+	Currency string        `json:"currency"`
+	Days     []CalendarDay `json:"days"`
+}
+
+// ParseCalendarQuery reads a route written "CDG-HND" and the "month" (YYYY-MM) and "priceCurrency" query params.
+// The other /flights filters, such as maxStops or cabin, are applied too; from and to come from the route.
+func ParseCalendarQuery(route string, values url.Values) (CalendarQuery, error) {
+	from, to, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(route)), "-")
+	if !ok || !isCode(from, 3, false) || !isCode(to, 3, false) {
+		return CalendarQuery{}, fmt.Errorf("%w: route %q must be written FROM-TO with IATA airport codes", ErrInvalidQuery, route)
+	}
+	if from == to {
+		return CalendarQuery{}, fmt.Errorf("%w: route %q has the same origin and destination", ErrInvalidQuery, route)
+	}
+
+	month := values.Get("month")
+	if month == "" {
+		return CalendarQuery{}, fmt.Errorf("%w: month is required", ErrInvalidQuery)
+	}
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return CalendarQuery{}, fmt.Errorf("%w: month %q must be formatted as YYYY-MM", ErrInvalidQuery, month)
+	}
+
+	values = cloneValues(values)
+	values.Set("from", from)
+	values.Set("to", to)
+	filters, err := ParseQuery(values)
+	if err != nil {
+		return CalendarQuery{}, err
+	}
+
+	q := CalendarQuery{Filters: filters, Month: start, PriceCurrency: BaseCurrency}
+	if s := strings.ToUpper(strings.TrimSpace(values.Get("priceCurrency"))); s != "" {
+		if !IsKnownCurrency(s) {
+			return CalendarQuery{}, fmt.Errorf("%w: %w: %s", ErrInvalidQuery, ErrUnknownCurrency, s)
+		}
+		q.PriceCurrency = s
+	}
+	return q, nil
+}
+
+// BuildFareCalendar finds, for every day of the month, the cheapest of the flights matching the query.
+// A journey belongs to the day it departs on, in the local time of its origin airport.
+// Ties on price go to the first flight in the default ordering.
+func BuildFareCalendar(flights domain.Flights, q CalendarQuery) FareCalendar {
+	from, to := q.Filters.From, q.Filters.To
+	cal := FareCalendar{
+		Route:    from + "-" + to,
+		From:     from,
+		To:       to,
+		Month:    q.Month.Format("2006-01"),
+		Currency: q.PriceCurrency,
+	}
+
+	byDate := make(map[string]int)
+	for d := q.Month; d.Month() == q.Month.Month(); d = d.AddDate(0, 0, 1) {
+		byDate[d.Format(time.DateOnly)] = len(cal.Days)
+		cal.Days = append(cal.Days, CalendarDay{Date: d.Format(time.DateOnly)})
+	}
+
+	flights = append(domain.Flights(nil), flights...)
+	DefaultOrdering.Sort(flights)
+	for _, f := range flights {
+		if !q.Filters.Match(f) {
+			continue
+		}
+		first := f.Segments()[0]
+		date := first.DepartTime().In(reference.AirportLocation(first.Departure())).Format(time.DateOnly)
+		i, ok := byDate[date]
+		if !ok {
+			continue
+		}
+		day := &cal.Days[i]
+		day.Options++
+
+		amount, err := NormalizedAmount(f, q.PriceCurrency)
+		if err != nil {
+			continue
+		}
+		if price := roundTo(amount, 2); day.Price == nil || price < *day.Price {
+			snapshot := f.Snapshot()
+			day.Price, day.Flight = &price, &snapshot
+		}
+	}
+	return cal
+}
+
+// GetFareCalendar builds the fare calendar of a route from the flights of every repository.
+func GetFareCalendar(ctx context.Context, r *repo.Multi, q CalendarQuery) (FareCalendar, error) {
+	after := q.Month.AddDate(0, 0, -1)
+	before := q.Month.AddDate(0, 1, 1)
+	// a day of margin on both sides covers every time zone; the exact day is checked in local time
+	flights, err := r.FindByDepartureRange(ctx, after, before)
+	if err != nil && !errors.Is(err, domain.ErrFlightsNotFound) {
+		return FareCalendar{}, err
+	}
+	return BuildFareCalendar(flights, q), nil
+}
+
+// cloneValues returns a copy of the query params that can be modified.
+func cloneValues(values url.Values) url.Values {
+	out := make(url.Values, len(values))
+	for k, v := range values {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownCurrency is returned when an amount is expressed in a currency missing from the rate table.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// BaseCurrency is the currency every rate of eurRates is expressed against and the default comparison currency.
+const BaseCurrency = "EUR"
+
+// eurRates holds indicative exchange rates as units of currency per 1 EUR.
+// They are only meant to make prices comparable across providers, not to price tickets.
+var eurRates = map[string]float64{
+	"EUR": 1,
+	"USD": 1.08,
+	"GBP": 0.85,
+	"CHF": 0.95,
+	"JPY": 162.0,
+	"CNY": 7.8,
+	"HKD": 8.45,
+	"KRW": 1450.0,
+	"SGD": 1.45,
+	"AED": 3.97,
+	"QAR": 3.93,
+	"CAD": 1.47,
+	"AUD": 1.64,
+	"SEK": 11.4,
+	"NOK": 11.6,
+	"DKK": 7.46,
+}
+
+// IsKnownCurrency reports whether amounts in the currency can be converted.
+func IsKnownCurrency(currency string) bool {
+	_, ok := eurRates[strings.ToUpper(currency)]
+	return ok
+}
+
+// knownCurrencies returns the currencies of the rate table, sorted.
+func knownCurrencies() []string {
+	out := make([]string, 0, len(eurRates))
+	for currency := range eurRates {
+		out = append(out, currency)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ConvertAmount converts an amount between two currencies using the indicative rate table.
+func ConvertAmount(amount float64, from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, nil
+	}
+	fromRate, ok := eurRates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, from)
+	}
+	toRate, ok := eurRates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, to)
+	}
+	return amount / fromRate * toRate, nil
+}
+
+// NormalizedAmount returns the total of a flight converted into the given currency.
+func NormalizedAmount(f domain.Flight, currency string) (float64, error) {
+	return ConvertAmount(f.Total().Amount(), f.Total().Currency(), currency)
+}
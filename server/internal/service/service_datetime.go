@@ -0,0 +1,154 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TimeZoneMode tells how bounds given without an offset are interpreted.
+type TimeZoneMode string
+
+const (
+	// TimeZoneUTC interprets floating bounds in UTC.
+	TimeZoneUTC TimeZoneMode = "utc"
+	// TimeZoneLocal interprets floating bounds in the local time of the airport: the origin for departures,
+	// the destination for arrivals.
+	TimeZoneLocal TimeZoneMode = "local"
+)
+
+// TimeBound is one end of a date or time window.
+// Bounds parsed from input without an offset are floating: they hold a wall clock time that is placed in a
+// time zone only when compared with a flight.
+type TimeBound struct {
+	At       time.Time
+	Floating bool
+}
+
+// Extreme UTC offsets of any time zone: a floating bound read in local time may designate any instant in between.
+const (
+	minUTCOffset = -12 * time.Hour
+	maxUTCOffset = 14 * time.Hour
+)
+
+// floatingLayouts are the accepted wall clock layouts, from most to least precise.
+var floatingLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// ParseTimeBound parses an RFC 3339 datetime, a datetime without offset or an ISO date.
+// When endOfDay is true a bare date designates the end of that day, so that an upper bound includes the whole day.
+func ParseTimeBound(s string, endOfDay bool) (TimeBound, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return TimeBound{At: t}, nil
+	}
+	for _, layout := range floatingLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return TimeBound{At: t, Floating: true}, nil
+		}
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return TimeBound{At: t, Floating: true}, nil
+	}
+	return TimeBound{}, fmt.Errorf("%q is not an ISO date or datetime", s)
+}
+
+// IsZero reports whether the bound is unset.
+func (b TimeBound) IsZero() bool { return b.At.IsZero() }
+
+// Resolve returns the instant designated by the bound when floating times are read in loc.
+func (b TimeBound) Resolve(loc *time.Location) time.Time {
+	if !b.Floating {
+		return b.At
+	}
+	return time.Date(b.At.Year(), b.At.Month(), b.At.Day(), b.At.Hour(), b.At.Minute(), b.At.Second(), 0, loc)
+}
+
+// TimeWindow selects instants from After (inclusive) to Before (exclusive). Either end may be unset.
+type TimeWindow struct {
+	After  TimeBound
+	Before TimeBound
+}
+
+// IsZero reports whether neither end of the window is set.
+func (w TimeWindow) IsZero() bool { return w.After.IsZero() && w.Before.IsZero() }
+
+// HasFloating reports whether one end of the window is floating and needs a time zone to be compared.
+func (w TimeWindow) HasFloating() bool {
+	return (!w.After.IsZero() && w.After.Floating) || (!w.Before.IsZero() && w.Before.Floating)
+}
+
+// Contains reports whether t falls into the window, reading floating bounds in loc.
+func (w TimeWindow) Contains(t time.Time, loc *time.Location) bool {
+	if !w.After.IsZero() && t.Before(w.After.Resolve(loc)) {
+		return false
+	}
+	if !w.Before.IsZero() && !t.Before(w.Before.Resolve(loc)) {
+		return false
+	}
+	return true
+}
+
+// parseTimeWindow reads "<prefix>After", "<prefix>Before" and the "<prefix>Date" shortcut covering a whole day.
+func parseTimeWindow(values url.Values, prefix string) (TimeWindow, error) {
+	var w TimeWindow
+	var err error
+
+	if s := values.Get(prefix + "Date"); s != "" {
+		day, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return TimeWindow{}, fmt.Errorf("%w: %sDate %q is not an ISO date", ErrInvalidQuery, prefix, s)
+		}
+		w.After = TimeBound{At: day, Floating: true}
+		w.Before = TimeBound{At: day.AddDate(0, 0, 1), Floating: true}
+	}
+	if s := values.Get(prefix + "After"); s != "" {
+		if w.After, err = ParseTimeBound(s, false); err != nil {
+			return TimeWindow{}, fmt.Errorf("%w: %sAfter %v", ErrInvalidQuery, prefix, err)
+		}
+	}
+	if s := values.Get(prefix + "Before"); s != "" {
+		if w.Before, err = ParseTimeBound(s, true); err != nil {
+			return TimeWindow{}, fmt.Errorf("%w: %sBefore %v", ErrInvalidQuery, prefix, err)
+		}
+	}
+	return w, nil
+}
+
+// check rejects a window that cannot contain any instant. Two floating bounds are read in the same time zone and
+// compared as wall clock times. A floating bound compared with a fixed one is read in UTC, or in local mode at the
+// offset that widens the window most, since the airport is only known when a flight is compared.
+func (w TimeWindow) check(prefix string, mode TimeZoneMode) error {
+	if w.After.IsZero() || w.Before.IsZero() {
+		return nil
+	}
+	after, before := w.After.At, w.Before.At
+	if w.After.Floating != w.Before.Floating {
+		after, before = w.After.Resolve(time.UTC), w.Before.Resolve(time.UTC)
+		if mode == TimeZoneLocal && w.After.Floating {
+			after = after.Add(-maxUTCOffset)
+		}
+		if mode == TimeZoneLocal && w.Before.Floating {
+			before = before.Add(-minUTCOffset)
+		}
+	}
+	if !after.Before(before) {
+		return fmt.Errorf("%w: %sAfter must be earlier than %sBefore", ErrInvalidQuery, prefix, prefix)
+	}
+	return nil
+}
+
+// parseTimeZoneMode reads the "tz" query param, defaulting to UTC.
+func parseTimeZoneMode(values url.Values) (TimeZoneMode, error) {
+	switch mode := TimeZoneMode(strings.ToLower(values.Get("tz"))); mode {
+	case "", TimeZoneUTC:
+		return TimeZoneUTC, nil
+	case TimeZoneLocal:
+		return TimeZoneLocal, nil
+	default:
+		return "", fmt.Errorf("%w: tz must be utc or local", ErrInvalidQuery)
+	}
+}
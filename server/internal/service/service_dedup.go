@@ -0,0 +1,155 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MergePolicy decides which provider record wins when duplicates disagree.
+type MergePolicy string
+
+const (
+	// PolicyCheapest keeps the record with the lowest total amount.
+	PolicyCheapest MergePolicy = "cheapest"
+	// PolicyMostRecent keeps the record with the latest scheduled departure, i.e. the most recently rescheduled one.
+	PolicyMostRecent MergePolicy = "recent"
+	// PolicyProviderPriority keeps the record coming from the provider listed first in MergeOptions.Priority.
+	PolicyProviderPriority MergePolicy = "priority"
+)
+
+// MergeOptions configures how duplicate bookings are merged into a canonical record.
+type MergeOptions struct {
+	Policy MergePolicy
+	// Priority lists provider sources from most to least trusted. It breaks ties for every policy.
+	Priority []string
+}
+
+// ParseMergePolicy converts a user supplied policy name into a MergePolicy.
+func ParseMergePolicy(s string) (MergePolicy, error) {
+	switch MergePolicy(strings.ToLower(strings.TrimSpace(s))) {
+	case PolicyCheapest, "":
+		return PolicyCheapest, nil
+	case PolicyMostRecent, "most_recent", "latest":
+		return PolicyMostRecent, nil
+	case PolicyProviderPriority, "provider", "provider_priority":
+		return PolicyProviderPriority, nil
+	default:
+		return "", fmt.Errorf("unknown merge policy %q", s)
+	}
+}
+
+// DuplicateKey returns the key under which bookings of the same passenger on the same physical flights collide,
+// each segment identified by its PhysicalFlightKey.
+// Returns an empty key for flights without segments, which are never considered duplicates.
+func DuplicateKey(f domain.Flight) string {
+	segs := f.Segments()
+	if len(segs) == 0 {
+		return ""
+	}
+	keys := make([]string, len(segs))
+	for i, s := range segs {
+		keys[i] = PhysicalFlightKey(s)
+	}
+	return passengerKey(f.PassengerName()) + "|" + strings.Join(keys, ",")
+}
+
+// PhysicalFlightKey identifies the physical flight a segment is flown on: its operating flight number, departure airport
+// and UTC departure date. Codeshare segments sold under different numbers share the key of the flight they are flown on.
+func PhysicalFlightKey(s domain.Segment) string {
+	return domain.NormalizeFlightNumber(s.OperatingFlightNumber()) + "|" +
+		strings.ToUpper(s.Departure()) + "|" +
+		s.DepartTime().UTC().Format("2006-01-02")
+}
+
+// passengerKey normalizes a passenger name so that case and spacing differences do not prevent a match.
+// Word order is kept: "Lee Min" and "Min Lee" may be different people, only the fuzzy passenger search ignores it.
+func passengerKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// MergeDuplicates groups flights by DuplicateKey and merges every group into one canonical record.
+// A group holds at most one booking per source: two bookings of one provider are two reservations, so the n-th booking
+// of a source joins the n-th group of its key. Groups keep the order in which their first booking appears.
+func MergeDuplicates(flights domain.Flights, opts MergeOptions) []domain.MergedFlight {
+	var order []string
+	groups := make(map[string]domain.Flights)
+	seen := make(map[string]int)
+	var out []domain.MergedFlight
+
+	for i, f := range flights {
+		key := DuplicateKey(f)
+		if key == "" {
+			key = fmt.Sprintf("#%d", i)
+		} else {
+			n := seen[f.Source()+"|"+key]
+			seen[f.Source()+"|"+key] = n + 1
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], f)
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		providers := make([]domain.ProviderRef, len(group))
+		for i, f := range group {
+			providers[i] = domain.NewProviderRef(f.Source(), f.ID())
+		}
+		out = append(out, domain.NewMergedFlight(pickCanonical(group, opts), providers))
+	}
+	return out
+}
+
+// pickCanonical returns the flight of the group that wins according to the merge options.
+func pickCanonical(group domain.Flights, opts MergeOptions) domain.Flight {
+	best := group[0]
+	for _, f := range group[1:] {
+		if preferFlight(f, best, opts) {
+			best = f
+		}
+	}
+	return best
+}
+
+// preferFlight reports whether candidate should replace current as the canonical record.
+func preferFlight(candidate, current domain.Flight, opts MergeOptions) bool {
+	switch opts.Policy {
+	case PolicyMostRecent:
+		c, k := firstDeparture(candidate), firstDeparture(current)
+		if !c.Equal(k) {
+			return c.After(k)
+		}
+	case PolicyProviderPriority:
+		// handled by the priority tie-break below
+	default:
+		c, k := candidate.Total().Amount(), current.Total().Amount()
+		if c != k {
+			return c < k
+		}
+	}
+	return providerRank(candidate.Source(), opts.Priority) < providerRank(current.Source(), opts.Priority)
+}
+
+// providerRank returns the position of source in priority, or len(priority) for unlisted sources.
+func providerRank(source string, priority []string) int {
+	for i, p := range priority {
+		if strings.EqualFold(p, source) {
+			return i
+		}
+	}
+	return len(priority)
+}
+
+// MergeFlights retrieves all flights from repositories and merges cross-provider duplicates into canonical records.
+func MergeFlights(ctx context.Context, r *repo.Multi, opts MergeOptions) ([]domain.MergedFlight, error) {
+	flights, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return MergeDuplicates(flights, opts), nil
+}
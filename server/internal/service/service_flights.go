@@ -4,59 +4,24 @@ import (
 	"aggregator/internal/domain"
 	"aggregator/internal/repo"
 	"context"
-	"sort"
 	"time"
 )
 
 // SortByPrice retrieves a list of flights from repositories and sorts them in ascending order by their total price.
 func SortByPrice(ctx context.Context, r *repo.Multi) (domain.Flights, error) {
-	sortFlights, err := r.List(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	sort.Slice(sortFlights, func(i, j int) bool {
-		return sortFlights[i].Total().Amount() <
-			sortFlights[j].Total().Amount()
-	})
-	return sortFlights, nil
+	return SortFlights(ctx, r, SortSpec{{Key: SortPrice}})
 }
 
 // SortByTimeTravel retrieves and sorts flights by their total travel time in ascending order.
 // Returns the sorted flights or an error if it fails to retrieve or sort the flights.
 func SortByTimeTravel(ctx context.Context, r *repo.Multi) (domain.Flights, error) {
-	flights, err := r.List(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	sort.Slice(flights, func(i, j int) bool {
-		durationI := TotalTravelTime(flights[i])
-		durationJ := TotalTravelTime(flights[j])
-		return durationI < durationJ
-	})
-	return flights, nil
+	return SortFlights(ctx, r, SortSpec{{Key: SortDuration}})
 }
 
 // SortByDepartureDate retrieves flights and sorts them by the earliest departure date of their segments.
-// It returns the sorted list of flights or an error.
+// Flights without segments are placed last. It returns the sorted list of flights or an error.
 func SortByDepartureDate(ctx context.Context, r *repo.Multi) (domain.Flights, error) {
-	flights, err := r.List(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	sort.Slice(flights, func(i, j int) bool {
-		segmentsI := flights[i].Segments()
-		segmentsJ := flights[j].Segments()
-
-		if len(segmentsI) == 0 || len(segmentsJ) == 0 {
-			return false
-		}
-		return segmentsI[0].DepartTime().Before(segmentsJ[0].DepartTime())
-	})
-
-	return flights, nil
+	return SortFlights(ctx, r, SortSpec{{Key: SortDeparture}})
 }
 
 // TotalTravelTime calculates the total travel time of a flight by measuring the time difference between the first departure and last arrival.
@@ -70,3 +35,23 @@ func TotalTravelTime(f domain.Flight) time.Duration {
 	lastArrive := segs[len(segs)-1].ArriveTime()
 	return lastArrive.Sub(firstDepart)
 }
+
+// TotalLayover sums the time spent on the ground between consecutive segments of a flight.
+// Returns zero for direct flights and flights without segments.
+func TotalLayover(f domain.Flight) time.Duration {
+	segs := f.Segments()
+	var total time.Duration
+	for i := 1; i < len(segs); i++ {
+		total += segs[i].DepartTime().Sub(segs[i-1].ArriveTime())
+	}
+	return total
+}
+
+// firstDeparture returns the departure time of the first segment of a flight, or the zero time if it has no segments.
+func firstDeparture(f domain.Flight) time.Time {
+	segs := f.Segments()
+	if len(segs) == 0 {
+		return time.Time{}
+	}
+	return segs[0].DepartTime()
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"aggregator/internal/history"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FlightHistory is the recorded prices of a booking. Change is the latest price minus the first one, set when
+// both are known in the same currency: negative means the booking got cheaper.
+type FlightHistory struct {
+	ID      string          `json:"id"`
+	Entries []history.Entry `json:"entries"`
+	Change  *float64        `json:"change,omitempty"`
+}
+
+// RoutePoint is the lowest price of a route on one departure date, as of one catalogue refresh.
+// LowestPrice, ID and Source are unset when no booking of the date is offered or priced in a known currency.
+type RoutePoint struct {
+	RecordedAt  time.Time `json:"recordedAt"`
+	Version     uint64    `json:"version"`
+	Options     int       `json:"options"`
+	LowestPrice *float64  `json:"lowestPrice"`
+	ID          string    `json:"id,omitempty"`
+	Source      string    `json:"source,omitempty"`
+}
+
+// RouteHistoryDay is the evolution of the lowest price of a route for one departure date.
+type RouteHistoryDay struct {
+	Date   string       `json:"date"`
+	Points []RoutePoint `json:"points"`
+}
+
+// RouteHistory lists, by departure date, how the lowest price of a route evolved across catalogue refreshes.
+type RouteHistory struct {
+	Route    string            `json:"route"`
+	Currency string            `json:"currency"`
+	Days     []RouteHistoryDay `json:"days"`
+}
+
+// RouteHistoryQuery selects the history of a route, optionally for one departure date.
+type RouteHistoryQuery struct {
+	Route         string
+	Date          string
+	PriceCurrency string
+}
+
+// ParseRouteHistoryQuery reads a route written "CDG-HND" and the "date" (YYYY-MM-DD) and "priceCurrency" query params.
+func ParseRouteHistoryQuery(route string, values url.Values) (RouteHistoryQuery, error) {
+	from, to, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(route)), "-")
+	if !ok || !isCode(from, 3, false) || !isCode(to, 3, false) {
+		return RouteHistoryQuery{}, fmt.Errorf("%w: route %q must be written FROM-TO with IATA airport codes", ErrInvalidQuery, route)
+	}
+	q := RouteHistoryQuery{Route: from + "-" + to, Date: values.Get("date"), PriceCurrency: BaseCurrency}
+	if q.Date != "" {
+		if _, err := time.Parse(time.DateOnly, q.Date); err != nil {
+			return RouteHistoryQuery{}, fmt.Errorf("%w: date %q must be formatted as YYYY-MM-DD", ErrInvalidQuery, q.Date)
+		}
+	}
+	if s := strings.ToUpper(strings.TrimSpace(values.Get("priceCurrency"))); s != "" {
+		if !IsKnownCurrency(s) {
+			return RouteHistoryQuery{}, fmt.Errorf("%w: %w: %s", ErrInvalidQuery, ErrUnknownCurrency, s)
+		}
+		q.PriceCurrency = s
+	}
+	return q, nil
+}
+
+// BuildFlightHistory wraps the entries of a booking and computes its price change. When several providers use the id,
+// the change is the one of the provider seen last.
+func BuildFlightHistory(id string, entries []history.Entry) FlightHistory {
+	h := FlightHistory{ID: id, Entries: entries}
+	if h.Entries == nil {
+		h.Entries = []history.Entry{}
+	}
+	source := ""
+	for _, e := range entries {
+		if !e.Removed {
+			source = e.Source
+		}
+	}
+	var first, last *history.Entry
+	for i := range entries {
+		if entries[i].Removed || entries[i].Source != source {
+			continue
+		}
+		if first == nil {
+			first = &entries[i]
+		}
+		last = &entries[i]
+	}
+	if first != nil && first != last && first.Currency == last.Currency {
+		change := roundTo(last.Amount-first.Amount, 2)
+		h.Change = &change
+	}
+	return h
+}
+
+// BuildRouteHistory replays the entries of a route, in recording order, and returns for every departure date
+// (or only the date of the query, if any) the lowest price after each catalogue refresh that changed one of its bookings.
+func BuildRouteHistory(entries []history.Entry, q RouteHistoryQuery) RouteHistory {
+	h := RouteHistory{Route: q.Route, Currency: q.PriceCurrency, Days: []RouteHistoryDay{}}
+
+	// offered holds, per date, the latest entry of every booking still offered; dates the date of every booking,
+	// so that a booking moved to another day leaves its former one
+	offered := make(map[string]map[string]history.Entry)
+	dates := make(map[string]string)
+	points := make(map[string][]RoutePoint)
+	for i := 0; i < len(entries); {
+		// a refresh records its entries at the same instant; versions cannot tell refreshes apart as they restart at 1
+		// with the process
+		at := entries[i].RecordedAt
+		touched := make(map[string]bool)
+		for ; i < len(entries) && entries[i].RecordedAt.Equal(at); i++ {
+			e := entries[i]
+			key := e.Source + "|" + e.ID
+			if date, ok := dates[key]; ok && date != e.Date {
+				delete(offered[date], key)
+				touched[date] = true
+			}
+			if offered[e.Date] == nil {
+				offered[e.Date] = make(map[string]history.Entry)
+			}
+			if e.Removed {
+				delete(offered[e.Date], key)
+				delete(dates, key)
+			} else {
+				offered[e.Date][key] = e
+				dates[key] = e.Date
+			}
+			touched[e.Date] = true
+		}
+		for date := range touched {
+			points[date] = append(points[date], lowestPoint(offered[date], entries[i-1], q.PriceCurrency))
+		}
+	}
+
+	days := make([]string, 0, len(points))
+	for date := range points {
+		if q.Date == "" || date == q.Date {
+			days = append(days, date)
+		}
+	}
+	sort.Strings(days)
+	for _, date := range days {
+		h.Days = append(h.Days, RouteHistoryDay{Date: date, Points: points[date]})
+	}
+	return h
+}
+
+// lowestPoint returns the cheapest of the offered bookings, converted into the currency, as of the given entry.
+// Equal prices go to the lowest source then id, so that replays are deterministic.
+func lowestPoint(offered map[string]history.Entry, at history.Entry, currency string) RoutePoint {
+	p := RoutePoint{RecordedAt: at.RecordedAt, Version: at.Version, Options: len(offered)}
+	for _, e := range offered {
+		amount, err := ConvertAmount(e.Amount, e.Currency, currency)
+		if err != nil {
+			continue
+		}
+		price := roundTo(amount, 2)
+		if p.LowestPrice == nil || price < *p.LowestPrice ||
+			(price == *p.LowestPrice && e.Source+"|"+e.ID < p.Source+"|"+p.ID) {
+			p.LowestPrice, p.ID, p.Source = &price, e.ID, e.Source
+		}
+	}
+	return p
+}
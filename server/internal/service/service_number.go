@@ -0,0 +1,76 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"aggregator/internal/repo"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// FlightBookingsQuery selects the bookings on one flight number, optionally on one day.
+type FlightBookingsQuery struct {
+	Number string
+	// Date, as YYYY-MM-DD, is the local departure date of the flight at its departure airport; empty means any date.
+	Date string
+}
+
+// ParseFlightBookingsQuery reads the "all" and "date" query params of /flights/number/{number}.
+// It reports false when "all" is not set, in which case the endpoint returns a single booking.
+func ParseFlightBookingsQuery(number string, values url.Values) (FlightBookingsQuery, bool, error) {
+	q := FlightBookingsQuery{Number: number, Date: values.Get("date")}
+	all := false
+	if v := values.Get("all"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return FlightBookingsQuery{}, false, fmt.Errorf("%w: all must be true or false", ErrInvalidQuery)
+		}
+		all = b
+	}
+	if q.Date != "" {
+		if !all {
+			return FlightBookingsQuery{}, false, fmt.Errorf("%w: date requires all=true", ErrInvalidQuery)
+		}
+		if _, err := time.Parse(time.DateOnly, q.Date); err != nil {
+			return FlightBookingsQuery{}, false, fmt.Errorf("%w: date %q must be formatted as YYYY-MM-DD", ErrInvalidQuery, q.Date)
+		}
+	}
+	return q, all, nil
+}
+
+// Match reports whether a booking has a segment sold or operated under the flight number, departing on the date if any.
+func (q FlightBookingsQuery) Match(f domain.Flight) bool {
+	number := domain.NormalizeFlightNumber(q.Number)
+	for _, s := range f.Segments() {
+		if domain.NormalizeFlightNumber(s.FlightNumber()) != number &&
+			domain.NormalizeFlightNumber(s.OperatingFlightNumber()) != number {
+			continue
+		}
+		if q.Date == "" || s.DepartTime().In(reference.AirportLocation(s.Departure())).Format(time.DateOnly) == q.Date {
+			return true
+		}
+	}
+	return false
+}
+
+// FindFlightBookings retrieves every booking on a flight number from all repositories, scoped to the date if given.
+// Returns domain.ErrFlightsNotFound when there is none.
+func FindFlightBookings(ctx context.Context, r *repo.Multi, q FlightBookingsQuery) (domain.Flights, error) {
+	flights, err := r.FindAllByNumber(ctx, q.Number)
+	if err != nil {
+		return nil, err
+	}
+	var out domain.Flights
+	for _, f := range flights {
+		if q.Match(f) {
+			out = append(out, f)
+		}
+	}
+	if len(out) == 0 {
+		return nil, domain.ErrFlightsNotFound
+	}
+	return out, nil
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultPageLimit is the page size used when only a cursor is given.
+	DefaultPageLimit = 50
+	// MaxPageLimit caps the page size a client may request.
+	MaxPageLimit = 500
+)
+
+var (
+	// ErrInvalidCursor is returned when a cursor cannot be decoded or was issued for another query or ordering.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrCursorExpired is returned when a cursor was issued for another catalogue version than the one paginated.
+	ErrCursorExpired = errors.New("cursor expired")
+)
+
+// Ordering describes how a result list is ordered so that it can be paginated with keyset cursors.
+// Key must end with a tie-breaker making it unique per flight, and Compare must order keys consistently with the list.
+type Ordering struct {
+	Name    string
+	Key     func(domain.Flight) []any
+	Compare func(a, b []any) int
+}
+
+// DefaultOrdering orders flights by source then booking id; it is used by endpoints without an explicit sort.
+var DefaultOrdering = Ordering{
+	Name:    "default",
+	Key:     func(f domain.Flight) []any { return tieBreaker(f) },
+	Compare: compareKeys,
+}
+
+// ScoreOrdering orders ranked results by decreasing score, then source and booking id.
+// score must know every flight of the list being paginated.
+func ScoreOrdering(name string, score func(domain.Flight) float64) Ordering {
+	return Ordering{
+		Name: name,
+		Key: func(f domain.Flight) []any {
+			return append([]any{-score(f)}, tieBreaker(f)...)
+		},
+		Compare: compareKeys,
+	}
+}
+
+// tieBreaker returns the values identifying a flight across providers.
+func tieBreaker(f domain.Flight) []any {
+	return []any{f.Source(), f.ID()}
+}
+
+// Sort orders flights in place according to the ordering, keeping the relative order of equal keys.
+func (o Ordering) Sort(flights domain.Flights) {
+	type keyed struct {
+		key    []any
+		flight domain.Flight
+	}
+	items := make([]keyed, len(flights))
+	for i, f := range flights {
+		items[i] = keyed{key: o.Key(f), flight: f}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return o.Compare(items[i].key, items[j].key) < 0 })
+	for i, item := range items {
+		flights[i] = item.flight
+	}
+}
+
+// Cursor is the opaque position handed to clients between two pages.
+type Cursor struct {
+	// Version is the catalogue version the page was computed from.
+	Version uint64 `json:"v"`
+	// Ordering and Query identify the list the cursor belongs to.
+	Ordering string `json:"o"`
+	Query    string `json:"q"`
+	// Key is the sort key, tie-breaker included, of the boundary item.
+	Key []any `json:"k"`
+	// Backward is true for cursors pointing to the previous page.
+	Backward bool `json:"b,omitempty"`
+}
+
+// Encode serializes the cursor into an opaque URL-safe string.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor produced by Cursor.Encode.
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if len(c.Key) == 0 {
+		return Cursor{}, fmt.Errorf("%w: empty key", ErrInvalidCursor)
+	}
+	return c, nil
+}
+
+// PageRequest is the pagination part of a request.
+type PageRequest struct {
+	Limit  int
+	Cursor *Cursor
+	// Query fingerprints the other parameters, so that a cursor cannot be replayed against another search.
+	Query string
+}
+
+// IsPaginated reports whether the request asks for a page rather than the whole list.
+func IsPaginated(values url.Values) bool {
+	return values.Has("limit") || values.Has("cursor")
+}
+
+// ParsePageRequest reads "limit" and "cursor" from the query params of a request to path.
+func ParsePageRequest(path string, values url.Values) (PageRequest, error) {
+	req := PageRequest{Limit: DefaultPageLimit, Query: QueryFingerprint(path, values)}
+	if s := values.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > MaxPageLimit {
+			return PageRequest{}, fmt.Errorf("%w: limit must be an integer between 1 and %d", ErrInvalidQuery, MaxPageLimit)
+		}
+		req.Limit = n
+	}
+	if s := values.Get("cursor"); s != "" {
+		c, err := DecodeCursor(s)
+		if err != nil {
+			return PageRequest{}, err
+		}
+		if c.Query != req.Query {
+			return PageRequest{}, fmt.Errorf("%w: cursor was issued for another query", ErrInvalidCursor)
+		}
+		req.Cursor = &c
+	}
+	return req, nil
+}
+
+// QueryFingerprint hashes the path and the query params of a request, pagination params excluded.
+func QueryFingerprint(path string, values url.Values) string {
+	rest := url.Values{}
+	for k, v := range values {
+		if k != "limit" && k != "cursor" {
+			rest[k] = v
+		}
+	}
+	sum := sha256.Sum256([]byte(path + "?" + rest.Encode()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Page is one slice of an ordered result list.
+type Page struct {
+	Flights domain.Flights
+	Total   int
+	Next    *Cursor
+	Prev    *Cursor
+}
+
+// Paginate returns the page designated by the request. flights must already be sorted by the ordering and be the
+// flights of the catalogue version given; a cursor issued for another version is rejected with ErrCursorExpired,
+// since its pages would no longer be consistent.
+func Paginate(flights domain.Flights, o Ordering, req PageRequest, version uint64) (Page, error) {
+	page := Page{Total: len(flights)}
+	start, end := 0, len(flights)
+
+	if c := req.Cursor; c != nil {
+		if c.Ordering != o.Name {
+			return Page{}, fmt.Errorf("%w: cursor was issued for the %q ordering", ErrInvalidCursor, c.Ordering)
+		}
+		if c.Version != version {
+			return Page{}, fmt.Errorf("%w: cursor was issued for catalogue version %d, not %d", ErrCursorExpired, c.Version, version)
+		}
+		if c.Backward {
+			end = sort.Search(len(flights), func(i int) bool { return o.Compare(o.Key(flights[i]), c.Key) >= 0 })
+			start = max(0, end-req.Limit)
+		} else {
+			start = sort.Search(len(flights), func(i int) bool { return o.Compare(o.Key(flights[i]), c.Key) > 0 })
+		}
+	}
+	if !(req.Cursor != nil && req.Cursor.Backward) {
+		end = min(len(flights), start+req.Limit)
+	}
+
+	page.Flights = flights[start:end]
+	if end < len(flights) && end > 0 {
+		page.Next = &Cursor{Version: version, Ordering: o.Name, Query: req.Query, Key: o.Key(flights[end-1])}
+	}
+	if start > 0 && start < len(flights) {
+		page.Prev = &Cursor{Version: version, Ordering: o.Name, Query: req.Query, Key: o.Key(flights[start]), Backward: true}
+	}
+	return page, nil
+}
+
+// compareKeys compares two keys value by value. Numbers compare numerically, anything else as strings.
+func compareKeys(a, b []any) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// compareValues compares two key values; values decoded from JSON cursors are float64 or string.
+func compareValues(a, b any) int {
+	fa, aNum := toFloat(a)
+	fb, bNum := toFloat(b)
+	switch {
+	case aNum && bNum:
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case aNum != bNum:
+		// numbers sort before strings so that mixed keys still have a total order
+		if aNum {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// toFloat converts the numeric key values used by orderings into a float64.
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/textnorm"
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMinScore is the lowest score a fuzzy passenger match may have when the client does not choose one.
+const DefaultMinScore = 0.75
+
+// PassengerSearch describes a search by passenger name. Names always match regardless of case, diacritics, spacing
+// and word order; with Fuzzy, names within a few typos also match and are scored.
+type PassengerSearch struct {
+	Name     string
+	Fuzzy    bool
+	MinScore float64
+}
+
+// ParsePassengerSearch builds a PassengerSearch for name from the "fuzzy" and "minScore" query params.
+func ParsePassengerSearch(name string, values url.Values) (PassengerSearch, error) {
+	s := PassengerSearch{Name: name, MinScore: DefaultMinScore}
+	if textnorm.NameKey(name) == "" {
+		return PassengerSearch{}, fmt.Errorf("%w: passenger name is empty", ErrInvalidQuery)
+	}
+	if v := values.Get("fuzzy"); v != "" {
+		fuzzy, err := strconv.ParseBool(v)
+		if err != nil {
+			return PassengerSearch{}, fmt.Errorf("%w: fuzzy must be true or false", ErrInvalidQuery)
+		}
+		s.Fuzzy = fuzzy
+	}
+	if v := values.Get("minScore"); v != "" {
+		if !s.Fuzzy {
+			return PassengerSearch{}, fmt.Errorf("%w: minScore requires fuzzy=true", ErrInvalidQuery)
+		}
+		score, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(score) || score <= 0 || score > 1 {
+			return PassengerSearch{}, fmt.Errorf("%w: minScore must be a number in (0, 1]", ErrInvalidQuery)
+		}
+		s.MinScore = score
+	}
+	return s, nil
+}
+
+// PassengerScore rates how closely name matches the searched query, from 0 to 1. Names equal once normalized score 1.
+// Otherwise the best of two measures is kept: the similarity of the whole normalized names, which tolerates words
+// glued or split differently, and the similarity of their words paired in any order, where each word of the query
+// counts its closest word in the name and words left unpaired on either side count 0.
+func PassengerScore(query, name string) float64 {
+	qKey, nKey := textnorm.NameKey(query), textnorm.NameKey(name)
+	if qKey == "" || nKey == "" {
+		return 0
+	}
+	if qKey == nKey {
+		return 1
+	}
+
+	qTokens, nTokens := strings.Fields(qKey), strings.Fields(nKey)
+	var sum float64
+	for _, q := range qTokens {
+		best := 0.0
+		for _, n := range nTokens {
+			best = max(best, textnorm.Similarity(q, n))
+		}
+		sum += best
+	}
+	tokens := sum / float64(max(len(qTokens), len(nTokens)))
+
+	score := max(textnorm.Similarity(qKey, nKey), tokens)
+	return math.Round(score*1000) / 1000
+}
+
+// PassengerMatch is a flight found by a passenger search with the score of its passenger name.
+type PassengerMatch struct {
+	Flight domain.Flight
+	Score  float64
+}
+
+// PassengerMatchSnapshot is the JSON representation of a PassengerMatch.
+type PassengerMatchSnapshot struct {
+	domain.FlightSnapshot
+	Score float64 `json:"score"`
+}
+
+// Snapshot returns the JSON representation of the match.
+func (m PassengerMatch) Snapshot() PassengerMatchSnapshot {
+	return PassengerMatchSnapshot{FlightSnapshot: m.Flight.Snapshot(), Score: m.Score}
+}
+
+// PassengerMatches is a list of matches ranked by decreasing score.
+type PassengerMatches []PassengerMatch
+
+// Flights returns the matched flights, in rank order.
+func (ms PassengerMatches) Flights() domain.Flights {
+	flights := make(domain.Flights, len(ms))
+	for i, m := range ms {
+		flights[i] = m.Flight
+	}
+	return flights
+}
+
+// Ordering returns the keyset ordering of the ranking: decreasing score, then source and booking id.
+// It only knows the flights of the matches.
+func (ms PassengerMatches) Ordering() Ordering {
+	scores := ms.scores()
+	return ScoreOrdering("score", func(f domain.Flight) float64 { return scores[matchKey(f)] })
+}
+
+// Snapshot returns the JSON representation of the matches of the given flights, in their order.
+func (ms PassengerMatches) Snapshot(flights domain.Flights) []PassengerMatchSnapshot {
+	scores := ms.scores()
+	snapshots := make([]PassengerMatchSnapshot, len(flights))
+	for i, f := range flights {
+		snapshots[i] = PassengerMatch{Flight: f, Score: scores[matchKey(f)]}.Snapshot()
+	}
+	return snapshots
+}
+
+// SearchPassengers retrieves the flights whose passenger matches the search, ranked by decreasing score.
+// Exact searches are answered by the repositories and every match scores 1; fuzzy searches score every flight.
+// Returns domain.ErrFlightsNotFound when nothing matches.
+func SearchPassengers(ctx context.Context, r *repo.Multi, s PassengerSearch) (PassengerMatches, error) {
+	var matches PassengerMatches
+	if !s.Fuzzy {
+		flights, err := r.FindByPassenger(ctx, s.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range flights {
+			matches = append(matches, PassengerMatch{Flight: f, Score: 1})
+		}
+	} else {
+		flights, err := r.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range flights {
+			if score := PassengerScore(s.Name, f.PassengerName()); score >= s.MinScore {
+				matches = append(matches, PassengerMatch{Flight: f, Score: score})
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return nil, domain.ErrFlightsNotFound
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return compareKeys(tieBreaker(a.Flight), tieBreaker(b.Flight)) < 0
+	})
+	return matches, nil
+}
+
+// scores indexes the score of every match by matchKey.
+func (ms PassengerMatches) scores() map[[2]string]float64 {
+	scores := make(map[[2]string]float64, len(ms))
+	for _, m := range ms {
+		scores[matchKey(m.Flight)] = m.Score
+	}
+	return scores
+}
+
+// matchKey identifies a flight across providers.
+func matchKey(f domain.Flight) [2]string {
+	return [2]string{f.Source(), f.ID()}
+}
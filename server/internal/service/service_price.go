@@ -0,0 +1,160 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// priceEpsilon absorbs rounding noise when prices are compared after a currency conversion.
+const priceEpsilon = 0.005
+
+// PriceRange selects flights whose total lies between Min and Max, both inclusive and optional.
+// Totals are converted into Currency, BaseCurrency when empty, before comparison and flights in unknown currencies
+// are skipped.
+type PriceRange struct {
+	Min      *float64
+	Max      *float64
+	Currency string
+}
+
+// ParsePrice parses a price given by a client, rejecting non-numeric, non-finite and negative values.
+func ParsePrice(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%w: price %q is not a number", ErrInvalidQuery, s)
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("%w: price must not be negative", ErrInvalidQuery)
+	}
+	return v, nil
+}
+
+// ParsePriceRange builds a PriceRange from the "min", "max" and "currency" query params; at least one bound is required.
+func ParsePriceRange(values url.Values) (PriceRange, error) {
+	var pr PriceRange
+	for _, bound := range []struct {
+		name string
+		dst  **float64
+	}{{"min", &pr.Min}, {"max", &pr.Max}} {
+		s := values.Get(bound.name)
+		if s == "" {
+			continue
+		}
+		v, err := ParsePrice(s)
+		if err != nil {
+			return PriceRange{}, fmt.Errorf("%s: %w", bound.name, err)
+		}
+		*bound.dst = &v
+	}
+	if pr.Min == nil && pr.Max == nil {
+		return PriceRange{}, fmt.Errorf("%w: min or max is required", ErrInvalidQuery)
+	}
+	pr.Currency = strings.ToUpper(strings.TrimSpace(values.Get("currency")))
+	if pr.Currency == "" {
+		pr.Currency = BaseCurrency
+	}
+	if err := pr.Validate(); err != nil {
+		return PriceRange{}, err
+	}
+	return pr, nil
+}
+
+// AroundPrice builds the range "price ± tolerance%". A zero tolerance selects the exact price.
+func AroundPrice(price, tolerancePct float64, currency string) (PriceRange, error) {
+	if math.IsNaN(tolerancePct) || tolerancePct < 0 || tolerancePct > 100 {
+		return PriceRange{}, fmt.Errorf("%w: tolerance must be between 0 and 100", ErrInvalidQuery)
+	}
+	delta := price * tolerancePct / 100
+	lo, hi := price-delta, price+delta
+	pr := PriceRange{Min: &lo, Max: &hi, Currency: strings.ToUpper(strings.TrimSpace(currency))}
+	if pr.Currency == "" {
+		pr.Currency = BaseCurrency
+	}
+	if err := pr.Validate(); err != nil {
+		return PriceRange{}, err
+	}
+	return pr, nil
+}
+
+// Validate checks that the bounds are ordered and the currency can be converted.
+func (p PriceRange) Validate() error {
+	if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+		return fmt.Errorf("%w: min is greater than max", ErrInvalidQuery)
+	}
+	if p.Currency != "" && !IsKnownCurrency(p.Currency) {
+		return fmt.Errorf("%w: %w: %s", ErrInvalidQuery, ErrUnknownCurrency, p.Currency)
+	}
+	return nil
+}
+
+// Match reports whether the total of a flight, converted into the currency of the range, lies within the range.
+func (p PriceRange) Match(f domain.Flight) bool {
+	currency := p.Currency
+	if currency == "" {
+		currency = BaseCurrency
+	}
+	amount, err := NormalizedAmount(f, currency)
+	if err != nil {
+		return false
+	}
+	if p.Min != nil && amount < *p.Min-priceEpsilon {
+		return false
+	}
+	if p.Max != nil && amount > *p.Max+priceEpsilon {
+		return false
+	}
+	return true
+}
+
+// FindByPriceRange returns the flights of all repositories whose total, converted into the currency of the range,
+// lies within it, by currency. The range is converted into every known currency and looked up in the price indexes
+// of the repositories, bookings in other currencies never matching. Returns domain.ErrFlightsNotFound when none match.
+func FindByPriceRange(ctx context.Context, r *repo.Multi, p PriceRange) (domain.Flights, error) {
+	from := p.Currency
+	if from == "" {
+		from = BaseCurrency
+	}
+	lo, hi := 0.0, math.Inf(1)
+	if p.Min != nil {
+		lo = *p.Min - priceEpsilon
+	}
+	if p.Max != nil {
+		hi = *p.Max + priceEpsilon
+	}
+
+	var out domain.Flights
+	for _, currency := range knownCurrencies() {
+		min, err := ConvertAmount(lo, from, currency)
+		if err != nil {
+			return nil, err
+		}
+		max, err := ConvertAmount(hi, from, currency)
+		if err != nil {
+			return nil, err
+		}
+		flights, err := r.FindByPriceRange(ctx, currency, min, max)
+		if errors.Is(err, domain.ErrFlightsNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// the widened bounds may let in an amount that only rounding separates from the range
+		for _, f := range flights {
+			if p.Match(f) {
+				out = append(out, f)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, domain.ErrFlightsNotFound
+	}
+	return out, nil
+}
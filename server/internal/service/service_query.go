@@ -0,0 +1,351 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"aggregator/internal/repo"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery is returned when a filter parameter cannot be parsed or the filters contradict each other.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Query holds the composable filters accepted by the search endpoints.
+// Zero values mean "no filter"; optional numeric filters are pointers so that 0 stays a valid bound.
+type Query struct {
+	MinPrice *float64
+	MaxPrice *float64
+	// Currency is the currency of the price bounds, BaseCurrency when empty: totals are converted into it before
+	// comparison, like in PriceRange, and bookings in a currency missing from the rate table never match the bounds.
+	Currency string
+	From     string
+	To       string
+	Depart   TimeWindow
+	Arrive   TimeWindow
+	TimeZone TimeZoneMode
+	Carrier  string
+	Alliance string
+	// Cabin requires every segment to be booked in that cabin; BookingClass and FareBasis need one segment matching both.
+	Cabin        domain.Cabin
+	BookingClass string
+	FareBasis    string
+	// WithCheckedBag keeps the bookings that include a checked bag or sell one at a known fee;
+	// the price bounds then apply to the total plus that fee.
+	WithCheckedBag bool
+	Status         string
+	MaxStops       *int
+	MaxDuration    time.Duration
+	Source         string
+}
+
+// ParseQuery builds a validated Query from URL query parameters: minPrice, maxPrice and currency; from and to;
+// departAfter, departBefore, departDate, arriveAfter, arriveBefore, arriveDate and tz; carrier and alliance; cabin,
+// bookingClass and fareBasis; withCheckedBag; status, maxStops, maxDuration and source. Unknown parameters, such as
+// those of sorting and pagination, are ignored.
+func ParseQuery(values url.Values) (Query, error) {
+	var q Query
+	var err error
+
+	if q.MinPrice, err = parseOptionalFloat(values, "minPrice"); err != nil {
+		return Query{}, err
+	}
+	if q.MaxPrice, err = parseOptionalFloat(values, "maxPrice"); err != nil {
+		return Query{}, err
+	}
+	if q.Depart, err = parseTimeWindow(values, "depart"); err != nil {
+		return Query{}, err
+	}
+	if q.Arrive, err = parseTimeWindow(values, "arrive"); err != nil {
+		return Query{}, err
+	}
+	if q.TimeZone, err = parseTimeZoneMode(values); err != nil {
+		return Query{}, err
+	}
+	if err := q.Depart.check("depart", q.TimeZone); err != nil {
+		return Query{}, err
+	}
+	if err := q.Arrive.check("arrive", q.TimeZone); err != nil {
+		return Query{}, err
+	}
+	if s := values.Get("maxStops"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return Query{}, fmt.Errorf("%w: maxStops %q is not an integer", ErrInvalidQuery, s)
+		}
+		q.MaxStops = &n
+	}
+	if s := values.Get("maxDuration"); s != "" {
+		if q.MaxDuration, err = parseDuration(s); err != nil {
+			return Query{}, fmt.Errorf("%w: maxDuration %q: %v", ErrInvalidQuery, s, err)
+		}
+	}
+
+	q.Currency = strings.ToUpper(strings.TrimSpace(values.Get("currency")))
+	q.From = strings.ToUpper(strings.TrimSpace(values.Get("from")))
+	q.To = strings.ToUpper(strings.TrimSpace(values.Get("to")))
+	if q.TimeZone == TimeZoneLocal {
+		// local bounds at an airport without a known time zone could only be guessed
+		for _, a := range []struct {
+			name, code string
+			window     TimeWindow
+		}{{"from", q.From, q.Depart}, {"to", q.To, q.Arrive}} {
+			if _, ok := reference.LookupLocation(a.code); a.code != "" && a.window.HasFloating() && !ok {
+				return Query{}, fmt.Errorf("%w: %s airport %q has no known time zone for tz=local", ErrInvalidQuery, a.name, a.code)
+			}
+		}
+	}
+	q.Carrier = strings.ToUpper(strings.TrimSpace(values.Get("carrier")))
+	if a, ok := reference.LookupAirline(q.Carrier); ok {
+		// ICAO codes are accepted and matched as the IATA code of the airline
+		q.Carrier = a.Code
+	}
+	if s := values.Get("alliance"); s != "" {
+		alliance, ok := reference.ParseAlliance(s)
+		if !ok {
+			return Query{}, fmt.Errorf("%w: unknown alliance %q", ErrInvalidQuery, s)
+		}
+		q.Alliance = alliance
+	}
+	if s := values.Get("cabin"); s != "" {
+		cabin, ok := domain.ParseCabin(s)
+		if !ok {
+			return Query{}, fmt.Errorf("%w: unknown cabin %q", ErrInvalidQuery, s)
+		}
+		q.Cabin = cabin
+	}
+	if s := values.Get("withCheckedBag"); s != "" {
+		if q.WithCheckedBag, err = strconv.ParseBool(s); err != nil {
+			return Query{}, fmt.Errorf("%w: withCheckedBag must be true or false", ErrInvalidQuery)
+		}
+	}
+	q.BookingClass = strings.ToUpper(strings.TrimSpace(values.Get("bookingClass")))
+	q.FareBasis = strings.ToUpper(strings.TrimSpace(values.Get("fareBasis")))
+	q.Status = strings.ToLower(strings.TrimSpace(values.Get("status")))
+	q.Source = strings.TrimSpace(values.Get("source"))
+
+	if err := q.Validate(); err != nil {
+		return Query{}, err
+	}
+	return q, nil
+}
+
+// Validate checks the bounds and codes of the query and returns an ErrInvalidQuery error describing the first problem.
+func (q Query) Validate() error {
+	if q.MinPrice != nil && *q.MinPrice < 0 {
+		return fmt.Errorf("%w: minPrice must not be negative", ErrInvalidQuery)
+	}
+	if q.MaxPrice != nil && *q.MaxPrice < 0 {
+		return fmt.Errorf("%w: maxPrice must not be negative", ErrInvalidQuery)
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		return fmt.Errorf("%w: minPrice is greater than maxPrice", ErrInvalidQuery)
+	}
+	if q.MaxStops != nil && *q.MaxStops < 0 {
+		return fmt.Errorf("%w: maxStops must not be negative", ErrInvalidQuery)
+	}
+	if q.MaxDuration < 0 {
+		return fmt.Errorf("%w: maxDuration must not be negative", ErrInvalidQuery)
+	}
+	if q.Currency != "" && !isCode(q.Currency, 3, false) {
+		return fmt.Errorf("%w: currency %q is not an ISO 4217 code", ErrInvalidQuery, q.Currency)
+	}
+	if q.Currency != "" && !IsKnownCurrency(q.Currency) {
+		return fmt.Errorf("%w: %w: %s", ErrInvalidQuery, ErrUnknownCurrency, q.Currency)
+	}
+	if q.From != "" && !isCode(q.From, 3, false) {
+		return fmt.Errorf("%w: from %q is not an IATA airport code", ErrInvalidQuery, q.From)
+	}
+	if q.To != "" && !isCode(q.To, 3, false) {
+		return fmt.Errorf("%w: to %q is not an IATA airport code", ErrInvalidQuery, q.To)
+	}
+	if q.Carrier != "" && !isCode(q.Carrier, 2, true) {
+		return fmt.Errorf("%w: carrier %q is neither an IATA airline code nor a known ICAO one", ErrInvalidQuery, q.Carrier)
+	}
+	return nil
+}
+
+// Match reports whether a flight satisfies every filter of the query.
+func (q Query) Match(f domain.Flight) bool {
+	segs := f.Segments()
+
+	if !q.matchPrice(f) {
+		return false
+	}
+	if q.Status != "" && !strings.EqualFold(f.Status(), q.Status) {
+		return false
+	}
+	if q.Source != "" && !strings.EqualFold(f.Source(), q.Source) {
+		return false
+	}
+	if q.MaxStops != nil && len(segs)-1 > *q.MaxStops {
+		return false
+	}
+	if q.MaxDuration > 0 && TotalTravelTime(f) > q.MaxDuration {
+		return false
+	}
+
+	if q.From != "" || q.To != "" || !q.Depart.IsZero() || !q.Arrive.IsZero() || q.Carrier != "" || q.Alliance != "" ||
+		q.Cabin != "" || q.BookingClass != "" || q.FareBasis != "" {
+		if len(segs) == 0 {
+			return false
+		}
+	}
+	if q.From != "" && !strings.EqualFold(segs[0].Departure(), q.From) {
+		return false
+	}
+	if q.To != "" && !strings.EqualFold(segs[len(segs)-1].Arrival(), q.To) {
+		return false
+	}
+	if !q.Depart.IsZero() && !q.inWindow(q.Depart, segs[0].DepartTime(), segs[0].Departure()) {
+		return false
+	}
+	if !q.Arrive.IsZero() {
+		last := segs[len(segs)-1]
+		if !q.inWindow(q.Arrive, last.ArriveTime(), last.Arrival()) {
+			return false
+		}
+	}
+	if q.Carrier != "" && !hasCarrier(segs, q.Carrier) {
+		return false
+	}
+	if q.Alliance != "" && !hasAlliance(segs, q.Alliance) {
+		return false
+	}
+	if !q.matchFare(segs) {
+		return false
+	}
+	return true
+}
+
+// matchPrice reports whether the total of a flight, plus a checked bag with WithCheckedBag, lies within the price
+// bounds once converted into the currency of the query.
+func (q Query) matchPrice(f domain.Flight) bool {
+	currency := q.Currency
+	if currency == "" {
+		currency = BaseCurrency
+	}
+	var price float64
+	if q.WithCheckedBag {
+		var ok bool
+		if price, ok = PriceWithCheckedBag(f, currency); !ok {
+			return false
+		}
+	} else if q.MinPrice != nil || q.MaxPrice != nil {
+		var err error
+		if price, err = NormalizedAmount(f, currency); err != nil {
+			return false
+		}
+	}
+	if q.MinPrice != nil && price < *q.MinPrice-priceEpsilon {
+		return false
+	}
+	if q.MaxPrice != nil && price > *q.MaxPrice+priceEpsilon {
+		return false
+	}
+	return true
+}
+
+// Apply returns the flights matching the query, keeping their order.
+func (q Query) Apply(flights domain.Flights) domain.Flights {
+	out := make(domain.Flights, 0, len(flights))
+	for _, f := range flights {
+		if q.Match(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// FilterFlights returns the flights of every repository that match the query.
+func FilterFlights(ctx context.Context, r *repo.Multi, q Query) (domain.Flights, error) {
+	return r.Filter(ctx, q.Match)
+}
+
+// inWindow reports whether an instant at an airport falls into the window. In local mode, floating bounds are read in
+// the time zone of the airport, and flights at airports without a known time zone never match them.
+func (q Query) inWindow(w TimeWindow, t time.Time, airport string) bool {
+	loc := time.UTC
+	if q.TimeZone == TimeZoneLocal && w.HasFloating() {
+		var ok bool
+		if loc, ok = reference.LookupLocation(airport); !ok {
+			return false
+		}
+	}
+	return w.Contains(t, loc)
+}
+
+// hasCarrier reports whether one of the segments is marketed or operated by the given airline code.
+func hasCarrier(segs []domain.Segment, carrier string) bool {
+	for _, s := range segs {
+		if s.Carrier() == carrier || s.OperatingCarrier() == carrier {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOptionalFloat parses a finite float query parameter, returning nil when the parameter is absent.
+func parseOptionalFloat(values url.Values, name string) (*float64, error) {
+	s := values.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("%w: %s %q is not a number", ErrInvalidQuery, name, s)
+	}
+	return &v, nil
+}
+
+// parseDuration accepts Go durations ("13h30m") or a plain number of minutes ("810").
+func parseDuration(s string) (time.Duration, error) {
+	if minutes, err := strconv.Atoi(s); err == nil {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// isCode reports whether s is made of exactly n upper-case letters, or letters and digits when digits is true.
+func isCode(s string, n int, digits bool) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'A' || c > 'Z') && (!digits || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAlliance reports whether one of the segments is marketed by a member of the given alliance.
+func hasAlliance(segs []domain.Segment, alliance string) bool {
+	for _, s := range segs {
+		if a, ok := reference.LookupAirline(s.Carrier()); ok && a.Alliance == alliance {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFare reports whether the segments satisfy the cabin, booking class and fare basis filters: every segment is in
+// the cabin, and one segment has both the booking class and the fare basis, since a fare basis belongs to the booking
+// class of its segment. Segments whose cabin is unknown never satisfy a cabin filter.
+func (q Query) matchFare(segs []domain.Segment) bool {
+	fare := q.BookingClass == "" && q.FareBasis == ""
+	for _, s := range segs {
+		if q.Cabin != "" && s.Cabin() != q.Cabin {
+			return false
+		}
+		fare = fare || ((q.BookingClass == "" || s.BookingClass() == q.BookingClass) &&
+			(q.FareBasis == "" || s.FareBasis() == q.FareBasis))
+	}
+	return fare
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourcedValue is the value one provider holds for a compared field.
+type SourcedValue struct {
+	Source string `json:"source"`
+	ID     string `json:"id"`
+	Value  string `json:"value"`
+}
+
+// FieldMismatch describes a field on which providers disagree for the same passenger and flight number.
+type FieldMismatch struct {
+	PassengerName string         `json:"passengerName"`
+	FlightNumber  string         `json:"flightNumber"`
+	Field         string         `json:"field"`
+	Values        []SourcedValue `json:"values"`
+}
+
+// ReconciliationReport lists every disagreement found between providers.
+type ReconciliationReport struct {
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Bookings    int             `json:"bookings"`
+	Compared    int             `json:"compared"`
+	Mismatches  []FieldMismatch `json:"mismatches"`
+}
+
+// reconciliationKey groups bookings of the same passenger on the same physical flights, like DuplicateKey,
+// so that the same flight number flown on another day is another booking rather than a mismatch.
+func reconciliationKey(f domain.Flight) string {
+	return DuplicateKey(f)
+}
+
+// flightNumbers joins the normalized operating flight numbers of all segments of a flight, so that bookings sold
+// under different codeshare numbers of the same physical flights get the same value.
+func flightNumbers(f domain.Flight) string {
+	segs := f.Segments()
+	numbers := make([]string, len(segs))
+	for i, s := range segs {
+		numbers[i] = domain.NormalizeFlightNumber(s.OperatingFlightNumber())
+	}
+	return strings.Join(numbers, "-")
+}
+
+// comparedFields flattens the fields of a flight that are compared between providers, in a stable order.
+func comparedFields(f domain.Flight) ([]string, map[string]string) {
+	values := map[string]string{
+		"status":         f.Status(),
+		"total.amount":   strconv.FormatFloat(f.Total().Amount(), 'f', 2, 64),
+		"total.currency": f.Total().Currency(),
+		"segments.count": strconv.Itoa(len(f.Segments())),
+	}
+	names := []string{"status", "total.amount", "total.currency", "segments.count"}
+	for i, s := range f.Segments() {
+		prefix := fmt.Sprintf("segments[%d].", i)
+		for _, field := range []struct{ name, value string }{
+			{"from", s.Departure()},
+			{"to", s.Arrival()},
+			{"depart", s.DepartTime().UTC().Format(time.RFC3339)},
+			{"arrive", s.ArriveTime().UTC().Format(time.RFC3339)},
+		} {
+			names = append(names, prefix+field.name)
+			values[prefix+field.name] = field.value
+		}
+	}
+	return names, values
+}
+
+// Reconcile compares the bookings that different providers hold for the same passenger and flight number
+// and reports every field whose values differ.
+func Reconcile(flights domain.Flights) ReconciliationReport {
+	report := ReconciliationReport{GeneratedAt: time.Now().UTC(), Bookings: len(flights)}
+
+	var order []string
+	groups := make(map[string]domain.Flights)
+	for _, f := range flights {
+		key := reconciliationKey(f)
+		if key == "" {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], f)
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		if !spansSources(group) {
+			continue
+		}
+		report.Compared++
+
+		var names []string
+		seen := make(map[string]bool)
+		values := make([]map[string]string, len(group))
+		for i, f := range group {
+			fieldNames, fieldValues := comparedFields(f)
+			values[i] = fieldValues
+			for _, n := range fieldNames {
+				if !seen[n] {
+					seen[n] = true
+					names = append(names, n)
+				}
+			}
+		}
+
+		for _, name := range names {
+			if allEqual(values, name) {
+				continue
+			}
+			mismatch := FieldMismatch{
+				PassengerName: group[0].PassengerName(),
+				FlightNumber:  flightNumbers(group[0]),
+				Field:         name,
+			}
+			for i, f := range group {
+				mismatch.Values = append(mismatch.Values, SourcedValue{Source: f.Source(), ID: f.ID(), Value: values[i][name]})
+			}
+			report.Mismatches = append(report.Mismatches, mismatch)
+		}
+	}
+	return report
+}
+
+// spansSources reports whether the bookings of a group come from more than one provider.
+func spansSources(group domain.Flights) bool {
+	for _, f := range group[1:] {
+		if f.Source() != group[0].Source() {
+			return true
+		}
+	}
+	return false
+}
+
+// allEqual reports whether every booking of a group holds the same value for the field.
+func allEqual(values []map[string]string, field string) bool {
+	first, ok := values[0][field]
+	for _, v := range values[1:] {
+		value, has := v[field]
+		if has != ok || value != first {
+			return false
+		}
+	}
+	return true
+}
+
+// ReconcileFlights retrieves all flights from repositories and reports where providers disagree.
+func ReconcileFlights(ctx context.Context, r *repo.Multi) (ReconciliationReport, error) {
+	flights, err := r.List(ctx)
+	if err != nil {
+		return ReconciliationReport{}, err
+	}
+	return Reconcile(flights), nil
+}
+
+// WriteCSV writes the mismatches of the report as CSV, one row per provider value.
+func (r ReconciliationReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"passengerName", "flightNumber", "field", "source", "id", "value"}); err != nil {
+		return err
+	}
+	for _, m := range r.Mismatches {
+		for _, v := range m.Values {
+			if err := cw.Write([]string{m.PassengerName, m.FlightNumber, m.Field, v.Source, v.ID, v.Value}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"aggregator/internal/schedule"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Connection statuses, from the least to the most severe.
+const (
+	ConnectionOK     = "ok"
+	ConnectionShort  = "under_minimum"
+	ConnectionBroken = "broken"
+)
+
+// Severities of a schedule change: retimed when every connection still holds, otherwise the worst connection status.
+const (
+	SeverityRetimed      = "retimed"
+	SeverityUnderMinimum = ConnectionShort
+	SeverityBroken       = ConnectionBroken
+)
+
+// severityRank orders severities, the most severe last.
+var severityRank = map[string]int{SeverityRetimed: 0, SeverityUnderMinimum: 1, SeverityBroken: 2}
+
+// SegmentChange is a segment flown at other times than originally. Delays are negative when the segment is earlier.
+type SegmentChange struct {
+	Index              int       `json:"index"`
+	FlightNumber       string    `json:"flightNumber"`
+	From               string    `json:"from"`
+	To                 string    `json:"to"`
+	OldDepart          time.Time `json:"oldDepart"`
+	NewDepart          time.Time `json:"newDepart"`
+	OldArrive          time.Time `json:"oldArrive"`
+	NewArrive          time.Time `json:"newArrive"`
+	DepartDelayMinutes int       `json:"departDelayMinutes"`
+	ArriveDelayMinutes int       `json:"arriveDelayMinutes"`
+}
+
+// ConnectionImpact is the connection between two consecutive segments, before and after the change, against the
+// minimum connection time of the airport.
+type ConnectionImpact struct {
+	Airport        string `json:"airport"`
+	Inbound        string `json:"inbound"`
+	Outbound       string `json:"outbound"`
+	OldMinutes     int    `json:"oldMinutes"`
+	NewMinutes     int    `json:"newMinutes"`
+	MinimumMinutes int    `json:"minimumMinutes"`
+	Status         string `json:"status"`
+}
+
+// ScheduleImpact is the assessment of a retimed booking: its changed segments, every connection and the delay of its
+// final arrival.
+type ScheduleImpact struct {
+	Source              string             `json:"source"`
+	ID                  string             `json:"id"`
+	PassengerName       string             `json:"passengerName"`
+	Status              string             `json:"status"`
+	Route               string             `json:"route"`
+	Severity            string             `json:"severity"`
+	ArrivalDelayMinutes int                `json:"arrivalDelayMinutes"`
+	MaxDelayMinutes     int                `json:"maxDelayMinutes"`
+	DetectedAt          time.Time          `json:"detectedAt"`
+	UpdatedAt           time.Time          `json:"updatedAt"`
+	Version             uint64             `json:"version"`
+	Segments            []SegmentChange    `json:"segments"`
+	Connections         []ConnectionImpact `json:"connections"`
+}
+
+// AffectedPassenger lists the retimed bookings of one passenger and the worst severity among them.
+type AffectedPassenger struct {
+	PassengerName string   `json:"passengerName"`
+	Severity      string   `json:"severity"`
+	Bookings      []string `json:"bookings"`
+}
+
+// ScheduleReport lists the bookings flying at other times than originally, the most severe first.
+type ScheduleReport struct {
+	GeneratedAt time.Time           `json:"generatedAt"`
+	Bookings    int                 `json:"bookings"`
+	Passengers  []AffectedPassenger `json:"passengers"`
+	Changes     []ScheduleImpact    `json:"changes"`
+}
+
+// ScheduleQuery filters the schedule change report.
+type ScheduleQuery struct {
+	// Severity keeps the changes at least this severe; every change when empty.
+	Severity string
+	// MinDelay keeps the changes moving a segment by at least this many minutes, earlier or later.
+	MinDelay int
+}
+
+// ParseScheduleQuery reads the "severity" (retimed, under_minimum or broken) and "minDelay" (minutes) query params.
+func ParseScheduleQuery(values url.Values) (ScheduleQuery, error) {
+	var q ScheduleQuery
+	q.Severity = strings.ToLower(strings.TrimSpace(values.Get("severity")))
+	if _, ok := severityRank[q.Severity]; q.Severity != "" && !ok {
+		return ScheduleQuery{}, fmt.Errorf("%w: severity %q must be one of %s, %s, %s", ErrInvalidQuery, q.Severity,
+			SeverityRetimed, SeverityUnderMinimum, SeverityBroken)
+	}
+	if s := values.Get("minDelay"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return ScheduleQuery{}, fmt.Errorf("%w: minDelay %q must be a non-negative number of minutes", ErrInvalidQuery, s)
+		}
+		q.MinDelay = n
+	}
+	return q, nil
+}
+
+// AssessScheduleChange compares the original and current segments of a tracked booking.
+func AssessScheduleChange(t schedule.Tracked) ScheduleImpact {
+	old, cur := t.Original.Segments(), t.Current.Segments()
+	impact := ScheduleImpact{
+		Source:        t.Current.Source(),
+		ID:            t.Current.ID(),
+		PassengerName: t.Current.PassengerName(),
+		Status:        t.Current.Status(),
+		Route:         routeOf(cur),
+		Severity:      SeverityRetimed,
+		DetectedAt:    t.DetectedAt,
+		UpdatedAt:     t.UpdatedAt,
+		Version:       t.Version,
+		Segments:      []SegmentChange{},
+		Connections:   []ConnectionImpact{},
+	}
+	for i := range cur {
+		depart := minutes(cur[i].DepartTime().Sub(old[i].DepartTime()))
+		arrive := minutes(cur[i].ArriveTime().Sub(old[i].ArriveTime()))
+		impact.MaxDelayMinutes = max(impact.MaxDelayMinutes, abs(depart), abs(arrive))
+		if depart == 0 && arrive == 0 {
+			continue
+		}
+		impact.Segments = append(impact.Segments, SegmentChange{
+			Index: i, FlightNumber: cur[i].FlightNumber(), From: cur[i].Departure(), To: cur[i].Arrival(),
+			OldDepart: old[i].DepartTime(), NewDepart: cur[i].DepartTime(),
+			OldArrive: old[i].ArriveTime(), NewArrive: cur[i].ArriveTime(),
+			DepartDelayMinutes: depart, ArriveDelayMinutes: arrive,
+		})
+	}
+	if n := len(cur); n > 0 {
+		impact.ArrivalDelayMinutes = minutes(cur[n-1].ArriveTime().Sub(old[n-1].ArriveTime()))
+	}
+	for i := 1; i < len(cur); i++ {
+		c := ConnectionImpact{
+			Airport:        cur[i].Departure(),
+			Inbound:        cur[i-1].FlightNumber(),
+			Outbound:       cur[i].FlightNumber(),
+			OldMinutes:     minutes(old[i].DepartTime().Sub(old[i-1].ArriveTime())),
+			NewMinutes:     minutes(cur[i].DepartTime().Sub(cur[i-1].ArriveTime())),
+			MinimumMinutes: minutes(reference.MinimumConnectionTime(cur[i-1].Departure(), cur[i].Departure(), cur[i].Arrival())),
+			Status:         ConnectionOK,
+		}
+		switch {
+		case c.NewMinutes < 0:
+			c.Status = ConnectionBroken
+		case c.NewMinutes < c.MinimumMinutes:
+			c.Status = ConnectionShort
+		}
+		if c.Status != ConnectionOK && severityRank[c.Status] > severityRank[impact.Severity] {
+			impact.Severity = c.Status
+		}
+		impact.Connections = append(impact.Connections, c)
+	}
+	return impact
+}
+
+// BuildScheduleReport assesses the tracked bookings matching the query and groups them by passenger.
+func BuildScheduleReport(tracked []schedule.Tracked, q ScheduleQuery) ScheduleReport {
+	report := ScheduleReport{GeneratedAt: time.Now().UTC(), Passengers: []AffectedPassenger{}, Changes: []ScheduleImpact{}}
+	for _, t := range tracked {
+		impact := AssessScheduleChange(t)
+		if q.Severity != "" && severityRank[impact.Severity] < severityRank[q.Severity] {
+			continue
+		}
+		if impact.MaxDelayMinutes < q.MinDelay {
+			continue
+		}
+		report.Changes = append(report.Changes, impact)
+	}
+	sort.SliceStable(report.Changes, func(i, j int) bool {
+		a, b := report.Changes[i], report.Changes[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		return a.MaxDelayMinutes > b.MaxDelayMinutes
+	})
+	report.Bookings = len(report.Changes)
+
+	index := make(map[string]int)
+	for _, c := range report.Changes {
+		key := passengerKey(c.PassengerName)
+		i, ok := index[key]
+		if !ok {
+			i = len(report.Passengers)
+			index[key] = i
+			report.Passengers = append(report.Passengers, AffectedPassenger{PassengerName: c.PassengerName, Severity: c.Severity})
+		}
+		report.Passengers[i].Bookings = append(report.Passengers[i].Bookings, c.ID)
+	}
+	return report
+}
+
+// WriteCSV writes the changes of the report as CSV, one row per changed segment.
+func (r ScheduleReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"source", "id", "passengerName", "route", "severity", "arrivalDelayMinutes",
+		"flightNumber", "from", "to", "oldDepart", "newDepart", "departDelayMinutes", "arriveDelayMinutes"}); err != nil {
+		return err
+	}
+	for _, c := range r.Changes {
+		for _, s := range c.Segments {
+			if err := cw.Write([]string{c.Source, c.ID, c.PassengerName, c.Route, c.Severity, strconv.Itoa(c.ArrivalDelayMinutes),
+				s.FlightNumber, s.From, s.To, s.OldDepart.Format(time.RFC3339), s.NewDepart.Format(time.RFC3339),
+				strconv.Itoa(s.DepartDelayMinutes), strconv.Itoa(s.ArriveDelayMinutes)}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// routeOf joins the airports of the segments, such as "CDG-FRA-HND".
+func routeOf(segs []domain.Segment) string {
+	if len(segs) == 0 {
+		return ""
+	}
+	airports := []string{segs[0].Departure()}
+	for _, s := range segs {
+		airports = append(airports, s.Arrival())
+	}
+	return strings.Join(airports, "-")
+}
+
+// minutes rounds a duration to whole minutes.
+func minutes(d time.Duration) int { return int(d.Round(time.Minute) / time.Minute) }
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
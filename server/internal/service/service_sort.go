@@ -0,0 +1,204 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SortKey names a criterion flights can be sorted on.
+type SortKey string
+
+const (
+	SortPrice     SortKey = "price"
+	SortPriceBag  SortKey = "priceWithBag"
+	SortDuration  SortKey = "duration"
+	SortDeparture SortKey = "departure"
+	SortArrival   SortKey = "arrival"
+	SortStops     SortKey = "stops"
+	SortCarrier   SortKey = "carrier"
+	SortLayover   SortKey = "layover"
+	SortSource    SortKey = "source"
+	SortDistance  SortKey = "distance"
+	SortCO2       SortKey = "co2"
+)
+
+// sortKeyAliases maps accepted spellings, including the legacy /flights/sorted types, to sort keys.
+var sortKeyAliases = map[string]SortKey{
+	"price":          SortPrice,
+	"pricewithbag":   SortPriceBag,
+	"duration":       SortDuration,
+	"time":           SortDuration,
+	"timetravel":     SortDuration,
+	"departure":      SortDeparture,
+	"depart":         SortDeparture,
+	"departure_date": SortDeparture,
+	"arrival":        SortArrival,
+	"arrive":         SortArrival,
+	"stops":          SortStops,
+	"carrier":        SortCarrier,
+	"layover":        SortLayover,
+	"source":         SortSource,
+	"distance":       SortDistance,
+	"co2":            SortCO2,
+	"emissions":      SortCO2,
+}
+
+// sortValue returns the value of a key for a flight, and false when the flight has no such value
+// (flights without segments have no departure, arrival, carrier, ...).
+type sortValue func(domain.Flight) (any, bool)
+
+// sortValues extracts the value of every sort key.
+var sortValues = map[SortKey]sortValue{
+	SortPrice:  func(f domain.Flight) (any, bool) { return f.Total().Amount(), true },
+	SortSource: func(f domain.Flight) (any, bool) { return f.Source(), true },
+	// Prices with a bag are compared in BaseCurrency; bookings without a checked bag or a known bag fee, or in an
+	// unknown currency, have no value and come last.
+	SortPriceBag: func(f domain.Flight) (any, bool) { return PriceWithCheckedBag(f, BaseCurrency) },
+	// Itineraries through an airport missing from the reference table have no distance nor emissions and come last.
+	SortDistance: func(f domain.Flight) (any, bool) { return f.DistanceKm() },
+	SortCO2:      func(f domain.Flight) (any, bool) { return f.CO2Kg() },
+	SortDuration: withSegments(func(f domain.Flight, _ []domain.Segment) any {
+		return TotalTravelTime(f).Seconds()
+	}),
+	SortDeparture: withSegments(func(_ domain.Flight, segs []domain.Segment) any {
+		return float64(segs[0].DepartTime().Unix())
+	}),
+	SortArrival: withSegments(func(_ domain.Flight, segs []domain.Segment) any {
+		return float64(segs[len(segs)-1].ArriveTime().Unix())
+	}),
+	SortStops: withSegments(func(_ domain.Flight, segs []domain.Segment) any {
+		return float64(len(segs) - 1)
+	}),
+	SortCarrier: withSegments(func(_ domain.Flight, segs []domain.Segment) any {
+		return segs[0].Carrier()
+	}),
+	SortLayover: withSegments(func(f domain.Flight, _ []domain.Segment) any {
+		return TotalLayover(f).Seconds()
+	}),
+}
+
+// withSegments wraps a value extractor that needs at least one segment.
+func withSegments(value func(domain.Flight, []domain.Segment) any) sortValue {
+	return func(f domain.Flight) (any, bool) {
+		segs := f.Segments()
+		if len(segs) == 0 {
+			return nil, false
+		}
+		return value(f, segs), true
+	}
+}
+
+// SortField is one criterion of a sort specification.
+type SortField struct {
+	Key  SortKey
+	Desc bool
+}
+
+// SortSpec is an ordered list of sort criteria. Ties left by every criterion are broken by source then booking id,
+// and flights lacking a value for a criterion (no segments) are always placed after the others, whatever the direction.
+type SortSpec []SortField
+
+// ParseSort parses a specification such as "price:asc,departure:desc". The direction defaults to ascending.
+func ParseSort(s string) (SortSpec, error) {
+	var spec SortSpec
+	seen := make(map[SortKey]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(strings.ToLower(part))
+		if part == "" {
+			continue
+		}
+		name, dir, _ := strings.Cut(part, ":")
+		key, ok := sortKeyAliases[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown sort key %q", ErrInvalidQuery, name)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: sort key %q given twice", ErrInvalidQuery, key)
+		}
+		seen[key] = true
+
+		field := SortField{Key: key}
+		switch strings.TrimSpace(dir) {
+		case "", "asc":
+		case "desc":
+			field.Desc = true
+		default:
+			return nil, fmt.Errorf("%w: sort direction %q must be asc or desc", ErrInvalidQuery, dir)
+		}
+		spec = append(spec, field)
+	}
+	if len(spec) == 0 {
+		return nil, fmt.Errorf("%w: empty sort", ErrInvalidQuery)
+	}
+	return spec, nil
+}
+
+// String returns the canonical form of the specification, e.g. "price:asc,departure:desc".
+func (s SortSpec) String() string {
+	parts := make([]string, len(s))
+	for i, f := range s {
+		dir := "asc"
+		if f.Desc {
+			dir = "desc"
+		}
+		parts[i] = string(f.Key) + ":" + dir
+	}
+	return strings.Join(parts, ",")
+}
+
+// Ordering returns the keyset ordering implementing the specification.
+// Each criterion contributes two key values: a missing flag, always ascending, then the value itself.
+func (s SortSpec) Ordering() Ordering {
+	return Ordering{
+		Name: s.String(),
+		Key: func(f domain.Flight) []any {
+			key := make([]any, 0, 2*len(s)+2)
+			for _, field := range s {
+				v, ok := sortValues[field.Key](f)
+				if !ok {
+					key = append(key, 1.0, "")
+					continue
+				}
+				key = append(key, 0.0, v)
+			}
+			return append(key, tieBreaker(f)...)
+		},
+		Compare: func(a, b []any) int {
+			n := 2 * len(s)
+			if len(a) < n || len(b) < n {
+				return compareKeys(a, b)
+			}
+			for i, field := range s {
+				if c := compareValues(a[2*i], b[2*i]); c != 0 {
+					return c
+				}
+				c := compareValues(a[2*i+1], b[2*i+1])
+				if field.Desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return compareKeys(a[n:], b[n:])
+		},
+	}
+}
+
+// Sort orders flights in place according to the specification, using a stable sort.
+func (s SortSpec) Sort(flights domain.Flights) {
+	s.Ordering().Sort(flights)
+}
+
+// SortFlights retrieves all flights from repositories and sorts them according to the specification.
+func SortFlights(ctx context.Context, r *repo.Multi, spec SortSpec) (domain.Flights, error) {
+	flights, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spec.Sort(flights)
+	return flights, nil
+}
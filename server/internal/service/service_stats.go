@@ -0,0 +1,198 @@
+package service
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// StatsQuery selects the bookings summarized by /stats and the currency their prices are normalized into.
+type StatsQuery struct {
+	Filters Query
+	// PriceCurrency is the currency of the price statistics; BaseCurrency when empty.
+	PriceCurrency string
+}
+
+// PriceStats summarizes the totals of a group of bookings, converted into one currency.
+type PriceStats struct {
+	Currency string  `json:"currency"`
+	Min      float64 `json:"min"`
+	Avg      float64 `json:"avg"`
+	Median   float64 `json:"median"`
+	Max      float64 `json:"max"`
+}
+
+// RouteStats summarizes the bookings from one origin to one destination, whatever their connections.
+type RouteStats struct {
+	Route    string `json:"route"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Bookings int    `json:"bookings"`
+	// Price is nil when no booking of the route has a total in a known currency.
+	Price *PriceStats `json:"price,omitempty"`
+}
+
+// Stats summarizes the bookings of the aggregated catalogue that match the filters.
+type Stats struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Version is the number of the catalogue version summarized.
+	Version  uint64         `json:"version"`
+	Bookings int            `json:"bookings"`
+	BySource map[string]int `json:"bySource"`
+	ByStatus map[string]int `json:"byStatus"`
+	// ByCarrier counts the bookings with at least one segment marketed by each carrier.
+	ByCarrier map[string]int `json:"byCarrier"`
+	// PhysicalFlights counts the distinct flights the segments are flown on, codeshares sold under several numbers
+	// counting once.
+	PhysicalFlights int `json:"physicalFlights"`
+	// Routes are ordered by decreasing number of bookings, then by route.
+	Routes []RouteStats `json:"routes"`
+	// UnpricedBookings counts the bookings whose currency cannot be converted, left out of the price statistics.
+	UnpricedBookings int `json:"unpricedBookings"`
+	// AverageDurationMinutes is the mean total travel time of the bookings with segments.
+	AverageDurationMinutes float64 `json:"averageDurationMinutes"`
+	// AverageLayoverMinutes is the mean total layover of the bookings with at least one connection.
+	AverageLayoverMinutes float64 `json:"averageLayoverMinutes"`
+}
+
+// ParseStatsQuery reads the /stats query params: the filters of the search endpoints and "priceCurrency".
+func ParseStatsQuery(values url.Values) (StatsQuery, error) {
+	filters, err := ParseQuery(values)
+	if err != nil {
+		return StatsQuery{}, err
+	}
+	q := StatsQuery{Filters: filters, PriceCurrency: BaseCurrency}
+	if s := strings.ToUpper(strings.TrimSpace(values.Get("priceCurrency"))); s != "" {
+		if !IsKnownCurrency(s) {
+			return StatsQuery{}, fmt.Errorf("%w: %w: %s", ErrInvalidQuery, ErrUnknownCurrency, s)
+		}
+		q.PriceCurrency = s
+	}
+	return q, nil
+}
+
+// ComputeStats summarizes the flights, which are expected to be already filtered.
+func ComputeStats(flights domain.Flights, currency string) Stats {
+	if currency == "" {
+		currency = BaseCurrency
+	}
+	stats := Stats{
+		GeneratedAt: time.Now().UTC(),
+		Bookings:    len(flights),
+		BySource:    make(map[string]int),
+		ByStatus:    make(map[string]int),
+		ByCarrier:   make(map[string]int),
+		Routes:      []RouteStats{},
+	}
+
+	routes := make(map[string]*RouteStats)
+	physical := make(map[string]bool)
+	prices := make(map[string][]float64)
+	var duration, layover time.Duration
+	timed, connecting := 0, 0
+
+	for _, f := range flights {
+		stats.BySource[f.Source()]++
+		stats.ByStatus[strings.ToLower(f.Status())]++
+
+		segs := f.Segments()
+		if len(segs) == 0 {
+			continue
+		}
+		carriers := make(map[string]bool)
+		for _, s := range segs {
+			carriers[s.Carrier()] = true
+			physical[PhysicalFlightKey(s)] = true
+		}
+		for c := range carriers {
+			stats.ByCarrier[c]++
+		}
+
+		duration += TotalTravelTime(f)
+		timed++
+		if len(segs) > 1 {
+			layover += TotalLayover(f)
+			connecting++
+		}
+
+		from, to := strings.ToUpper(segs[0].Departure()), strings.ToUpper(segs[len(segs)-1].Arrival())
+		key := from + "-" + to
+		route, ok := routes[key]
+		if !ok {
+			route = &RouteStats{Route: key, From: from, To: to}
+			routes[key] = route
+		}
+		route.Bookings++
+
+		amount, err := NormalizedAmount(f, currency)
+		if err != nil {
+			stats.UnpricedBookings++
+			continue
+		}
+		prices[key] = append(prices[key], amount)
+	}
+
+	stats.PhysicalFlights = len(physical)
+
+	for key, route := range routes {
+		route.Price = priceStats(prices[key], currency)
+		stats.Routes = append(stats.Routes, *route)
+	}
+	slices.SortFunc(stats.Routes, func(a, b RouteStats) int {
+		if a.Bookings != b.Bookings {
+			return b.Bookings - a.Bookings
+		}
+		return strings.Compare(a.Route, b.Route)
+	})
+
+	if timed > 0 {
+		stats.AverageDurationMinutes = roundTo(duration.Minutes()/float64(timed), 1)
+	}
+	if connecting > 0 {
+		stats.AverageLayoverMinutes = roundTo(layover.Minutes()/float64(connecting), 1)
+	}
+	return stats
+}
+
+// priceStats returns the min, mean, median and max of the amounts, or nil when there is none.
+func priceStats(amounts []float64, currency string) *PriceStats {
+	if len(amounts) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(amounts)
+	slices.Sort(sorted)
+	sum := 0.0
+	for _, a := range sorted {
+		sum += a
+	}
+	n := len(sorted)
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return &PriceStats{
+		Currency: currency,
+		Min:      roundTo(sorted[0], 2),
+		Avg:      roundTo(sum/float64(n), 2),
+		Median:   roundTo(median, 2),
+		Max:      roundTo(sorted[n-1], 2),
+	}
+}
+
+// roundTo rounds v to the given number of decimals.
+func roundTo(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+// GetStats summarizes the bookings of a catalogue version matching the query, without another call to the providers.
+func GetStats(v catalogue.Version, q StatsQuery) Stats {
+	stats := ComputeStats(q.Filters.Apply(v.Flights), q.PriceCurrency)
+	stats.Version = v.Number
+	return stats
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"aggregator/internal/changes"
+	"aggregator/internal/domain"
+)
+
+// Event types sent on /flights/stream.
+const (
+	EventSnapshot = "snapshot"
+	EventAdd      = "add"
+	EventUpdate   = "update"
+	EventRemove   = "remove"
+)
+
+// StreamEvent is one event of a flight stream. Version is the catalogue version the event belongs to.
+type StreamEvent struct {
+	Type    string
+	Version uint64
+	Data    any
+}
+
+// StreamSnapshot is the data of a snapshot event: the bookings matching the stream filters.
+type StreamSnapshot struct {
+	Version uint64                  `json:"version"`
+	Flights []domain.FlightSnapshot `json:"flights"`
+}
+
+// FlightStream turns catalogue changes into the events of one client, keeping the bookings the client was sent.
+type FlightStream struct {
+	match func(domain.Flight) bool
+	// visible is the set of matching bookings the client knows of; nil when unknown, after a resumption.
+	visible map[changes.BookingRef]bool
+}
+
+// NewFlightStream creates a stream of the bookings matching the query.
+func NewFlightStream(q Query) *FlightStream {
+	return NewMatchStream(q.Match)
+}
+
+// NewMatchStream creates a stream of the bookings accepted by match.
+func NewMatchStream(match func(domain.Flight) bool) *FlightStream {
+	return &FlightStream{match: match}
+}
+
+// Snapshot returns the snapshot event of the matching bookings, in the default order, and remembers them.
+func (s *FlightStream) Snapshot(version uint64, flights domain.Flights) StreamEvent {
+	matched := make(domain.Flights, 0, len(flights))
+	for _, f := range flights {
+		if s.match(f) {
+			matched = append(matched, f)
+		}
+	}
+	DefaultOrdering.Sort(matched)
+	s.visible = make(map[changes.BookingRef]bool, len(matched))
+	for _, f := range matched {
+		s.visible[changes.BookingRef{Source: f.Source(), ID: f.ID()}] = true
+	}
+	return StreamEvent{Type: EventSnapshot, Version: version, Data: StreamSnapshot{Version: version, Flights: matched.ToSnapshot()}}
+}
+
+// Resume starts a stream whose client already holds the bookings of an earlier version, which are not known here.
+// Until the next Snapshot, updates of matching bookings are sent as "update" events that clients apply as upserts,
+// and removals are sent for every booking that left the catalogue or stopped matching.
+func (s *FlightStream) Resume() {
+	s.visible = nil
+}
+
+// Apply returns the events of one catalogue change, in the order of the change: bookings entering the filtered set
+// are added, bookings leaving it removed and bookings staying in it updated.
+func (s *FlightStream) Apply(c changes.Change) []StreamEvent {
+	var out []StreamEvent
+	emit := func(kind string, data any) {
+		out = append(out, StreamEvent{Type: kind, Version: c.Version, Data: data})
+	}
+	for _, snapshot := range c.Added {
+		if s.match(*snapshot.ToDomain()) {
+			s.show(changes.BookingRef{Source: snapshot.Source, ID: snapshot.ID})
+			emit(EventAdd, snapshot)
+		}
+	}
+	for _, m := range c.Modified {
+		matches := s.match(*m.Flight.ToDomain())
+		switch {
+		case matches && s.visible != nil && !s.visible[m.BookingRef]:
+			s.show(m.BookingRef)
+			emit(EventAdd, m.Flight)
+		case matches:
+			emit(EventUpdate, m.Flight)
+		case s.visible == nil || s.visible[m.BookingRef]:
+			s.hide(m.BookingRef)
+			emit(EventRemove, m.BookingRef)
+		}
+	}
+	for _, ref := range c.Removed {
+		if s.visible == nil || s.visible[ref] {
+			s.hide(ref)
+			emit(EventRemove, ref)
+		}
+	}
+	return out
+}
+
+// show remembers that the client holds a booking, when the set is known.
+func (s *FlightStream) show(ref changes.BookingRef) {
+	if s.visible != nil {
+		s.visible[ref] = true
+	}
+}
+
+// hide forgets a booking the client was told to remove.
+func (s *FlightStream) hide(ref changes.BookingRef) {
+	delete(s.visible, ref)
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Watch selects the bookings a subscription follows: the bookings of a route, such as "CDG-HND", optionally departing
+// on one date (YYYY-MM-DD, in the local time of the origin), or one booking by id, optionally of one provider.
+type Watch struct {
+	Route   string `json:"route,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Booking string `json:"booking,omitempty"`
+	Source  string `json:"source,omitempty"`
+}
+
+// ParseWatch validates a watch and returns it normalized: route codes upper-cased, surrounding spaces trimmed.
+// Exactly one of route and booking must be set.
+func ParseWatch(w Watch) (Watch, error) {
+	w.Route, w.Date = strings.ToUpper(strings.TrimSpace(w.Route)), strings.TrimSpace(w.Date)
+	w.Booking, w.Source = strings.TrimSpace(w.Booking), strings.TrimSpace(w.Source)
+	switch {
+	case (w.Route == "") == (w.Booking == ""):
+		return Watch{}, fmt.Errorf("%w: a watch needs either a route or a booking", ErrInvalidQuery)
+	case w.Booking != "" && w.Date != "":
+		return Watch{}, fmt.Errorf("%w: date only applies to a route watch", ErrInvalidQuery)
+	case w.Route != "" && w.Source != "":
+		return Watch{}, fmt.Errorf("%w: source only applies to a booking watch", ErrInvalidQuery)
+	}
+	if w.Route != "" {
+		from, to, ok := strings.Cut(w.Route, "-")
+		if !ok || !isCode(from, 3, false) || !isCode(to, 3, false) {
+			return Watch{}, fmt.Errorf("%w: route %q must be written FROM-TO with IATA airport codes", ErrInvalidQuery, w.Route)
+		}
+	}
+	if w.Date != "" {
+		if _, err := time.Parse(time.DateOnly, w.Date); err != nil {
+			return Watch{}, fmt.Errorf("%w: date %q must be formatted as YYYY-MM-DD", ErrInvalidQuery, w.Date)
+		}
+	}
+	return w, nil
+}
+
+// Match reports whether a booking is followed by the watch.
+func (w Watch) Match(f domain.Flight) bool {
+	if w.Booking != "" {
+		return f.ID() == w.Booking && (w.Source == "" || strings.EqualFold(f.Source(), w.Source))
+	}
+	segs := f.Segments()
+	if len(segs) == 0 {
+		return false
+	}
+	first, last := segs[0], segs[len(segs)-1]
+	if !strings.EqualFold(first.Departure()+"-"+last.Arrival(), w.Route) {
+		return false
+	}
+	return w.Date == "" ||
+		first.DepartTime().In(reference.AirportLocation(first.Departure())).Format(time.DateOnly) == w.Date
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"aggregator/internal/service"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createAirlineFlights generates one booking per alliance plus one on an airline outside them.
+func createAirlineFlights() domain.Flights {
+	seg := func(number string, leg int) domain.Segment {
+		return newTestSegment(number, "CDG", "HND", testDay.Add(time.Duration(leg)*4*time.Hour), 3*time.Hour)
+	}
+	total := domain.NewTotal(900.00, "EUR")
+
+	return domain.Flights{
+		newTestFlight("A10001", total, seg("AF276", 0)),
+		newTestFlight("A10002", total, seg("JL046", 0)),
+		newTestFlight("A10003", total, seg("EK076", 0), seg("NH216", 1)),
+		newTestFlight("A10004", total, seg("EK312", 0)),
+	}
+}
+
+// TestSegment_Carrier verifies carrier extraction from flight numbers and the airline reference table.
+func TestSegment_Carrier(t *testing.T) {
+	println("=====================AIRLINE_UNIT_TEST====================")
+
+	d, ok := domain.ParseFlightNumber("af 0276")
+	assert.True(t, ok)
+	assert.Equal(t, domain.FlightDesignator{Carrier: "AF", Number: 276}, d)
+
+	_, ok = domain.ParseFlightNumber("not a flight")
+	assert.False(t, ok)
+
+	now := time.Now()
+	for number, carrier := range map[string]string{
+		"AF276":  "AF",
+		"AFR276": "AF",
+		"U2 012": "U2",
+		"XYZ123": "XYZ",
+		"?":      "?",
+	} {
+		assert.Equal(t, carrier, domain.NewSegment(number, "CDG", "HND", now, now).Carrier(), number)
+	}
+
+	a, ok := reference.LookupAirline("jal")
+	assert.True(t, ok)
+	assert.Equal(t, reference.Airline{Code: "JL", ICAO: "JAL", Name: "Japan Airlines", Alliance: reference.AllianceOneworld}, a)
+
+	alliance, ok := reference.ParseAlliance("Star alliance")
+	assert.True(t, ok)
+	assert.Equal(t, reference.AllianceStar, alliance)
+
+	snapshot := domain.NewSegment("AF276", "CDG", "HND", now, now).Snapshot()
+	assert.Equal(t, "AF", snapshot.Carrier)
+	assert.Equal(t, "Air France", snapshot.CarrierName)
+}
+
+// TestQuery_CarrierAndAlliance verifies the carrier and alliance filters.
+func TestQuery_CarrierAndAlliance(t *testing.T) {
+	for _, tc := range []struct {
+		values url.Values
+		want   []string
+	}{
+		{url.Values{"carrier": {"af"}}, []string{"A10001"}},
+		{url.Values{"carrier": {"JAL"}}, []string{"A10002"}},
+		{url.Values{"alliance": {"skyteam"}}, []string{"A10001"}},
+		{url.Values{"alliance": {"star"}}, []string{"A10003"}},
+		{url.Values{"alliance": {"oneworld"}, "carrier": {"AF"}}, nil},
+	} {
+		q, err := service.ParseQuery(tc.values)
+		assert.NoError(t, err, tc.values.Encode())
+		assert.Equal(t, tc.want, flightIDs(q.Apply(createAirlineFlights())), tc.values.Encode())
+	}
+
+	for _, values := range []url.Values{{"alliance": {"unknown"}}, {"carrier": {"XXXX"}}} {
+		_, err := service.ParseQuery(values)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, values.Encode())
+	}
+}
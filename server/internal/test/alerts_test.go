@@ -0,0 +1,301 @@
+package test
+
+import (
+	"aggregator/internal/alerts"
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/webhook"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitDelivery polls a dispatcher until its only delivery is no longer pending.
+func waitDelivery(t *testing.T, d *webhook.Dispatcher) webhook.Delivery {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if list := d.Deliveries(); len(list) == 1 && list[0].Status != webhook.StatusPending {
+			return list[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("delivery still pending")
+	return webhook.Delivery{}
+}
+
+// TestParseAlert verifies the validation and defaults of alert inputs.
+func TestParseAlert(t *testing.T) {
+	println("=====================ALERTS_UNIT_TEST====================")
+
+	a, err := alerts.ParseAlert(alerts.Input{Route: "cdg-hnd", Date: "2026-01-01", Below: 850, URL: "https://crm.example/hooks"})
+	assert.NoError(t, err)
+	assert.Equal(t, "CDG-HND", a.Route)
+	assert.Equal(t, "EUR", a.Currency)
+
+	for _, invalid := range []alerts.Input{
+		{Below: 850, URL: "https://crm.example"},
+		{Route: "CDG-HND", URL: "https://crm.example"},
+		{Route: "CDG-HND", Below: 850, URL: "crm.example"},
+		{Route: "CDG-HND", Below: 850, Currency: "XXX", URL: "https://crm.example"},
+		{Route: "CDG-HND", Below: 850, URL: "http://169.254.169.254/latest/meta-data"},
+	} {
+		_, err = alerts.ParseAlert(invalid)
+		assert.ErrorIs(t, err, alerts.ErrInvalidAlert, invalid)
+	}
+}
+
+// TestWebhook_Targets verifies that webhooks cannot reach the loopback, private and link-local networks, whether the
+// URL names an address or a host resolving to one.
+func TestWebhook_Targets(t *testing.T) {
+	for raw, forbidden := range map[string]bool{
+		"https://crm.example/hooks":       false,
+		"http://93.184.216.34/hooks":      false,
+		"http://localhost:8080/":          true,
+		"http://api.localhost/":           true,
+		"http://127.0.0.1:3001/alerts":    true,
+		"http://10.0.0.5/":                true,
+		"http://192.168.1.1/":             true,
+		"http://169.254.169.254/latest":   true,
+		"http://[::1]:3001/":              true,
+		"http://[fe80::1]/":               true,
+		"http://0.0.0.0/":                 true,
+		"http://[::ffff:127.0.0.1]:3001/": true,
+	} {
+		err := webhook.CheckURL(raw)
+		if forbidden {
+			assert.ErrorIs(t, err, webhook.ErrForbiddenTarget, raw)
+		} else {
+			assert.NoError(t, err, raw)
+		}
+	}
+	assert.Error(t, webhook.CheckURL("ftp://crm.example"))
+
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+
+	// a host name is checked once resolved, when the delivery is sent
+	d := webhook.New(webhook.Options{MaxAttempts: 1})
+	_, err := d.Send("t1", "test", strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1), "s3cret", nil)
+	assert.NoError(t, err)
+	delivery := waitDelivery(t, d)
+	assert.Equal(t, webhook.StatusFailed, delivery.Status)
+	assert.Contains(t, delivery.LastError, webhook.ErrForbiddenTarget.Error())
+	assert.Zero(t, calls.Load())
+}
+
+// TestWebhook_Retries verifies the signature of a delivery and its retries until the receiver accepts it.
+func TestWebhook_Retries(t *testing.T) {
+	// the receivers of the tests listen on the loopback interface
+	webhook.AllowPrivateNetworks(true)
+	defer webhook.AllowPrivateNetworks(false)
+	var calls atomic.Int32
+	var signed atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signed.Store(webhook.Verify("s3cret", r.Header.Get(webhook.HeaderTimestamp), body, r.Header.Get(webhook.HeaderSignature)))
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	d := webhook.New(webhook.Options{Backoff: func(int) time.Duration { return time.Millisecond }})
+	_, err := d.Send("t1", "test", receiver.URL, "s3cret", map[string]int{"n": 1})
+	assert.NoError(t, err)
+
+	delivery := waitDelivery(t, d)
+	assert.Equal(t, webhook.StatusDelivered, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, http.StatusNoContent, delivery.StatusCode)
+	assert.True(t, signed.Load())
+	assert.Empty(t, d.DeadLetters())
+
+	// a receiver that never accepts ends in the dead letters
+	d = webhook.New(webhook.Options{MaxAttempts: 2, Backoff: func(int) time.Duration { return time.Millisecond }})
+	_, err = d.Send("t1", "test", "http://127.0.0.1:1/unreachable", "s3cret", nil)
+	assert.NoError(t, err)
+	delivery = waitDelivery(t, d)
+	assert.Equal(t, webhook.StatusFailed, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Len(t, d.DeadLetters(), 1)
+}
+
+// TestWebhook_LogSize verifies that dead letters stay in the log, and in the file, after more than LogSize deliveries.
+func TestWebhook_LogSize(t *testing.T) {
+	// the receivers of the tests listen on the loopback interface
+	webhook.AllowPrivateNetworks(true)
+	defer webhook.AllowPrivateNetworks(false)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	path := filepath.Join(t.TempDir(), "dead_letters.json")
+	d, err := webhook.Open(path, webhook.Options{MaxAttempts: 1, LogSize: 3})
+	assert.NoError(t, err)
+	dead, err := d.Send("a1", "test", receiver.URL+"/fail", "secret", map[string]int{"n": 0})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(d.DeadLetters()) == 1 }, 5*time.Second, 5*time.Millisecond)
+	for i := 1; i <= 5; i++ {
+		_, err = d.Send("a1", "test", receiver.URL, "secret", map[string]int{"n": i})
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool { return d.Deliveries()[len(d.Deliveries())-1].Status == webhook.StatusDelivered },
+			5*time.Second, 5*time.Millisecond)
+	}
+	assert.Len(t, d.Deliveries(), 3)
+	assert.Equal(t, []string{dead.ID}, deliveryIDs(d.DeadLetters()))
+
+	// saving a later dead letter keeps the first one in the file
+	second, err := d.Send("a1", "test", receiver.URL+"/fail", "secret", map[string]int{"n": 6})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(d.DeadLetters()) == 2 }, 5*time.Second, 5*time.Millisecond)
+	reopened, err := webhook.Open(path, webhook.Options{LogSize: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{dead.ID, second.ID}, deliveryIDs(reopened.DeadLetters()))
+}
+
+// deliveryIDs returns the ids of deliveries, in order.
+func deliveryIDs(list []webhook.Delivery) []string {
+	ids := make([]string, len(list))
+	for i, d := range list {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+// TestAlerts_Record verifies that an alert is notified once when its price drops below the threshold, armed again
+// when the price goes back up, and kept across a restart.
+func TestAlerts_Record(t *testing.T) {
+	// the receivers of the tests listen on the loopback interface
+	webhook.AllowPrivateNetworks(true)
+	defer webhook.AllowPrivateNetworks(false)
+	received := make(chan alerts.Notification, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n alerts.Notification
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		received <- n
+	}))
+	defer receiver.Close()
+
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	d := webhook.New(webhook.Options{})
+	store, err := alerts.Open(path, d)
+	assert.NoError(t, err)
+	input, err := alerts.ParseAlert(alerts.Input{Route: "CDG-HND", Date: "2026-01-01", Below: 850, URL: receiver.URL})
+	assert.NoError(t, err)
+	alert, err := store.Create(input)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, alert.Secret)
+
+	cat := catalogue.New()
+	cat.Subscribe(store.Record)
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 900, "EUR"), historyFlight("A10002", 2, 700, "EUR")})
+	assert.Empty(t, received)
+
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 820, "EUR"), historyFlight("A10002", 2, 700, "EUR")})
+	select {
+	case n := <-received:
+		assert.Equal(t, alerts.EventPriceBelow, n.Event)
+		assert.Equal(t, alert.ID, n.AlertID)
+		assert.Equal(t, 820.0, n.Price)
+		assert.Equal(t, "A10001", n.Flight.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("alert not notified")
+	}
+
+	// still below: not notified again
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 810, "EUR")})
+	stored, err := store.Get(alert.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.Triggered)
+	assert.Equal(t, 810.0, *stored.LastPrice)
+
+	// back up then down again: notified again
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 860, "EUR")})
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 800, "EUR")})
+	select {
+	case n := <-received:
+		assert.Equal(t, 800.0, n.Price)
+	case <-time.After(5 * time.Second):
+		t.Fatal("alert not notified again")
+	}
+	assert.Empty(t, received)
+
+	reopened, err := alerts.Open(path, d)
+	assert.NoError(t, err)
+	stored, err = reopened.Get(alert.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.Triggered)
+	assert.Equal(t, alert.Secret, stored.Secret)
+
+	assert.NoError(t, reopened.Delete(alert.ID))
+	_, err = reopened.Get(alert.ID)
+	assert.ErrorIs(t, err, alerts.ErrAlertNotFound)
+}
+
+// TestAlerts_DeadLetter verifies that an alert whose webhook fails is armed again, and that dead letters survive
+// a restart and can still be redelivered.
+func TestAlerts_DeadLetter(t *testing.T) {
+	// the receivers of the tests listen on the loopback interface
+	webhook.AllowPrivateNetworks(true)
+	defer webhook.AllowPrivateNetworks(false)
+
+	var accept atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !accept.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	path := filepath.Join(t.TempDir(), "dead_letters.json")
+	d, err := webhook.Open(path, webhook.Options{MaxAttempts: 1})
+	assert.NoError(t, err)
+	store := alerts.New(d)
+	d.SubscribeDeadLetters(store.Rearm)
+	input, err := alerts.ParseAlert(alerts.Input{Route: "CDG-HND", Below: 850, URL: receiver.URL})
+	assert.NoError(t, err)
+	alert, err := store.Create(input)
+	assert.NoError(t, err)
+
+	store.Record(catalogue.Version{}, catalogue.Version{Number: 1, Flights: domain.Flights{historyFlight("A10001", 1, 820, "EUR")}})
+	delivery := waitDelivery(t, d)
+	assert.Equal(t, webhook.StatusFailed, delivery.Status)
+	assert.Eventually(t, func() bool {
+		stored, err := store.Get(alert.ID)
+		return err == nil && !stored.Triggered
+	}, 5*time.Second, 5*time.Millisecond)
+
+	reopened, err := webhook.Open(path, webhook.Options{MaxAttempts: 1})
+	assert.NoError(t, err)
+	dead := reopened.DeadLetters()
+	assert.Len(t, dead, 1)
+	assert.Equal(t, delivery.ID, dead[0].ID)
+
+	accept.Store(true)
+	redelivery, err := reopened.Redeliver(delivery.ID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		for _, d := range reopened.Deliveries() {
+			if d.ID == redelivery.ID {
+				return d.Status == webhook.StatusDelivered
+			}
+		}
+		return false
+	}, 5*time.Second, 5*time.Millisecond)
+}
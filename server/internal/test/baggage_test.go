@@ -0,0 +1,158 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// baggageFlightsJSON is a j-server1 payload with a bag included, a bag sold in another currency, no baggage data, and
+// a booking priced in USD.
+const baggageFlightsJSON = `[
+	{"bookingId": "A10001", "status": "confirmed", "passengerName": "Marie Curie", "flightNumber": "AF276",
+	 "departureAirport": "CDG", "arrivalAirport": "HND", "departureTime": "2026-01-01T10:00:00Z",
+	 "arrivalTime": "2026-01-01T23:00:00Z", "price": 950, "currency": "EUR",
+	 "baggage": {"carryOn": {"pieces": 1, "weightKg": 12}, "checked": {"pieces": 1, "weightKg": 23}},
+	 "ancillaries": [{"type": "seat", "description": "Extra legroom", "price": 80, "currency": "EUR"}]},
+	{"bookingId": "A10002", "status": "confirmed", "passengerName": "Albert Einstein", "flightNumber": "JL046",
+	 "departureAirport": "CDG", "arrivalAirport": "HND", "departureTime": "2026-01-01T13:00:00Z",
+	 "arrivalTime": "2026-01-02T08:00:00Z", "price": 850, "currency": "EUR",
+	 "baggage": {"carryOn": {"pieces": 1}, "checked": {"pieces": 0}},
+	 "ancillaries": [{"type": "checked_bag", "price": 64.8, "currency": "USD"}, {"type": "bag", "price": 75, "currency": "EUR"}]},
+	{"bookingId": "A10003", "status": "confirmed", "passengerName": "Niels Bohr", "flightNumber": "NH216",
+	 "departureAirport": "CDG", "arrivalAirport": "HND", "departureTime": "2026-01-01T21:00:00Z",
+	 "arrivalTime": "2026-01-02T16:00:00Z", "price": 800, "currency": "EUR"},
+	{"bookingId": "A10004", "status": "confirmed", "passengerName": "Paul Dirac", "flightNumber": "AA8403",
+	 "departureAirport": "CDG", "arrivalAirport": "HND", "departureTime": "2026-01-01T18:00:00Z",
+	 "arrivalTime": "2026-01-02T13:00:00Z", "price": 900, "currency": "USD",
+	 "baggage": {"carryOn": {"pieces": 1}, "checked": {"pieces": 0}},
+	 "ancillaries": [{"type": "checked_bag", "price": 54, "currency": "USD"}]}
+]`
+
+// baggageFlightToBookJSON is a j-server2 payload whose allowance is given per segment, included on one leg only.
+const baggageFlightToBookJSON = `[
+	{"reference": "B30001", "status": "confirmed", "traveler": {"firstName": "Lise", "lastName": "Meitner"},
+	 "segments": [
+	   {"flight": {"number": "LH1035", "from": "CDG", "to": "FRA", "depart": "2026-01-01T07:00:00Z", "arrive": "2026-01-01T08:10:00Z"},
+	    "baggage": {"carryOn": {"pieces": 1, "weightKg": 8}, "checked": {"pieces": 0}}},
+	   {"flight": {"number": "NH204", "from": "FRA", "to": "HND", "depart": "2026-01-01T11:00:00Z", "arrive": "2026-01-02T06:00:00Z"},
+	    "baggage": {"carryOn": {"pieces": 1, "weightKg": 10}, "checked": {"pieces": 2, "weightKg": 23}}}
+	 ],
+	 "total": {"amount": 780, "currency": "EUR"},
+	 "ancillaries": [{"type": "meal", "description": "Vegetarian", "price": {"amount": 15, "currency": "EUR"}},
+	                 {"type": "checkedBag", "price": {"amount": 60, "currency": "EUR"}}]}
+]`
+
+// newBaggageRepos builds both repositories from the baggage payloads.
+func newBaggageRepos(t *testing.T) *repo.Multi {
+	r1, err := repo.NewRepoFlightsFromReader(strings.NewReader(baggageFlightsJSON))
+	assert.NoError(t, err)
+	r2, err := repo.NewRepoFlightToBookFromReader(strings.NewReader(baggageFlightToBookJSON))
+	assert.NoError(t, err)
+	return repo.NewMulti(r1, r2)
+}
+
+// TestBaggage verifies that allowances and ancillaries are parsed and surfaced, and the checked bag filter and price.
+func TestBaggage(t *testing.T) {
+	println("=====================BAGGAGE_UNIT_TEST====================")
+
+	ctx := context.Background()
+	multi := newBaggageRepos(t)
+
+	t.Run("adapters populate baggage and ancillaries", func(t *testing.T) {
+		f, err := multi.FindByID(ctx, "A10001")
+		assert.NoError(t, err)
+		assert.True(t, f.Baggage().Known())
+		assert.Equal(t, domain.NewAllowance(1, 23), f.Baggage().Checked())
+		assert.True(t, f.IncludesCheckedBag())
+		assert.Equal(t, []domain.Ancillary{
+			domain.NewAncillary(domain.AncillarySeat, "Extra legroom", domain.NewTotal(80, "EUR")),
+		}, f.Ancillaries())
+
+		f, err = multi.FindByID(ctx, "B30001")
+		assert.NoError(t, err)
+		assert.False(t, f.Baggage().Known())
+		assert.Equal(t, 2, f.Segments()[1].Baggage().Checked().Pieces())
+		assert.False(t, f.IncludesCheckedBag())
+		assert.Equal(t, domain.AncillaryMeal, f.Ancillaries()[0].Kind())
+		assert.Equal(t, domain.AncillaryCheckedBag, f.Ancillaries()[1].Kind())
+
+		f, err = multi.FindByID(ctx, "A10003")
+		assert.NoError(t, err)
+		assert.False(t, f.Baggage().Known())
+		assert.Empty(t, f.Ancillaries())
+	})
+
+	t.Run("snapshots surface baggage and ancillaries", func(t *testing.T) {
+		all, err := multi.List(ctx)
+		assert.NoError(t, err)
+		for _, f := range all {
+			assert.Equal(t, f, *f.Snapshot().ToDomain(), f.ID())
+		}
+
+		f, err := multi.FindByID(ctx, "A10001")
+		assert.NoError(t, err)
+		snapshot := f.Snapshot()
+		assert.Equal(t, &domain.BaggageSnapshot{
+			CarryOn: domain.AllowanceSnapshot{Pieces: 1, WeightKg: 12},
+			Checked: domain.AllowanceSnapshot{Pieces: 1, WeightKg: 23},
+		}, snapshot.Baggage)
+		assert.Equal(t, domain.AncillaryKind("seat"), snapshot.Ancillaries[0].Type)
+		assert.Nil(t, snapshot.Segments[0].Baggage)
+
+		f, err = multi.FindByID(ctx, "A10003")
+		assert.NoError(t, err)
+		assert.Nil(t, f.Snapshot().Baggage)
+		assert.Nil(t, f.Snapshot().Ancillaries)
+	})
+
+	t.Run("bag fees are added to the comparison price", func(t *testing.T) {
+		// 900 USD plus a 54 USD bag are 883.33 EUR
+		for id, want := range map[string]float64{"A10001": 950, "A10002": 910, "B30001": 840, "A10004": 883.333} {
+			f, err := multi.FindByID(ctx, id)
+			assert.NoError(t, err)
+			price, ok := service.PriceWithCheckedBag(f, service.BaseCurrency)
+			assert.True(t, ok, id)
+			assert.InDelta(t, want, price, 0.001, id)
+		}
+
+		f, err := multi.FindByID(ctx, "A10003")
+		assert.NoError(t, err)
+		_, ok := service.PriceWithCheckedBag(f, service.BaseCurrency)
+		assert.False(t, ok)
+	})
+
+	t.Run("filters and sorts on the checked bag", func(t *testing.T) {
+		all, err := multi.List(ctx)
+		assert.NoError(t, err)
+
+		for _, tc := range []struct {
+			values url.Values
+			want   []string
+		}{
+			{url.Values{"withCheckedBag": {"true"}}, []string{"A10001", "A10002", "A10004", "B30001"}},
+			{url.Values{"withCheckedBag": {"false"}}, []string{"A10001", "A10002", "A10003", "A10004", "B30001"}},
+			{url.Values{"withCheckedBag": {"true"}, "maxPrice": {"900"}}, []string{"A10004", "B30001"}},
+			{url.Values{"withCheckedBag": {"true"}, "maxPrice": {"960"}, "currency": {"USD"}}, []string{"A10004", "B30001"}},
+			{url.Values{"maxPrice": {"900"}}, []string{"A10002", "A10003", "A10004", "B30001"}},
+		} {
+			q, err := service.ParseQuery(tc.values)
+			assert.NoError(t, err, tc.values.Encode())
+			assert.Equal(t, tc.want, flightIDs(q.Apply(all)), tc.values.Encode())
+		}
+
+		_, err = service.ParseQuery(url.Values{"withCheckedBag": {"maybe"}})
+		assert.ErrorIs(t, err, service.ErrInvalidQuery)
+
+		spec, err := service.ParseSort("priceWithBag")
+		assert.NoError(t, err)
+		spec.Sort(all)
+		assert.Equal(t, []string{"B30001", "A10004", "A10002", "A10001", "A10003"}, flightIDs(all))
+	})
+}
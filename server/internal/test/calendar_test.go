@@ -0,0 +1,114 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createCalendarFlights generates CDG-HND journeys in January 2026: two direct flights and a connection on the 1st,
+// one late on the 1st in UTC which is the 2nd in Paris, one in USD on the 3rd, plus one in December and one to JFK.
+func createCalendarFlights() domain.Flights {
+	at := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+	seg := func(number, from, to, depart string) domain.Segment {
+		return newTestSegment(number, from, to, at(depart), 10*time.Hour)
+	}
+
+	return domain.Flights{
+		newTestFlight("A10001", domain.NewTotal(900, "EUR"), seg("AF276", "CDG", "HND", "2026-01-01T10:00:00Z")),
+		newTestFlight("A10002", domain.NewTotal(850, "EUR"), seg("JL046", "CDG", "HND", "2026-01-01T13:00:00Z")),
+		newTestFlight("A10003", domain.NewTotal(700, "EUR"),
+			seg("LH1035", "CDG", "FRA", "2026-01-01T07:00:00Z"), seg("NH204", "FRA", "HND", "2026-01-01T18:00:00Z")),
+		newTestFlight("A10004", domain.NewTotal(650, "EUR"), seg("AF274", "CDG", "HND", "2026-01-01T23:30:00Z")),
+		newTestFlight("A10005", domain.NewTotal(756, "USD"), seg("AF276", "CDG", "HND", "2026-01-03T10:00:00Z")),
+		newTestFlight("A10006", domain.NewTotal(500, "EUR"), seg("AF276", "CDG", "HND", "2025-12-31T10:00:00Z")),
+		newTestFlight("A10007", domain.NewTotal(400, "EUR"), seg("AF006", "CDG", "JFK", "2026-01-01T10:00:00Z")),
+	}
+}
+
+// TestFareCalendar verifies the lowest price per day of a route, across direct and connecting journeys.
+func TestFareCalendar(t *testing.T) {
+	println("=====================CALENDAR_UNIT_TEST====================")
+
+	q, err := service.ParseCalendarQuery("cdg-hnd", url.Values{"month": {"2026-01"}})
+	assert.NoError(t, err)
+
+	cal := service.BuildFareCalendar(createCalendarFlights(), q)
+	assert.Equal(t, "CDG-HND", cal.Route)
+	assert.Equal(t, "2026-01", cal.Month)
+	assert.Equal(t, "EUR", cal.Currency)
+	assert.Len(t, cal.Days, 31)
+	assert.Equal(t, "2026-01-31", cal.Days[30].Date)
+
+	first := cal.Days[0]
+	assert.Equal(t, 3, first.Options)
+	assert.Equal(t, 700.0, *first.Price)
+	assert.Equal(t, "A10003", first.Flight.ID)
+
+	assert.Equal(t, 1, cal.Days[1].Options)
+	assert.Equal(t, "A10004", cal.Days[1].Flight.ID)
+
+	assert.Equal(t, 700.0, *cal.Days[2].Price)
+	assert.Equal(t, "A10005", cal.Days[2].Flight.ID)
+
+	assert.Zero(t, cal.Days[3].Options)
+	assert.Nil(t, cal.Days[3].Price)
+	assert.Nil(t, cal.Days[3].Flight)
+
+	q, err = service.ParseCalendarQuery("CDG-HND", url.Values{"month": {"2026-01"}, "maxStops": {"0"}, "priceCurrency": {"usd"}})
+	assert.NoError(t, err)
+	cal = service.BuildFareCalendar(createCalendarFlights(), q)
+	assert.Equal(t, 2, cal.Days[0].Options)
+	assert.Equal(t, "A10002", cal.Days[0].Flight.ID)
+	assert.Equal(t, 918.0, *cal.Days[0].Price)
+}
+
+// TestParseCalendarQuery verifies the route and month validation.
+func TestParseCalendarQuery(t *testing.T) {
+	for _, tc := range []struct {
+		route  string
+		values url.Values
+	}{
+		{"CDG", url.Values{"month": {"2026-01"}}},
+		{"CDG-HN1", url.Values{"month": {"2026-01"}}},
+		{"CDG-CDG", url.Values{"month": {"2026-01"}}},
+		{"CDG-HND", url.Values{}},
+		{"CDG-HND", url.Values{"month": {"2026-13"}}},
+		{"CDG-HND", url.Values{"month": {"2026-01"}, "priceCurrency": {"XXX"}}},
+	} {
+		_, err := service.ParseCalendarQuery(tc.route, tc.values)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, tc.route+"?"+tc.values.Encode())
+	}
+}
+
+// TestGetFareCalendar verifies that an empty month yields a calendar without options.
+func TestGetFareCalendar(t *testing.T) {
+	r, err := repo.NewRepoFlightsFromReader(strings.NewReader(codeshareFlightsJSON))
+	assert.NoError(t, err)
+
+	q, err := service.ParseCalendarQuery("CDG-HND", url.Values{"month": {"2026-02"}})
+	assert.NoError(t, err)
+	cal, err := service.GetFareCalendar(context.Background(), repo.NewMulti(r), q)
+	assert.NoError(t, err)
+	assert.Len(t, cal.Days, 28)
+	for _, day := range cal.Days {
+		assert.Zero(t, day.Options, day.Date)
+	}
+
+	q, err = service.ParseCalendarQuery("CDG-HND", url.Values{"month": {"2026-01"}})
+	assert.NoError(t, err)
+	cal, err = service.GetFareCalendar(context.Background(), repo.NewMulti(r), q)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cal.Days[0].Options)
+	assert.Equal(t, "A10002", cal.Days[0].Flight.ID)
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/changes"
+	"aggregator/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDiff verifies that added, removed and modified bookings are detected with field-level changes.
+func TestDiff(t *testing.T) {
+	println("=====================CHANGES_UNIT_TEST====================")
+
+	prev := domain.Flights{historyFlight("A10001", 1, 900, "EUR"), historyFlight("A10002", 1, 850, "EUR")}
+	next := domain.Flights{historyFlight("A10003", 2, 700, "EUR"), historyFlight("A10001", 2, 800, "EUR")}
+
+	change := changes.Diff(prev, next)
+	assert.Len(t, change.Added, 1)
+	assert.Equal(t, "A10003", change.Added[0].ID)
+	assert.Equal(t, []changes.BookingRef{{Source: "flights", ID: "A10002"}}, change.Removed)
+
+	assert.Len(t, change.Modified, 1)
+	modified := change.Modified[0]
+	assert.Equal(t, "A10001", modified.ID)
+	assert.Equal(t, []changes.FieldChange{
+		{Field: "segments[0].arrive", Old: "2026-01-01T23:00:00Z", New: "2026-01-02T23:00:00Z"},
+		{Field: "segments[0].depart", Old: "2026-01-01T10:00:00Z", New: "2026-01-02T10:00:00Z"},
+		{Field: "total.amount", Old: 900.0, New: 800.0},
+	}, modified.Fields)
+
+	unchanged := changes.Diff(prev, prev)
+	assert.Empty(t, unchanged.Added)
+	assert.Empty(t, unchanged.Removed)
+	assert.Empty(t, unchanged.Modified)
+}
+
+// TestFeed_Since verifies incremental sync from a version and the eviction of the oldest changes.
+func TestFeed_Since(t *testing.T) {
+	feed := changes.New(2)
+	cat := catalogue.New()
+	cat.Subscribe(feed.Record)
+
+	list, version, err := feed.Since(0)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+	assert.Zero(t, version)
+
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 900, "EUR")})
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 800, "EUR")})
+
+	list, version, err = feed.Since(0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), version)
+	assert.Len(t, list, 2)
+	assert.Equal(t, uint64(0), list[0].PrevVersion)
+	assert.Len(t, list[0].Added, 1)
+	assert.Len(t, list[1].Modified, 1)
+
+	list, _, err = feed.Since(2)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	cat.Update(domain.Flights{})
+	list, version, err = feed.Since(1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), version)
+	assert.Equal(t, []uint64{2, 3}, []uint64{list[0].Version, list[1].Version})
+	assert.Len(t, list[1].Removed, 1)
+
+	_, _, err = feed.Since(0)
+	assert.ErrorIs(t, err, changes.ErrVersionGone)
+
+	// a version issued before a restart may be ahead of the current one
+	_, _, err = feed.Since(4)
+	assert.ErrorIs(t, err, changes.ErrVersionGone)
+	assert.NotEmpty(t, feed.Epoch())
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// codeshareFlightsJSON is a j-server1 payload with one booking sold by KLM on an Air France flight.
+const codeshareFlightsJSON = `[
+	{"bookingId": "A10001", "status": "confirmed", "passengerName": "Marie Curie", "flightNumber": "KL2276",
+	 "departureAirport": "CDG", "arrivalAirport": "HND", "departureTime": "2026-01-01T10:00:00Z",
+	 "arrivalTime": "2026-01-01T23:00:00Z", "price": 930, "currency": "EUR",
+	 "operatingCarrier": "AF", "operatingFlightNumber": "AF276"},
+	{"bookingId": "A10002", "status": "confirmed", "passengerName": "Albert Einstein", "flightNumber": "JL046",
+	 "departureAirport": "CDG", "arrivalAirport": "HND", "departureTime": "2026-01-01T13:00:00Z",
+	 "arrivalTime": "2026-01-02T08:00:00Z", "price": 850, "currency": "EUR"}
+]`
+
+// codeshareFlightToBookJSON is a j-server2 payload with the same trip booked under the Air France number.
+const codeshareFlightToBookJSON = `[
+	{"reference": "B30001", "status": "confirmed", "traveler": {"firstName": "Marie", "lastName": "Curie"},
+	 "segments": [{"flight": {"number": "AF 0276", "from": "CDG", "to": "HND",
+	   "depart": "2026-01-01T10:00:00Z", "arrive": "2026-01-01T23:00:00Z"}}],
+	 "total": {"amount": 950, "currency": "EUR"}},
+	{"reference": "B30002", "status": "confirmed", "traveler": {"firstName": "Niels", "lastName": "Bohr"},
+	 "segments": [{"flight": {"number": "DL8411", "from": "CDG", "to": "HND",
+	   "depart": "2026-01-01T10:00:00Z", "arrive": "2026-01-01T23:00:00Z", "operatedBy": {"number": "AF276"}}}],
+	 "total": {"amount": 990, "currency": "EUR"}}
+]`
+
+// newCodeshareRepos builds both repositories from the codeshare payloads.
+func newCodeshareRepos(t *testing.T) *repo.Multi {
+	r1, err := repo.NewRepoFlightsFromReader(strings.NewReader(codeshareFlightsJSON))
+	assert.NoError(t, err)
+	r2, err := repo.NewRepoFlightToBookFromReader(strings.NewReader(codeshareFlightToBookJSON))
+	assert.NoError(t, err)
+	return repo.NewMulti(r1, r2)
+}
+
+// TestCodeshare verifies that operating carriers and numbers are parsed, searchable and used to identify physical flights.
+func TestCodeshare(t *testing.T) {
+	println("=====================CODESHARE_UNIT_TEST====================")
+
+	ctx := context.Background()
+	multi := newCodeshareRepos(t)
+
+	t.Run("adapters populate operating fields", func(t *testing.T) {
+		f, err := multi.FindByID(ctx, "A10001")
+		assert.NoError(t, err)
+		seg := f.Segments()[0]
+		assert.Equal(t, "KL", seg.Carrier())
+		assert.Equal(t, "AF", seg.OperatingCarrier())
+		assert.Equal(t, "AF276", seg.OperatingFlightNumber())
+		assert.True(t, seg.IsCodeshare())
+
+		snapshot := seg.Snapshot()
+		assert.Equal(t, "AF", snapshot.OperatingCarrier)
+		assert.Equal(t, "AF276", snapshot.OperatingFlightNumber)
+		assert.Equal(t, seg, snapshot.ToDomain())
+
+		f, err = multi.FindByID(ctx, "B30002")
+		assert.NoError(t, err)
+		assert.Equal(t, "AF", f.Segments()[0].OperatingCarrier())
+
+		f, err = multi.FindByID(ctx, "A10002")
+		assert.NoError(t, err)
+		assert.False(t, f.Segments()[0].IsCodeshare())
+		assert.Empty(t, f.Segments()[0].Snapshot().OperatingFlightNumber)
+	})
+
+	t.Run("flight numbers match marketing or operating numbers", func(t *testing.T) {
+		flights, err := multi.FindAllByNumber(ctx, "AF276")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A10001", "B30001", "B30002"}, flightIDs(flights))
+
+		f, err := multi.FindByNumber(ctx, "KL 2276")
+		assert.NoError(t, err)
+		assert.Equal(t, "A10001", f.ID())
+
+		q, err := service.ParseQuery(url.Values{"carrier": {"AF"}})
+		assert.NoError(t, err)
+		all, err := multi.List(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A10001", "B30001", "B30002"}, flightIDs(q.Apply(all)))
+	})
+
+	t.Run("codeshares are the same physical flight", func(t *testing.T) {
+		all, err := multi.List(ctx)
+		assert.NoError(t, err)
+		keys := make(map[string][]string)
+		for _, f := range all {
+			key := service.PhysicalFlightKey(f.Segments()[0])
+			keys[key] = append(keys[key], f.ID())
+		}
+		assert.Equal(t, []string{"A10001", "B30001", "B30002"}, keys["AF276|CDG|2026-01-01"])
+
+		merged := service.MergeDuplicates(all, service.MergeOptions{Policy: service.PolicyCheapest})
+		assert.Len(t, merged, 3)
+		assert.Equal(t, "A10001", merged[0].Flight().ID())
+		assert.Len(t, merged[0].Providers(), 2)
+	})
+}
+
+// TestSegment_WithOperating verifies the operating carrier fallbacks.
+func TestSegment_WithOperating(t *testing.T) {
+	now := time.Now()
+	seg := domain.NewSegment("KL2276", "CDG", "HND", now, now)
+
+	assert.Equal(t, "KL", seg.OperatingCarrier())
+	assert.Equal(t, "KL2276", seg.OperatingFlightNumber())
+	assert.Equal(t, "AF", seg.WithOperating("AFR", "").OperatingCarrier())
+	assert.Equal(t, "KL2276", seg.WithOperating("AFR", "").OperatingFlightNumber())
+	assert.True(t, seg.WithOperating("AF", "").IsCodeshare())
+	assert.False(t, seg.WithOperating("KL", "KL 2276").IsCodeshare())
+}
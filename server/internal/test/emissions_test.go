@@ -0,0 +1,115 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"aggregator/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createEmissionFlights generates a direct long-haul flight in economy and in business, a connection,
+// a domestic flight and one through an unknown airport.
+func createEmissionFlights() domain.Flights {
+	seg := func(number, from, to, cabin string) domain.Segment {
+		return newTestSegment(number, from, to, testDay, 8*time.Hour).WithFare(cabin, "", "")
+	}
+	total := domain.NewTotal(900.00, "EUR")
+
+	return domain.Flights{
+		newTestFlight("A10001", total, seg("AF006", "CDG", "JFK", "economy")),
+		newTestFlight("A10002", total, seg("AF006", "CDG", "JFK", "business")),
+		newTestFlight("A10003", total, seg("LH1035", "CDG", "FRA", "economy"), seg("LH400", "FRA", "JFK", "economy")),
+		newTestFlight("A10004", total, seg("AA001", "JFK", "LAX", "")),
+		newTestFlight("A10005", total, seg("XX123", "CDG", "XXX", "economy")),
+	}
+}
+
+// TestEmissions verifies great-circle distances, the emission factor table, snapshots and the co2 sort.
+func TestEmissions(t *testing.T) {
+	println("=====================EMISSIONS_UNIT_TEST====================")
+
+	t.Run("great-circle distances", func(t *testing.T) {
+		km, ok := reference.GreatCircleKm("cdg", "JFK")
+		assert.True(t, ok)
+		assert.InDelta(t, 5834, km, 10)
+
+		km, ok = reference.GreatCircleKm("LHR", "SIN")
+		assert.True(t, ok)
+		assert.InDelta(t, 10880, km, 15)
+
+		_, ok = reference.GreatCircleKm("CDG", "XXX")
+		assert.False(t, ok)
+	})
+
+	t.Run("hauls and factors", func(t *testing.T) {
+		for _, tc := range []struct {
+			from, to string
+			want     reference.Haul
+		}{
+			{"JFK", "LAX", reference.HaulDomestic},
+			{"CDG", "FRA", reference.HaulShort},
+			{"CDG", "JFK", reference.HaulLong},
+		} {
+			km, _ := reference.GreatCircleKm(tc.from, tc.to)
+			haul, ok := reference.FlightHaul(tc.from, tc.to, km)
+			assert.True(t, ok)
+			assert.Equal(t, tc.want, haul, tc.from+"-"+tc.to)
+		}
+
+		assert.Equal(t, 0.20011, reference.EmissionFactor(reference.HaulLong, "economy"))
+		assert.Greater(t, reference.EmissionFactor(reference.HaulLong, "business"), reference.EmissionFactor(reference.HaulLong, "economy"))
+		assert.Equal(t, reference.EmissionFactor(reference.HaulLong, ""), reference.EmissionFactor(reference.HaulLong, "unknown"))
+	})
+
+	t.Run("itinerary distance and emissions", func(t *testing.T) {
+		flights := createEmissionFlights()
+
+		km, ok := flights[0].DistanceKm()
+		assert.True(t, ok)
+		co2, ok := flights[0].CO2Kg()
+		assert.True(t, ok)
+		assert.InDelta(t, km*reference.DistanceUplift*reference.EmissionFactor(reference.HaulLong, "economy"), co2, 0.001)
+
+		business, _ := flights[1].CO2Kg()
+		assert.Greater(t, business, co2)
+
+		connection, ok := flights[2].DistanceKm()
+		assert.True(t, ok)
+		assert.Greater(t, connection, km)
+
+		_, ok = flights[4].DistanceKm()
+		assert.False(t, ok)
+		_, ok = flights[4].CO2Kg()
+		assert.False(t, ok)
+	})
+
+	t.Run("snapshots surface distance and emissions", func(t *testing.T) {
+		flights := createEmissionFlights()
+
+		snapshot := flights[2].Snapshot()
+		assert.InDelta(t, snapshot.Segments[0].DistanceKm+snapshot.Segments[1].DistanceKm, snapshot.DistanceKm, 1)
+		assert.InDelta(t, snapshot.Segments[0].CO2Kg+snapshot.Segments[1].CO2Kg, snapshot.CO2Kg, 0.2)
+		assert.Equal(t, flights[2], *snapshot.ToDomain())
+
+		snapshot = flights[4].Snapshot()
+		assert.Zero(t, snapshot.DistanceKm)
+		assert.Zero(t, snapshot.CO2Kg)
+	})
+
+	t.Run("sorts on emissions", func(t *testing.T) {
+		flights := createEmissionFlights()
+		spec, err := service.ParseSort("co2")
+		assert.NoError(t, err)
+		spec.Sort(flights)
+		assert.Equal(t, []string{"A10004", "A10001", "A10003", "A10002", "A10005"}, flightIDs(flights))
+
+		spec, err = service.ParseSort("distance:desc")
+		assert.NoError(t, err)
+		spec.Sort(flights)
+		assert.Equal(t, "A10003", flights[0].ID())
+		assert.Equal(t, "A10005", flights[len(flights)-1].ID())
+	})
+}
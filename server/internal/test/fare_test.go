@@ -0,0 +1,98 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createFareFlights generates an economy booking, a business booking, a mixed-cabin connection and one without fare data.
+func createFareFlights() domain.Flights {
+	seg := newTestSegment("AF276", "CDG", "HND", testDay, 13*time.Hour)
+	total := domain.NewTotal(900.00, "EUR")
+
+	return domain.Flights{
+		newTestFlight("A10001", total, seg.WithFare("economy", "y", "yowfr")),
+		newTestFlight("A10002", total, seg.WithFare("Business", "J", "JOWFR")),
+		newTestFlight("A10003", total, seg.WithFare("business", "J", "JOWFR"),
+			newTestSegment("KE902", "CDG", "HND", testDay, 13*time.Hour).WithFare("premium economy", "W", "WOWKR")),
+		newTestFlight("A10004", total, seg),
+	}
+}
+
+// TestFare verifies cabin parsing, fare fields in adapters and snapshots, and the fare filters.
+func TestFare(t *testing.T) {
+	println("=====================FARE_UNIT_TEST====================")
+
+	t.Run("parses cabins", func(t *testing.T) {
+		for in, want := range map[string]domain.Cabin{
+			"Economy":         domain.CabinEconomy,
+			"premium_economy": domain.CabinPremium,
+			"Business":        domain.CabinBusiness,
+			"FIRST":           domain.CabinFirst,
+		} {
+			cabin, ok := domain.ParseCabin(in)
+			assert.True(t, ok, in)
+			assert.Equal(t, want, cabin, in)
+		}
+		for _, in := range []string{"cargo", "Y", "j", "c"} {
+			_, ok := domain.ParseCabin(in)
+			assert.False(t, ok, in)
+		}
+	})
+
+	t.Run("adapters and snapshots carry the fare", func(t *testing.T) {
+		r, err := repo.NewRepoFlightToBookFromReader(strings.NewReader(`[
+			{"reference": "B30001", "status": "confirmed", "traveler": {"firstName": "Marie", "lastName": "Curie"},
+			 "segments": [{"flight": {"number": "AF276", "from": "CDG", "to": "HND",
+			   "depart": "2026-01-01T10:00:00Z", "arrive": "2026-01-01T23:00:00Z",
+			   "cabin": "business", "bookingClass": "j", "fareBasis": "JOWFR"}}],
+			 "total": {"amount": 3950, "currency": "EUR"}}
+		]`))
+		assert.NoError(t, err)
+		f, err := r.FindById(context.Background(), "B30001")
+		assert.NoError(t, err)
+
+		seg := f.Segments()[0]
+		assert.Equal(t, domain.CabinBusiness, seg.Cabin())
+		assert.Equal(t, "J", seg.BookingClass())
+		assert.Equal(t, "JOWFR", seg.FareBasis())
+
+		snapshot := seg.Snapshot()
+		assert.Equal(t, domain.CabinBusiness, snapshot.Cabin)
+		assert.Equal(t, seg, snapshot.ToDomain())
+
+		assert.Empty(t, createFareFlights()[3].Segments()[0].Snapshot().Cabin)
+	})
+
+	t.Run("filters on cabin, booking class and fare basis", func(t *testing.T) {
+		for _, tc := range []struct {
+			values url.Values
+			want   []string
+		}{
+			{url.Values{"cabin": {"business"}}, []string{"A10002"}},
+			{url.Values{"cabin": {"Economy"}}, []string{"A10001"}},
+			{url.Values{"bookingClass": {"w"}}, []string{"A10003"}},
+			{url.Values{"fareBasis": {"jowfr"}}, []string{"A10002", "A10003"}},
+			{url.Values{"cabin": {"economy"}, "fareBasis": {"JOWFR"}}, nil},
+			{url.Values{"bookingClass": {"W"}, "fareBasis": {"WOWKR"}}, []string{"A10003"}},
+			{url.Values{"bookingClass": {"J"}, "fareBasis": {"WOWKR"}}, nil},
+		} {
+			q, err := service.ParseQuery(tc.values)
+			assert.NoError(t, err, tc.values.Encode())
+			assert.Equal(t, tc.want, flightIDs(q.Apply(createFareFlights())), tc.values.Encode())
+		}
+
+		for _, cabin := range []string{"cargo", "Y"} {
+			_, err := service.ParseQuery(url.Values{"cabin": {cabin}})
+			assert.ErrorIs(t, err, service.ErrInvalidQuery, cabin)
+		}
+	})
+}
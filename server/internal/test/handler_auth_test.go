@@ -0,0 +1,58 @@
+package test
+
+import (
+	"aggregator/internal/config"
+	"aggregator/internal/handler"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// authStatus returns the status of a request sent to a handler with the given bearer token, none when empty.
+func authStatus(h http.HandlerFunc, method, path, token string) int {
+	r := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w.Code
+}
+
+// TestHandleAlerts_Token verifies that every alert request, reads included, requires the API_TOKEN.
+func TestHandleAlerts_Token(t *testing.T) {
+	println("=====================HANDLER_AUTH_UNIT_TEST====================")
+	defer func(token string) { config.API_TOKEN = token }(config.API_TOKEN)
+
+	config.API_TOKEN = ""
+	assert.Equal(t, http.StatusForbidden, authStatus(handler.HandleAlerts, http.MethodGet, "/alerts", "s3cret"))
+
+	config.API_TOKEN = "s3cret"
+	for _, path := range []string{"/alerts", "/alerts/dead-letters"} {
+		assert.Equal(t, http.StatusUnauthorized, authStatus(handler.HandleAlerts, http.MethodGet, path, ""), path)
+		assert.Equal(t, http.StatusUnauthorized, authStatus(handler.HandleAlerts, http.MethodGet, path, "wrong"), path)
+		assert.Equal(t, http.StatusOK, authStatus(handler.HandleAlerts, http.MethodGet, path, "s3cret"), path)
+	}
+	assert.Equal(t, http.StatusUnauthorized, authStatus(handler.HandleAlerts, http.MethodDelete, "/alerts/unknown", ""))
+	assert.Equal(t, http.StatusNotFound, authStatus(handler.HandleAlerts, http.MethodGet, "/alerts/unknown", "s3cret"))
+}
+
+// TestHandleWebhooks_Token verifies that every webhook request, reads and redelivery included, requires the API_TOKEN.
+func TestHandleWebhooks_Token(t *testing.T) {
+	defer func(token string) { config.API_TOKEN = token }(config.API_TOKEN)
+
+	config.API_TOKEN = ""
+	assert.Equal(t, http.StatusForbidden, authStatus(handler.HandleWebhooks, http.MethodGet, "/webhooks", "s3cret"))
+
+	config.API_TOKEN = "s3cret"
+	for _, path := range []string{"/webhooks", "/webhooks/deliveries"} {
+		assert.Equal(t, http.StatusUnauthorized, authStatus(handler.HandleWebhooks, http.MethodGet, path, ""), path)
+		assert.Equal(t, http.StatusUnauthorized, authStatus(handler.HandleWebhooks, http.MethodGet, path, "wrong"), path)
+		assert.Equal(t, http.StatusOK, authStatus(handler.HandleWebhooks, http.MethodGet, path, "s3cret"), path)
+	}
+	redeliver := "/webhooks/deliveries/unknown/redeliver"
+	assert.Equal(t, http.StatusUnauthorized, authStatus(handler.HandleWebhooks, http.MethodPost, redeliver, ""))
+	assert.Equal(t, http.StatusNotFound, authStatus(handler.HandleWebhooks, http.MethodPost, redeliver, "s3cret"))
+}
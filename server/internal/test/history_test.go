@@ -0,0 +1,197 @@
+package test
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/history"
+	"aggregator/internal/service"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// historyFlight creates a CDG-HND booking departing on the given day of January 2026 at the given price.
+func historyFlight(id string, day int, amount float64, currency string) domain.Flight {
+	depart := time.Date(2026, 1, day, 10, 0, 0, 0, time.UTC)
+	segs := []domain.Segment{domain.NewSegment("AF276", "CDG", "HND", depart, depart.Add(13*time.Hour))}
+	return *domain.NewFlight(id, "confirmed", "Marie Curie", segs, domain.NewTotal(amount, currency), "flights")
+}
+
+// TestHistory_Record verifies that only new, changed and removed bookings are recorded, and that the file survives a restart.
+func TestHistory_Record(t *testing.T) {
+	println("=====================HISTORY_UNIT_TEST====================")
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, err := history.Open(path, 0)
+	assert.NoError(t, err)
+
+	cat := catalogue.New()
+	cat.Subscribe(store.Record)
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 900, "EUR"), historyFlight("A10002", 1, 850, "EUR")})
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 800, "EUR"), historyFlight("A10002", 1, 850, "EUR")})
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 800, "EUR")})
+
+	entries := store.ByID("A10001")
+	assert.Len(t, entries, 2)
+	assert.Equal(t, []float64{900, 800}, []float64{entries[0].Amount, entries[1].Amount})
+	assert.Equal(t, []uint64{1, 2}, []uint64{entries[0].Version, entries[1].Version})
+	assert.Equal(t, "CDG-HND", entries[0].Route)
+	assert.Equal(t, "2026-01-01", entries[0].Date)
+
+	entries = store.ByID("A10002")
+	assert.Len(t, entries, 2)
+	assert.True(t, entries[1].Removed)
+	assert.NoError(t, store.Close())
+
+	// a truncated line is skipped, and unchanged prices are not recorded again after a restart
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"recordedAt": "2026-`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	store, err = history.Open(path, 0)
+	assert.NoError(t, err)
+	assert.Len(t, store.ByRoute("CDG-HND"), 4)
+
+	cat = catalogue.New()
+	cat.Subscribe(store.Record)
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 800, "EUR"), historyFlight("A10002", 1, 870, "EUR")})
+	assert.Len(t, store.ByID("A10001"), 2)
+	assert.Len(t, store.ByID("A10002"), 3)
+	assert.NoError(t, store.Close())
+
+	store, err = history.Open(path, 0)
+	assert.NoError(t, err)
+	defer store.Close()
+	assert.Len(t, store.ByRoute("CDG-HND"), 5)
+}
+
+// TestHistory_Retention verifies that entries older than the retention are dropped from memory and from the file,
+// except the current price of a booking still offered.
+func TestHistory_Retention(t *testing.T) {
+	old, recent := time.Now().AddDate(0, 0, -60), time.Now().Add(-time.Hour)
+	entry := func(id string, at time.Time, amount float64, removed bool) history.Entry {
+		return history.Entry{RecordedAt: at, Version: 1, Source: "flights", ID: id, Route: "CDG-HND", Date: "2026-01-01",
+			Amount: amount, Currency: "EUR", Removed: removed}
+	}
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	enc := json.NewEncoder(f)
+	for _, e := range []history.Entry{
+		entry("A10001", old, 900, false),
+		entry("A10002", old, 850, false),
+		entry("A10001", old.Add(time.Hour), 800, false),
+		entry("A10002", old.Add(time.Hour), 850, true),
+		entry("A10003", recent, 700, false),
+	} {
+		assert.NoError(t, enc.Encode(e))
+	}
+	assert.NoError(t, f.Close())
+
+	store, err := history.Open(path, 30*24*time.Hour)
+	assert.NoError(t, err)
+	entries := store.ByID("A10001")
+	assert.Len(t, entries, 1)
+	assert.Equal(t, 800.0, entries[0].Amount)
+	assert.Empty(t, store.ByID("A10002"))
+	assert.Len(t, store.ByRoute("CDG-HND"), 2)
+
+	// the current price of A10001 is still known, while A10002 is recorded as new
+	cat := catalogue.New()
+	cat.Subscribe(store.Record)
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 800, "EUR"), historyFlight("A10002", 1, 850, "EUR"),
+		historyFlight("A10003", 1, 700, "EUR")})
+	assert.Len(t, store.ByID("A10001"), 1)
+	assert.Len(t, store.ByID("A10002"), 1)
+	assert.NoError(t, store.Close())
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(b), "\n"))
+}
+
+// TestBuildFlightHistory verifies the price change of a booking.
+func TestBuildFlightHistory(t *testing.T) {
+	h := service.BuildFlightHistory("A10001", []history.Entry{
+		{Source: "flights", ID: "A10001", Amount: 900, Currency: "EUR"},
+		{Source: "flights", ID: "A10001", Amount: 820.5, Currency: "EUR"},
+		{Source: "flights", ID: "A10001", Amount: 820.5, Currency: "EUR", Removed: true},
+	})
+	assert.Equal(t, -79.5, *h.Change)
+
+	h = service.BuildFlightHistory("A10001", []history.Entry{{Source: "flights", ID: "A10001", Amount: 900, Currency: "EUR"}})
+	assert.Nil(t, h.Change)
+
+	h = service.BuildFlightHistory("A10001", nil)
+	assert.NotNil(t, h.Entries)
+}
+
+// TestBuildRouteHistory verifies the lowest price of a route per departure date across versions.
+func TestBuildRouteHistory(t *testing.T) {
+	store := history.New(0)
+	cat := catalogue.New()
+	cat.Subscribe(store.Record)
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 900, "EUR"), historyFlight("A10002", 1, 972, "USD")})
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 950, "EUR"), historyFlight("A10002", 1, 972, "USD"), historyFlight("A10003", 2, 700, "EUR")})
+	cat.Update(domain.Flights{historyFlight("A10001", 2, 950, "EUR"), historyFlight("A10003", 2, 700, "EUR")})
+
+	q, err := service.ParseRouteHistoryQuery("cdg-hnd", url.Values{})
+	assert.NoError(t, err)
+	h := service.BuildRouteHistory(store.ByRoute(q.Route), q)
+	assert.Equal(t, "CDG-HND", h.Route)
+	assert.Len(t, h.Days, 2)
+
+	first := h.Days[0]
+	assert.Equal(t, "2026-01-01", first.Date)
+	assert.Len(t, first.Points, 3)
+	assert.Equal(t, 900.0, *first.Points[0].LowestPrice)
+	assert.Equal(t, 2, first.Points[0].Options)
+	assert.Equal(t, 900.0, *first.Points[1].LowestPrice)
+	assert.Equal(t, "A10002", first.Points[1].ID)
+	assert.Zero(t, first.Points[2].Options)
+	assert.Nil(t, first.Points[2].LowestPrice)
+
+	second := h.Days[1]
+	assert.Len(t, second.Points, 2)
+	assert.Equal(t, []int{1, 2}, []int{second.Points[0].Options, second.Points[1].Options})
+	assert.Equal(t, "A10003", second.Points[1].ID)
+
+	q, err = service.ParseRouteHistoryQuery("CDG-HND", url.Values{"date": {"2026-01-02"}, "priceCurrency": {"USD"}})
+	assert.NoError(t, err)
+	h = service.BuildRouteHistory(store.ByRoute(q.Route), q)
+	assert.Len(t, h.Days, 1)
+	assert.Equal(t, 756.0, *h.Days[0].Points[0].LowestPrice)
+
+	for _, values := range []url.Values{{"date": {"2026-1-2"}}, {"priceCurrency": {"XXX"}}} {
+		_, err = service.ParseRouteHistoryQuery("CDG-HND", values)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, values.Encode())
+	}
+	_, err = service.ParseRouteHistoryQuery("CDGHND", url.Values{})
+	assert.ErrorIs(t, err, service.ErrInvalidQuery)
+}
+
+// TestBuildRouteHistory_Restart verifies that refreshes are told apart by their time, as versions restart with the process.
+func TestBuildRouteHistory_Restart(t *testing.T) {
+	before, after := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	entries := []history.Entry{
+		{RecordedAt: before, Version: 1, Source: "flights", ID: "A10001", Route: "CDG-HND", Date: "2026-01-01", Amount: 900, Currency: "EUR"},
+		{RecordedAt: after, Version: 1, Source: "flights", ID: "A10001", Route: "CDG-HND", Date: "2026-01-01", Amount: 800, Currency: "EUR"},
+	}
+
+	q, err := service.ParseRouteHistoryQuery("CDG-HND", url.Values{})
+	assert.NoError(t, err)
+	h := service.BuildRouteHistory(entries, q)
+
+	assert.Len(t, h.Days, 1)
+	assert.Len(t, h.Days[0].Points, 2)
+	assert.Equal(t, []float64{900, 800}, []float64{*h.Days[0].Points[0].LowestPrice, *h.Days[0].Points[1].LowestPrice})
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/changes"
+	"aggregator/internal/domain"
+	"aggregator/internal/hooks"
+	"aggregator/internal/webhook"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseEndpoint verifies the validation of webhook endpoints and their events.
+func TestParseEndpoint(t *testing.T) {
+	println("=====================HOOKS_UNIT_TEST====================")
+
+	e, err := hooks.ParseEndpoint(hooks.Input{URL: "https://crm.example/hooks", Events: []string{"Booking.Status_Changed", "booking.status_changed"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{hooks.EventStatusChanged}, e.Events)
+	assert.True(t, e.Wants(hooks.EventStatusChanged))
+	assert.False(t, e.Wants(hooks.EventPriceChanged))
+
+	e, err = hooks.ParseEndpoint(hooks.Input{URL: "http://crm.example"})
+	assert.NoError(t, err)
+	assert.True(t, e.Wants(hooks.EventPriceChanged))
+
+	for _, invalid := range []hooks.Input{
+		{URL: "ftp://crm.example"},
+		{URL: "https://crm.example", Events: []string{"booking.created"}},
+		{URL: "http://localhost:3001/webhooks"},
+		{URL: "http://10.0.0.5/hooks"},
+	} {
+		_, err = hooks.ParseEndpoint(invalid)
+		assert.ErrorIs(t, err, hooks.ErrInvalidEndpoint, invalid)
+	}
+}
+
+// TestClassify verifies that changed fields are sorted into status, schedule and price events.
+func TestClassify(t *testing.T) {
+	prev := historyFlight("A10001", 1, 900, "EUR")
+	next := *domain.NewFlight("A10001", "cancelled", "Marie Curie", historyFlight("A10001", 2, 0, "EUR").Segments(),
+		domain.NewTotal(900, "EUR"), "flights")
+
+	change := changes.Diff(domain.Flights{prev}, domain.Flights{next})
+	events := hooks.Classify(change.Modified[0])
+	assert.Len(t, events, 2)
+	assert.Equal(t, []changes.FieldChange{{Field: "status", Old: "confirmed", New: "cancelled"}}, events[hooks.EventStatusChanged])
+	assert.Len(t, events[hooks.EventScheduleChanged], 2)
+	assert.NotContains(t, events, hooks.EventPriceChanged)
+}
+
+// TestHooks_Record verifies that endpoints only receive the events they subscribed to, that a delivery can be sent again,
+// and that a booking leaving the catalogue sends a removal event.
+func TestHooks_Record(t *testing.T) {
+	// the receivers of the tests listen on the loopback interface
+	webhook.AllowPrivateNetworks(true)
+	defer webhook.AllowPrivateNetworks(false)
+	received := make(chan hooks.Event, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e hooks.Event
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		received <- e
+	}))
+	defer receiver.Close()
+
+	d := webhook.New(webhook.Options{})
+	store := hooks.New(d)
+	prices, err := store.Create(hooks.Endpoint{URL: receiver.URL, Events: []string{hooks.EventPriceChanged}})
+	assert.NoError(t, err)
+	_, err = store.Create(hooks.Endpoint{URL: receiver.URL, Events: []string{hooks.EventStatusChanged}})
+	assert.NoError(t, err)
+
+	feed := changes.New(10)
+	feed.Subscribe(store.Record)
+	cat := catalogue.New()
+	cat.Subscribe(feed.Record)
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 900, "EUR")})
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 800, "EUR")})
+
+	select {
+	case e := <-received:
+		assert.Equal(t, hooks.EventPriceChanged, e.Event)
+		assert.Equal(t, "A10001", e.ID)
+		assert.Equal(t, []changes.FieldChange{{Field: "total.amount", Old: 900.0, New: 800.0}}, e.Changes)
+		assert.Equal(t, uint64(2), e.Version)
+	case <-time.After(5 * time.Second):
+		t.Fatal("price change not delivered")
+	}
+
+	delivery := waitDelivery(t, d)
+	assert.Equal(t, prices.ID, delivery.Owner)
+	again, err := d.Redeliver(delivery.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, delivery.ID, again.RedeliveryOf)
+	select {
+	case e := <-received:
+		assert.Equal(t, hooks.EventPriceChanged, e.Event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("redelivery not sent")
+	}
+
+	_, err = d.Redeliver("unknown")
+	assert.ErrorIs(t, err, webhook.ErrDeliveryNotFound)
+
+	_, err = store.Create(hooks.Endpoint{URL: receiver.URL, Events: []string{hooks.EventRemoved}})
+	assert.NoError(t, err)
+	cat.Update(domain.Flights{historyFlight("A10002", 1, 800, "EUR")})
+	select {
+	case e := <-received:
+		assert.Equal(t, hooks.EventRemoved, e.Event)
+		assert.Equal(t, "A10001", e.ID)
+		assert.Empty(t, e.Changes)
+		assert.Nil(t, e.Flight)
+		assert.Equal(t, uint64(3), e.Version)
+	case <-time.After(5 * time.Second):
+		t.Fatal("removal not delivered")
+	}
+}
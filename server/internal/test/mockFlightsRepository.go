@@ -3,6 +3,7 @@ package test
 import (
 	"aggregator/internal/domain"
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -33,6 +34,15 @@ func (m *MockFlightsRepository) FindByNumber(ctx context.Context, number string)
 	return args.Get(0).(domain.Flight), args.Error(1)
 }
 
+// FindAllByNumber retrieves every flight with a segment on the given flight number. Returns matching flights or an error.
+func (m *MockFlightsRepository) FindAllByNumber(ctx context.Context, number string) (domain.Flights, error) {
+	args := m.Called(ctx, number)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(domain.Flights), args.Error(1)
+}
+
 // FindByPassenger retrieves flights associated with a specific passenger by their name. Returns flights or an error.
 func (m *MockFlightsRepository) FindByPassenger(ctx context.Context, passengerName string) (domain.Flights, error) {
 	args := m.Called(ctx, passengerName)
@@ -59,3 +69,22 @@ func (m *MockFlightsRepository) FindByPrice(ctx context.Context, price float64)
 	}
 	return args.Get(0).(domain.Flights), args.Error(1)
 }
+
+// FindByPriceRange retrieves flights whose total is in the currency and whose amount lies between min and max.
+// Returns matching flights or an error.
+func (m *MockFlightsRepository) FindByPriceRange(ctx context.Context, currency string, min, max float64) (domain.Flights, error) {
+	args := m.Called(ctx, currency, min, max)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(domain.Flights), args.Error(1)
+}
+
+// FindByDepartureRange retrieves flights first departing between after and before. Returns matching flights or an error.
+func (m *MockFlightsRepository) FindByDepartureRange(ctx context.Context, after, before time.Time) (domain.Flights, error) {
+	args := m.Called(ctx, after, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(domain.Flights), args.Error(1)
+}
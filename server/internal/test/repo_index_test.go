@@ -0,0 +1,211 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// indexAirports are the airports generated bookings fly between.
+var indexAirports = []string{"CDG", "HND", "JFK", "LAX", "FRA", "SIN", "DXB", "AMS"}
+
+// generateFlightsJSON returns n bookings in the j-server1 "flights" payload format.
+// Every 7th passenger name is spelled with accents and swapped words, and prices repeat every 1000 bookings.
+func generateFlightsJSON(n int) []byte {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	flights := make([]map[string]any, n)
+	for i := range flights {
+		name := fmt.Sprintf("Passenger %d", i%5000)
+		if i%7 == 0 {
+			name = fmt.Sprintf("%d PASSÉNGER", i%5000)
+		}
+		depart := start.Add(time.Duration(i) * 17 * time.Minute)
+		flights[i] = map[string]any{
+			"bookingId":        fmt.Sprintf("A%06d", i),
+			"status":           "confirmed",
+			"passengerName":    name,
+			"flightNumber":     fmt.Sprintf("AF%d", i%2000),
+			"departureAirport": indexAirports[i%len(indexAirports)],
+			"arrivalAirport":   indexAirports[(i/len(indexAirports)+i+1)%len(indexAirports)],
+			"departureTime":    depart.Format(time.RFC3339),
+			"arrivalTime":      depart.Add(3 * time.Hour).Format(time.RFC3339),
+			"price":            float64(100 + i%1000),
+			"currency":         "EUR",
+		}
+	}
+	b, _ := json.Marshal(flights)
+	return b
+}
+
+// newIndexedRepo builds a RepoFlights over n generated bookings.
+func newIndexedRepo(tb testing.TB, n int) *repo.RepoFlights {
+	r, err := repo.NewRepoFlightsFromReader(bytes.NewReader(generateFlightsJSON(n)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return r
+}
+
+// scan returns the flights matching a predicate, as the repositories did before they were indexed.
+func scan(flights domain.Flights, match func(domain.Flight) bool) domain.Flights {
+	var out domain.Flights
+	for _, f := range flights {
+		if match(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// TestRepoIndexes verifies that indexed lookups return what a linear scan returns.
+func TestRepoIndexes(t *testing.T) {
+	println("=====================REPO_INDEX_UNIT_TEST====================")
+
+	ctx := context.Background()
+	r := newIndexedRepo(t, 3000)
+	all, err := r.List(ctx)
+	assert.NoError(t, err)
+
+	t.Run("finds by id and flight number", func(t *testing.T) {
+		f, err := r.FindById(ctx, "A001234")
+		assert.NoError(t, err)
+		assert.Equal(t, "A001234", f.ID())
+
+		f, err = r.FindById(ctx, "missing")
+		assert.NoError(t, err)
+		assert.Empty(t, f.ID())
+
+		f, err = r.FindByNumber(ctx, "AF1999")
+		assert.NoError(t, err)
+		assert.Equal(t, "A001999", f.ID())
+	})
+
+	t.Run("finds by normalized passenger name", func(t *testing.T) {
+		flights, err := r.FindByPassenger(ctx, "passenger 14")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A000014"}, flightIDs(flights))
+		assert.Equal(t, "14 PASSÉNGER", flights[0].PassengerName())
+	})
+
+	t.Run("finds by route", func(t *testing.T) {
+		flights, err := r.FindByDestination(ctx, "CDG", "HND")
+		assert.NoError(t, err)
+		want := scan(all, func(f domain.Flight) bool {
+			return f.Segments()[0].Departure() == "CDG" && f.Segments()[0].Arrival() == "HND"
+		})
+		assert.NotEmpty(t, want)
+		assert.Equal(t, flightIDs(want), flightIDs(flights))
+	})
+
+	t.Run("finds by exact price and price range", func(t *testing.T) {
+		flights, err := r.FindByPrice(ctx, 150)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A000050", "A001050", "A002050"}, flightIDs(flights))
+
+		flights, err = r.FindByPriceRange(ctx, "eur", 150, 152)
+		assert.NoError(t, err)
+		assert.Len(t, flights, 9)
+		for i := 1; i < len(flights); i++ {
+			assert.LessOrEqual(t, flights[i-1].Total().Amount(), flights[i].Total().Amount())
+		}
+
+		flights, err = r.FindByPriceRange(ctx, "EUR", 2000, 3000)
+		assert.NoError(t, err)
+		assert.Empty(t, flights)
+
+		flights, err = r.FindByPriceRange(ctx, "USD", 150, 152)
+		assert.NoError(t, err)
+		assert.Empty(t, flights, "only totals in the currency are in the range")
+	})
+
+	t.Run("finds by departure range", func(t *testing.T) {
+		after := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+		before := time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)
+
+		flights, err := r.FindByDepartureRange(ctx, after, before)
+		assert.NoError(t, err)
+		want := scan(all, func(f domain.Flight) bool {
+			d := f.Segments()[0].DepartTime()
+			return !d.Before(after) && d.Before(before)
+		})
+		assert.Equal(t, flightIDs(want), flightIDs(flights))
+
+		flights, err = r.FindByDepartureRange(ctx, time.Time{}, time.Date(2026, 1, 1, 0, 17, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A000000"}, flightIDs(flights))
+	})
+}
+
+// BenchmarkRepoIndexes compares indexed lookups with the linear scans they replaced, over 100k bookings.
+func BenchmarkRepoIndexes(b *testing.B) {
+	ctx := context.Background()
+	r := newIndexedRepo(b, 100_000)
+	all, _ := r.List(ctx)
+	after := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	before := after.Add(24 * time.Hour)
+
+	lookups := []struct {
+		name    string
+		indexed func()
+		linear  func()
+	}{
+		{
+			name:    "id",
+			indexed: func() { _, _ = r.FindById(ctx, "A099999") },
+			linear:  func() { scan(all, func(f domain.Flight) bool { return f.ID() == "A099999" }) },
+		},
+		{
+			name:    "passenger",
+			indexed: func() { _, _ = r.FindByPassenger(ctx, "Passenger 4242") },
+			linear: func() {
+				scan(all, func(f domain.Flight) bool { return f.PassengerName() == "Passenger 4242" })
+			},
+		},
+		{
+			name:    "route",
+			indexed: func() { _, _ = r.FindByDestination(ctx, "CDG", "HND") },
+			linear: func() {
+				scan(all, func(f domain.Flight) bool {
+					return f.Segments()[0].Departure() == "CDG" && f.Segments()[0].Arrival() == "HND"
+				})
+			},
+		},
+		{
+			name:    "price_range",
+			indexed: func() { _, _ = r.FindByPriceRange(ctx, "EUR", 500, 510) },
+			linear: func() {
+				scan(all, func(f domain.Flight) bool { return f.Total().Amount() >= 500 && f.Total().Amount() <= 510 })
+			},
+		},
+		{
+			name:    "departure_range",
+			indexed: func() { _, _ = r.FindByDepartureRange(ctx, after, before) },
+			linear: func() {
+				scan(all, func(f domain.Flight) bool {
+					d := f.Segments()[0].DepartTime()
+					return !d.Before(after) && d.Before(before)
+				})
+			},
+		},
+	}
+
+	for _, l := range lookups {
+		b.Run(l.name+"/indexed", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				l.indexed()
+			}
+		})
+		b.Run(l.name+"/linear", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				l.linear()
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/reference"
+	"aggregator/internal/schedule"
+	"aggregator/internal/service"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// connectingFlight returns a CDG-FRA-HND booking whose first leg arrives late by the given delay, with a 90 minutes
+// connection at Frankfurt when on time.
+func connectingFlight(id, passenger string, delay time.Duration) domain.Flight {
+	depart := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	segs := []domain.Segment{
+		domain.NewSegment("LH1029", "CDG", "FRA", depart.Add(delay), depart.Add(time.Hour+delay)),
+		domain.NewSegment("LH716", "FRA", "HND", depart.Add(150*time.Minute), depart.Add(14*time.Hour)),
+	}
+	return *domain.NewFlight(id, "confirmed", passenger, segs, domain.NewTotal(900, "EUR"), "flights")
+}
+
+// TestMinimumConnectionTime verifies the domestic and international minimums of an airport.
+func TestMinimumConnectionTime(t *testing.T) {
+	println("=====================SCHEDULE_UNIT_TEST====================")
+
+	assert.Equal(t, 45*time.Minute, reference.MinimumConnectionTime("CDG", "FRA", "HND"))
+	assert.Equal(t, 60*time.Minute, reference.MinimumConnectionTime("ORY", "CDG", "ORY"))
+	assert.Equal(t, 90*time.Minute, reference.MinimumConnectionTime("ORY", "CDG", "HND"))
+	assert.Equal(t, 90*time.Minute, reference.MinimumConnectionTime("XXX", "YYY", "ZZZ"))
+}
+
+// TestTracker_Record verifies that retimed bookings are tracked against their original schedule until back on time.
+func TestTracker_Record(t *testing.T) {
+	tracker := schedule.New()
+	cat := catalogue.New()
+	cat.Subscribe(tracker.Record)
+
+	cat.Update(domain.Flights{connectingFlight("A10001", "Marie Curie", 0), historyFlight("A10002", 1, 900, "EUR")})
+	cat.Update(domain.Flights{connectingFlight("A10001", "Marie Curie", 30*time.Minute), historyFlight("A10002", 1, 800, "EUR")})
+	cat.Update(domain.Flights{connectingFlight("A10001", "Marie Curie", time.Hour), historyFlight("A10002", 1, 800, "EUR")})
+
+	tracked := tracker.List()
+	assert.Len(t, tracked, 1)
+	assert.Equal(t, "A10001", tracked[0].Current.ID())
+	assert.Equal(t, time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC), tracked[0].Original.Segments()[0].ArriveTime())
+	assert.Equal(t, uint64(3), tracked[0].Version)
+
+	cat.Update(domain.Flights{connectingFlight("A10001", "Marie Curie", 0)})
+	assert.Empty(t, tracker.List())
+
+	cat.Update(domain.Flights{connectingFlight("A10001", "Marie Curie", time.Hour)})
+	assert.Len(t, tracker.List(), 1)
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 900, "EUR")})
+	assert.Empty(t, tracker.List(), "a rerouted booking is no longer retimed")
+}
+
+// TestTracker_Open verifies that a reopened tracker keeps its changes and compares the first version after a restart to
+// the bookings seen before it.
+func TestTracker_Open(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	tracker, err := schedule.Open(path)
+	assert.NoError(t, err)
+	cat := catalogue.New()
+	cat.Subscribe(tracker.Record)
+	cat.Update(domain.Flights{connectingFlight("A10001", "Marie Curie", 0), connectingFlight("A10002", "Ada Lovelace", 0)})
+	cat.Update(domain.Flights{connectingFlight("A10001", "Marie Curie", 30*time.Minute), connectingFlight("A10002", "Ada Lovelace", 0)})
+
+	reopened, err := schedule.Open(path)
+	assert.NoError(t, err)
+	tracked := reopened.List()
+	assert.Len(t, tracked, 1)
+	assert.Equal(t, "A10001", tracked[0].Current.ID())
+	assert.Equal(t, time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC), tracked[0].Original.Segments()[0].ArriveTime())
+
+	// the new server starts from an empty catalogue, retimed while it was down
+	restarted := catalogue.New()
+	restarted.Subscribe(reopened.Record)
+	restarted.Update(domain.Flights{connectingFlight("A10001", "Marie Curie", 30*time.Minute), connectingFlight("A10002", "Ada Lovelace", time.Hour)})
+	tracked = reopened.List()
+	assert.Len(t, tracked, 2)
+	assert.Equal(t, "A10002", tracked[1].Current.ID())
+	assert.Equal(t, uint64(1), tracked[1].Version)
+}
+
+// TestBuildScheduleReport verifies the connection statuses, the severity order and the passenger grouping.
+func TestBuildScheduleReport(t *testing.T) {
+	tracked := func(id, passenger string, delay time.Duration) schedule.Tracked {
+		return schedule.Tracked{Original: connectingFlight(id, passenger, 0), Current: connectingFlight(id, passenger, delay)}
+	}
+	list := []schedule.Tracked{
+		tracked("A10001", "Marie Curie", 15*time.Minute),
+		tracked("A10002", "Marie Curie", 2*time.Hour),
+		tracked("A10003", "Ada Lovelace", 60*time.Minute),
+	}
+
+	report := service.BuildScheduleReport(list, service.ScheduleQuery{})
+	assert.Equal(t, 3, report.Bookings)
+	assert.Equal(t, []string{"A10002", "A10003", "A10001"}, []string{report.Changes[0].ID, report.Changes[1].ID, report.Changes[2].ID})
+	assert.Equal(t, service.SeverityBroken, report.Changes[0].Severity)
+	assert.Equal(t, -30, report.Changes[0].Connections[0].NewMinutes)
+	assert.Equal(t, service.SeverityUnderMinimum, report.Changes[1].Severity)
+	assert.Equal(t, service.ConnectionImpact{Airport: "FRA", Inbound: "LH1029", Outbound: "LH716", OldMinutes: 90,
+		NewMinutes: 30, MinimumMinutes: 45, Status: service.ConnectionShort}, report.Changes[1].Connections[0])
+	assert.Equal(t, service.SeverityRetimed, report.Changes[2].Severity)
+	assert.Len(t, report.Changes[2].Segments, 1)
+	assert.Equal(t, 15, report.Changes[2].Segments[0].ArriveDelayMinutes)
+	assert.Equal(t, 0, report.Changes[2].ArrivalDelayMinutes)
+
+	assert.Equal(t, []service.AffectedPassenger{
+		{PassengerName: "Marie Curie", Severity: service.SeverityBroken, Bookings: []string{"A10002", "A10001"}},
+		{PassengerName: "Ada Lovelace", Severity: service.SeverityUnderMinimum, Bookings: []string{"A10003"}},
+	}, report.Passengers)
+
+	q, err := service.ParseScheduleQuery(url.Values{"severity": {"under_minimum"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, service.BuildScheduleReport(list, q).Bookings)
+	q, err = service.ParseScheduleQuery(url.Values{"minDelay": {"90"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, service.BuildScheduleReport(list, q).Bookings)
+
+	for _, invalid := range []url.Values{{"severity": {"late"}}, {"minDelay": {"-5"}}, {"minDelay": {"soon"}}} {
+		_, err = service.ParseScheduleQuery(invalid)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, invalid)
+	}
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/search"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createSearchIndex builds an index over a few bookings through a catalogue, as the server does.
+func createSearchIndex() (*catalogue.Catalogue, *search.Index) {
+	day := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	f1 := domain.NewFlight("A10001", "confirmed", "Marie Curie",
+		[]domain.Segment{domain.NewSegment("AF276", "CDG", "HND", day.Add(10*time.Hour), day.Add(23*time.Hour))},
+		domain.NewTotal(950.00, "EUR"), "flights")
+	f2 := domain.NewFlight("B30001", "cancelled", "Pierre Curie",
+		[]domain.Segment{domain.NewSegment("LH1029", "CDG", "FRA", day.AddDate(0, 1, 0), day.AddDate(0, 1, 0).Add(time.Hour))},
+		domain.NewTotal(210.00, "EUR"), "flight_to_book")
+	f3 := domain.NewFlight("A10002", "confirmed", "Évariste Galois",
+		[]domain.Segment{domain.NewSegment("JL046", "CDG", "HND", day.Add(13*time.Hour), day.Add(32*time.Hour))},
+		domain.NewTotal(850.00, "EUR"), "flights")
+
+	c := catalogue.New()
+	ix := search.New()
+	c.Subscribe(ix.Rebuild)
+	c.Update(domain.Flights{*f1, *f2, *f3})
+	return c, ix
+}
+
+// resultIDs returns the booking ids of search results, in order, or nil when there are none.
+func resultIDs(results []search.Result) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Flight.ID())
+	}
+	return ids
+}
+
+// TestIndex_Search verifies matching across fields, ranking, highlights and rebuilds on catalogue changes.
+func TestIndex_Search(t *testing.T) {
+	println("=====================SEARCH_UNIT_TEST====================")
+
+	t.Run("requires every word to match some field", func(t *testing.T) {
+		_, ix := createSearchIndex()
+
+		results, err := ix.Search("Curie HND January")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A10001"}, resultIDs(results))
+		assert.Equal(t, map[search.Field]string{
+			search.FieldPassenger: "Marie <em>Curie</em>",
+			search.FieldAirport:   "CDG <em>HND</em>",
+			search.FieldDeparture: "<em>2026-01-10</em>",
+		}, results[0].Highlights)
+	})
+
+	t.Run("matches flight numbers, ids, cities, statuses and dates", func(t *testing.T) {
+		_, ix := createSearchIndex()
+
+		for q, want := range map[string][]string{
+			"AF276":           {"A10001"},
+			"b30001":          {"B30001"},
+			"tokyo":           {"A10001", "A10002"},
+			"cancelled":       {"B30001"},
+			"2026-02":         {"B30001"},
+			"galois evariste": {"A10002"},
+			"unknown":         nil,
+		} {
+			results, err := ix.Search(q)
+			assert.NoError(t, err, q)
+			assert.Equal(t, want, resultIDs(results), q)
+		}
+	})
+
+	t.Run("ranks exact matches before prefix matches", func(t *testing.T) {
+		_, ix := createSearchIndex()
+
+		results, err := ix.Search("cur")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"B30001", "A10001"}, resultIDs(results))
+		assert.Equal(t, results[0].Score, results[1].Score)
+
+		results, err = ix.Search("hnd")
+		assert.NoError(t, err)
+		assert.Greater(t, results[0].Score, 0.0)
+		assert.Equal(t, "CDG <em>HND</em>", results[0].Highlights[search.FieldAirport])
+	})
+
+	t.Run("escapes highlighted values", func(t *testing.T) {
+		c, ix := createSearchIndex()
+		day := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+		f := domain.NewFlight("A10003", "confirmed", "<script>alert(1)</script> Marie",
+			[]domain.Segment{domain.NewSegment("AF276", "CDG", "HND", day.Add(10*time.Hour), day.Add(23*time.Hour))},
+			domain.NewTotal(950.00, "EUR"), "flights")
+		c.Update(append(c.Current().Flights, *f))
+
+		results, err := ix.Search("alert")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A10003"}, resultIDs(results))
+		assert.Equal(t, "&lt;script&gt;<em>alert</em>(1)&lt;/script&gt; Marie", results[0].Highlights[search.FieldPassenger])
+	})
+
+	t.Run("rejects queries without words", func(t *testing.T) {
+		_, ix := createSearchIndex()
+
+		_, err := ix.Search(" - ")
+		assert.ErrorIs(t, err, search.ErrEmptyQuery)
+	})
+
+	t.Run("is rebuilt when the catalogue changes", func(t *testing.T) {
+		c, ix := createSearchIndex()
+		assert.Equal(t, uint64(1), ix.Version())
+
+		c.Update(c.Current().Flights[:1])
+
+		assert.Equal(t, uint64(2), ix.Version())
+		results, err := ix.Search("galois")
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+	})
+}
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createLateNightFlight returns a flight leaving Paris at 00:30 local time on 2026-01-02, i.e. 23:30 UTC the day before.
+func createLateNightFlight() domain.Flight {
+	depart := time.Date(2026, 1, 1, 23, 30, 0, 0, time.UTC)
+	f := domain.NewFlight(
+		"N1",
+		"confirmed",
+		"Night Owl",
+		[]domain.Segment{domain.NewSegment("AF276", "CDG", "HND", depart, depart.Add(13*time.Hour))},
+		domain.NewTotal(900, "EUR"),
+		"flights",
+	)
+	return *f
+}
+
+// TestParseTimeBound verifies that dates, floating datetimes and offsets are parsed into the right bounds.
+func TestParseTimeBound(t *testing.T) {
+	println("=====================DATETIME_UNIT_TEST====================")
+
+	b, err := service.ParseTimeBound("2026-01-01T10:00:00+02:00", false)
+	assert.NoError(t, err)
+	assert.False(t, b.Floating)
+	assert.Equal(t, time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC), b.At.UTC())
+
+	b, err = service.ParseTimeBound("2026-01-01T10:00", false)
+	assert.NoError(t, err)
+	assert.True(t, b.Floating)
+
+	b, err = service.ParseTimeBound("2026-01-01", true)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), b.At)
+
+	_, err = service.ParseTimeBound("01/01/2026", false)
+	assert.Error(t, err)
+}
+
+// TestQuery_DateWindows verifies departure and arrival windows in UTC and in airport local time.
+func TestQuery_DateWindows(t *testing.T) {
+	flights := domain.Flights{createLateNightFlight()}
+
+	cases := []struct {
+		name   string
+		values url.Values
+		want   int
+	}{
+		{"departure date in UTC", url.Values{"departDate": {"2026-01-01"}}, 1},
+		{"departure date in origin local time", url.Values{"departDate": {"2026-01-02"}, "tz": {"local"}}, 1},
+		{"local date excludes the UTC day", url.Values{"departDate": {"2026-01-01"}, "tz": {"local"}}, 0},
+		{"inclusive date upper bound", url.Values{"departBefore": {"2026-01-01"}}, 1},
+		{"datetime lower bound", url.Values{"departAfter": {"2026-01-01T23:31:00Z"}}, 0},
+		{"arrival window in destination local time", url.Values{"arriveAfter": {"2026-01-02T21:30"}, "arriveBefore": {"2026-01-02T21:31"}, "tz": {"local"}}, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q, err := service.ParseQuery(c.values)
+			assert.NoError(t, err)
+			assert.Len(t, q.Apply(flights), c.want)
+		})
+	}
+
+	t.Run("rejects invalid windows", func(t *testing.T) {
+		for _, values := range []url.Values{
+			{"departDate": {"2026-13-01"}},
+			{"departAfter": {"2026-01-02"}, "departBefore": {"2026-01-01"}},
+			{"departAfter": {"2026-01-02T10:00"}, "departBefore": {"2026-01-02T09:00:00Z"}},
+			{"departAfter": {"2026-01-03T10:00"}, "departBefore": {"2026-01-02T09:00:00Z"}, "tz": {"local"}},
+			{"departDate": {"2026-01-02"}, "from": {"XYZ"}, "tz": {"local"}},
+			{"arriveBefore": {"2026-01-02T10:00"}, "to": {"XYZ"}, "tz": {"local"}},
+			{"tz": {"mars"}},
+		} {
+			_, err := service.ParseQuery(values)
+			assert.ErrorIs(t, err, service.ErrInvalidQuery, values.Encode())
+		}
+	})
+
+	t.Run("accepts mixed bounds that local time zones can reconcile", func(t *testing.T) {
+		_, err := service.ParseQuery(url.Values{"departAfter": {"2026-01-02T10:00"}, "departBefore": {"2026-01-02T09:00:00Z"}, "tz": {"local"}})
+		assert.NoError(t, err)
+		_, err = service.ParseQuery(url.Values{"departAfter": {"2026-01-02T10:00:00Z"}, "from": {"XYZ"}, "tz": {"local"}})
+		assert.NoError(t, err, "fixed bounds need no time zone")
+	})
+
+	t.Run("local bounds never match an airport without a known time zone", func(t *testing.T) {
+		depart := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+		unknown := *domain.NewFlight("U1", "confirmed", "Nobody", []domain.Segment{domain.NewSegment("XX1", "XYZ", "HND", depart, depart.Add(time.Hour))},
+			domain.NewTotal(100, "EUR"), "flights")
+
+		q, err := service.ParseQuery(url.Values{"departDate": {"2026-01-02"}, "tz": {"local"}})
+		assert.NoError(t, err)
+		assert.Empty(t, q.Apply(domain.Flights{unknown}))
+
+		q, err = service.ParseQuery(url.Values{"departDate": {"2026-01-02"}})
+		assert.NoError(t, err)
+		assert.Len(t, q.Apply(domain.Flights{unknown}), 1)
+	})
+}
+
+// TestMulti_Filter verifies that date filters apply to the flights of every repository.
+func TestMulti_Filter(t *testing.T) {
+	ctx := context.Background()
+
+	repo1 := new(MockFlightsRepository)
+	repo1.On("List", ctx).Return(domain.Flights{createLateNightFlight()}, nil)
+
+	repo2 := new(MockFlightsRepository)
+	repo2.On("List", ctx).Return(createMockFlights(), nil)
+
+	q, err := service.ParseQuery(url.Values{"departDate": {"2026-01-02"}, "tz": {"local"}})
+	assert.NoError(t, err)
+
+	result, err := service.FilterFlights(ctx, repo.NewMulti(repo1, repo2), q)
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "N1", result[0].ID())
+
+	repo1.AssertExpectations(t)
+	repo2.AssertExpectations(t)
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createDuplicateFlights generates the same trip booked through two providers plus an unrelated booking.
+func createDuplicateFlights() domain.Flights {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	f1 := domain.NewFlight(
+		"A10001",
+		"confirmed",
+		"Marie Curie",
+		[]domain.Segment{domain.NewSegment("JL046", "CDG", "HND", day.Add(13*time.Hour), day.Add(32*time.Hour))},
+		domain.NewTotal(850.00, "EUR"),
+		"flights",
+	)
+	f2 := domain.NewFlight(
+		"B30002",
+		"confirmed",
+		"marie  curie",
+		[]domain.Segment{domain.NewSegment("JL046", "CDG", "HND", day.Add(11*time.Hour), day.Add(25*time.Hour))},
+		domain.NewTotal(1020.00, "EUR"),
+		"flight_to_book",
+	)
+	f3 := domain.NewFlight(
+		"A10002",
+		"confirmed",
+		"Albert Einstein",
+		[]domain.Segment{domain.NewSegment("AF276", "CDG", "HND", day.Add(10*time.Hour), day.Add(30*time.Hour))},
+		domain.NewTotal(910.00, "EUR"),
+		"flights",
+	)
+
+	return domain.Flights{*f1, *f2, *f3}
+}
+
+// TestMergeDuplicates verifies that bookings of the same passenger, flight number and date are merged with each conflict policy.
+func TestMergeDuplicates(t *testing.T) {
+	println("=====================DEDUP_UNIT_TEST====================")
+
+	priority := []string{"flight_to_book", "flights"}
+
+	t.Run("merges duplicates and lists contributing providers", func(t *testing.T) {
+		merged := service.MergeDuplicates(createDuplicateFlights(), service.MergeOptions{Policy: service.PolicyCheapest})
+
+		assert.Len(t, merged, 2)
+		providers := merged[0].Providers()
+		assert.Len(t, providers, 2)
+		assert.Equal(t, "A10001", providers[0].ID())
+		assert.Equal(t, "flights", providers[0].Source())
+		assert.Equal(t, "B30002", providers[1].ID())
+		assert.Equal(t, "flight_to_book", providers[1].Source())
+		assert.Len(t, merged[1].Providers(), 1)
+	})
+
+	t.Run("cheapest policy keeps the lowest price", func(t *testing.T) {
+		merged := service.MergeDuplicates(createDuplicateFlights(), service.MergeOptions{Policy: service.PolicyCheapest, Priority: priority})
+
+		assert.Equal(t, "A10001", merged[0].Flight().ID())
+		assert.Equal(t, 850.00, merged[0].Flight().Total().Amount())
+	})
+
+	t.Run("most recent policy keeps the latest departure", func(t *testing.T) {
+		merged := service.MergeDuplicates(createDuplicateFlights(), service.MergeOptions{Policy: service.PolicyMostRecent, Priority: priority})
+
+		assert.Equal(t, "A10001", merged[0].Flight().ID())
+	})
+
+	t.Run("provider priority policy keeps the preferred source", func(t *testing.T) {
+		merged := service.MergeDuplicates(createDuplicateFlights(), service.MergeOptions{Policy: service.PolicyProviderPriority, Priority: priority})
+
+		assert.Equal(t, "B30002", merged[0].Flight().ID())
+	})
+
+	t.Run("does not merge different departure dates", func(t *testing.T) {
+		flights := createDuplicateFlights()
+		seg := flights[1].Segments()[0]
+		moved := domain.NewFlight(
+			"B30002",
+			"confirmed",
+			"Marie Curie",
+			[]domain.Segment{domain.NewSegment("JL046", "CDG", "HND", seg.DepartTime().Add(24*time.Hour), seg.ArriveTime().Add(24*time.Hour))},
+			domain.NewTotal(1020.00, "EUR"),
+			"flight_to_book",
+		)
+		flights[1] = *moved
+
+		merged := service.MergeDuplicates(flights, service.MergeOptions{Policy: service.PolicyCheapest})
+
+		assert.Len(t, merged, 3)
+	})
+
+	t.Run("does not merge bookings of the same source", func(t *testing.T) {
+		flights := createDuplicateFlights()
+		again := domain.NewFlight("A10003", "confirmed", "Marie Curie", flights[0].Segments(), domain.NewTotal(800.00, "EUR"), "flights")
+		flights = append(flights, *again)
+
+		merged := service.MergeDuplicates(flights, service.MergeOptions{Policy: service.PolicyCheapest})
+
+		assert.Len(t, merged, 3)
+		assert.Equal(t, []string{"A10001", "B30002"}, []string{merged[0].Providers()[0].ID(), merged[0].Providers()[1].ID()})
+		assert.Equal(t, "A10003", merged[2].Flight().ID())
+		assert.Len(t, merged[2].Providers(), 1)
+	})
+
+	t.Run("does not merge names in another word order", func(t *testing.T) {
+		flights := createDuplicateFlights()
+		swapped := domain.NewFlight("B30002", "confirmed", "Curie Marie", flights[1].Segments(), flights[1].Total(), flights[1].Source())
+		flights[1] = *swapped
+
+		merged := service.MergeDuplicates(flights, service.MergeOptions{Policy: service.PolicyCheapest})
+
+		assert.Len(t, merged, 3)
+	})
+}
+
+// TestParseMergePolicy checks that policy names and aliases are recognized and unknown ones rejected.
+func TestParseMergePolicy(t *testing.T) {
+	for input, want := range map[string]service.MergePolicy{
+		"":         service.PolicyCheapest,
+		"cheapest": service.PolicyCheapest,
+		"recent":   service.PolicyMostRecent,
+		"Priority": service.PolicyProviderPriority,
+	} {
+		policy, err := service.ParseMergePolicy(input)
+		assert.NoError(t, err)
+		assert.Equal(t, want, policy)
+	}
+
+	_, err := service.ParseMergePolicy("random")
+	assert.Error(t, err)
+}
+
+// TestMergeFlights verifies that MergeFlights merges the flights aggregated from every repository.
+func TestMergeFlights(t *testing.T) {
+	ctx := context.Background()
+	flights := createDuplicateFlights()
+
+	repo1 := new(MockFlightsRepository)
+	repo1.On("List", ctx).Return(domain.Flights{flights[0], flights[2]}, nil)
+
+	repo2 := new(MockFlightsRepository)
+	repo2.On("List", ctx).Return(flights[1:2], nil)
+
+	merged, err := service.MergeFlights(ctx, repo.NewMulti(repo1, repo2), service.MergeOptions{Policy: service.PolicyCheapest})
+
+	assert.NoError(t, err)
+	assert.Len(t, merged, 2)
+
+	repo1.AssertExpectations(t)
+	repo2.AssertExpectations(t)
+}
@@ -68,6 +68,36 @@ func createMockFlights() domain.Flights {
 	return domain.Flights{*f1, *f2, *f3}
 }
 
+// testDay is the departure time of the fixtures built with newTestSegment.
+var testDay = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// newTestSegment returns a segment departing at depart and landing after the given duration.
+func newTestSegment(number, from, to string, depart time.Time, duration time.Duration) domain.Segment {
+	return domain.NewSegment(number, from, to, depart, depart.Add(duration))
+}
+
+// newTestFlight returns a confirmed booking of Marie Curie from the "flights" source.
+func newTestFlight(id string, total domain.Total, segs ...domain.Segment) domain.Flight {
+	return newTestBooking(id, "confirmed", "flights", total, segs...)
+}
+
+// newTestBooking returns a booking of Marie Curie with the given status and source.
+func newTestBooking(id, status, source string, total domain.Total, segs ...domain.Segment) domain.Flight {
+	return *domain.NewFlight(id, status, "Marie Curie", segs, total, source)
+}
+
+// flightIDs returns the ids of the flights in order, or nil when there are none.
+func flightIDs(flights domain.Flights) []string {
+	if len(flights) == 0 {
+		return nil
+	}
+	ids := make([]string, len(flights))
+	for i, f := range flights {
+		ids[i] = f.ID()
+	}
+	return ids
+}
+
 // createMockFlightsWithConnections generates mock flight data, including flights with multiple connections, for testing purposes.
 func createMockFlightsWithConnections() domain.Flights {
 	now := time.Now()
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createFlightBookings generates three bookings on AF276: two on the 1st of January, one on the 2nd
+// with the number written with a leading zero, plus a booking on another flight.
+func createFlightBookings() domain.Flights {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	af276 := func(id, name, number string, depart time.Time, source string) domain.Flight {
+		return *domain.NewFlight(id, "confirmed", name,
+			[]domain.Segment{domain.NewSegment(number, "CDG", "HND", depart, depart.Add(13*time.Hour))},
+			domain.NewTotal(950.00, "EUR"), source)
+	}
+
+	return domain.Flights{
+		af276("A10001", "Marie Curie", "AF276", day.Add(10*time.Hour), "flights"),
+		af276("B30001", "Albert Einstein", "AF 0276", day.Add(10*time.Hour), "flight_to_book"),
+		// 23:30 UTC on the 1st is already the 2nd in Paris
+		af276("A10002", "Niels Bohr", "af276", day.Add(23*time.Hour+30*time.Minute), "flights"),
+		af276("A10003", "Ada Lovelace", "JL046", day.Add(10*time.Hour), "flights"),
+	}
+}
+
+// TestNormalizeFlightNumber verifies that spacing, case and leading zeros do not change a flight number.
+func TestNormalizeFlightNumber(t *testing.T) {
+	println("=====================FLIGHT_NUMBER_UNIT_TEST====================")
+
+	for in, want := range map[string]string{
+		"AF276":    "AF276",
+		"AF 276":   "AF276",
+		"af-0276":  "AF276",
+		"U2 0012":  "U2012",
+		"9W 0007":  "9W007",
+		"AFR0276":  "AFR276",
+		"JL46":     "JL046",
+		"BA 1234A": "BA1234A",
+		"AF":       "AF",
+		"12 345":   "12345",
+	} {
+		assert.Equal(t, want, domain.NormalizeFlightNumber(in), in)
+	}
+}
+
+// TestParseFlightBookingsQuery verifies the "all" and "date" params.
+func TestParseFlightBookingsQuery(t *testing.T) {
+	_, all, err := service.ParseFlightBookingsQuery("AF276", url.Values{})
+	assert.NoError(t, err)
+	assert.False(t, all)
+
+	q, all, err := service.ParseFlightBookingsQuery("AF276", url.Values{"all": {"true"}, "date": {"2026-01-01"}})
+	assert.NoError(t, err)
+	assert.True(t, all)
+	assert.Equal(t, "2026-01-01", q.Date)
+
+	for _, values := range []url.Values{
+		{"all": {"yes please"}},
+		{"date": {"2026-01-01"}},
+		{"all": {"true"}, "date": {"01/01/2026"}},
+	} {
+		_, _, err := service.ParseFlightBookingsQuery("AF276", values)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, values.Encode())
+	}
+}
+
+// TestFindFlightBookings verifies that every booking on a flight is returned across repositories and scoped by local date.
+func TestFindFlightBookings(t *testing.T) {
+	ctx := context.Background()
+	flights := createFlightBookings()
+
+	repo1 := new(MockFlightsRepository)
+	repo1.On("FindAllByNumber", ctx, "AF 276").Return(domain.Flights{flights[0], flights[2]}, nil)
+	repo2 := new(MockFlightsRepository)
+	repo2.On("FindAllByNumber", ctx, "AF 276").Return(domain.Flights{flights[1]}, nil)
+	multi := repo.NewMulti(repo1, repo2)
+
+	t.Run("returns every booking from every repository", func(t *testing.T) {
+		result, err := service.FindFlightBookings(ctx, multi, service.FlightBookingsQuery{Number: "AF 276"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A10001", "A10002", "B30001"}, flightIDs(result))
+	})
+
+	t.Run("scopes by local departure date", func(t *testing.T) {
+		result, err := service.FindFlightBookings(ctx, multi, service.FlightBookingsQuery{Number: "AF 276", Date: "2026-01-02"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A10002"}, flightIDs(result))
+
+		_, err = service.FindFlightBookings(ctx, multi, service.FlightBookingsQuery{Number: "AF 276", Date: "2026-03-01"})
+		assert.ErrorIs(t, err, domain.ErrFlightsNotFound)
+	})
+
+	repo1.AssertExpectations(t)
+	repo2.AssertExpectations(t)
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// paginate sorts the flights by the ordering and returns the page designated by the query params.
+func paginate(t *testing.T, flights domain.Flights, o service.Ordering, values url.Values, version uint64) service.Page {
+	req, err := service.ParsePageRequest("/flights", values)
+	assert.NoError(t, err)
+	o.Sort(flights)
+	page, err := service.Paginate(flights, o, req, version)
+	assert.NoError(t, err)
+	return page
+}
+
+// pageIDs returns the ids of the flights of a page.
+func pageIDs(page service.Page) []string {
+	return flightIDs(page.Flights)
+}
+
+// TestPaginate verifies keyset pagination forward and backward, and cursor validation.
+func TestPaginate(t *testing.T) {
+	println("=====================PAGINATION_UNIT_TEST====================")
+
+	spec, err := service.ParseSort("price")
+	assert.NoError(t, err)
+	ordering := spec.Ordering()
+
+	t.Run("walks pages forward and backward", func(t *testing.T) {
+		first := paginate(t, createMockFlights(), ordering, url.Values{"limit": {"2"}}, 1)
+		assert.Equal(t, []string{"2", "3"}, pageIDs(first))
+		assert.Equal(t, 3, first.Total)
+		assert.Nil(t, first.Prev)
+		assert.NotNil(t, first.Next)
+
+		second := paginate(t, createMockFlights(), ordering, url.Values{"limit": {"2"}, "cursor": {first.Next.Encode()}}, 1)
+		assert.Equal(t, []string{"1"}, pageIDs(second))
+		assert.Nil(t, second.Next)
+		assert.NotNil(t, second.Prev)
+
+		back := paginate(t, createMockFlights(), ordering, url.Values{"limit": {"2"}, "cursor": {second.Prev.Encode()}}, 1)
+		assert.Equal(t, []string{"2", "3"}, pageIDs(back))
+	})
+
+	t.Run("rejects cursors of another catalogue version", func(t *testing.T) {
+		flights := createMockFlights()
+		first := paginate(t, flights, ordering, url.Values{"limit": {"1"}}, 1)
+
+		req, err := service.ParsePageRequest("/flights", url.Values{"limit": {"1"}, "cursor": {first.Next.Encode()}})
+		assert.NoError(t, err)
+		_, err = service.Paginate(flights[1:], ordering, req, 2)
+		assert.ErrorIs(t, err, service.ErrCursorExpired)
+	})
+
+	t.Run("rejects foreign or malformed cursors", func(t *testing.T) {
+		first := paginate(t, createMockFlights(), ordering, url.Values{"limit": {"1"}}, 1)
+
+		_, err := service.ParsePageRequest("/flights", url.Values{"cursor": {first.Next.Encode()}, "status": {"confirmed"}})
+		assert.ErrorIs(t, err, service.ErrInvalidCursor)
+
+		_, err = service.ParsePageRequest("/flights", url.Values{"cursor": {"not-a-cursor"}})
+		assert.ErrorIs(t, err, service.ErrInvalidCursor)
+
+		_, err = service.ParsePageRequest("/flights", url.Values{"limit": {"0"}})
+		assert.ErrorIs(t, err, service.ErrInvalidQuery)
+
+		req, err := service.ParsePageRequest("/flights", url.Values{"cursor": {first.Next.Encode()}})
+		assert.NoError(t, err)
+		_, err = service.Paginate(createMockFlights(), service.DefaultOrdering, req, 1)
+		assert.ErrorIs(t, err, service.ErrInvalidCursor)
+	})
+}
+
+// TestCatalogue_Update verifies that versions only increase when the content changes and listeners are notified.
+func TestCatalogue_Update(t *testing.T) {
+	c := catalogue.New()
+	var notified []uint64
+	c.Subscribe(func(prev, next catalogue.Version) {
+		notified = append(notified, next.Number)
+	})
+
+	flights := createMockFlights()
+	assert.Equal(t, uint64(1), c.Update(flights).Number)
+
+	reordered := domain.Flights{flights[2], flights[0], flights[1]}
+	assert.Equal(t, uint64(1), c.Update(reordered).Number)
+
+	assert.Equal(t, uint64(2), c.Update(flights[:2]).Number)
+	assert.Equal(t, []uint64{1, 2}, notified)
+	assert.Len(t, c.Current().Flights, 2)
+}
+
+// TestCatalogue_Version verifies that the latest versions are retained, so that pages can be read from the version
+// their cursor was issued for.
+func TestCatalogue_Version(t *testing.T) {
+	c := catalogue.New()
+	flights := createMockFlights()
+	for i := 0; i < catalogue.RetainedVersions+2; i++ {
+		c.Update(flights[:1+i%3])
+	}
+
+	_, ok := c.Version(2)
+	assert.False(t, ok, "the oldest versions are dropped")
+	v, ok := c.Version(3)
+	assert.True(t, ok)
+	assert.Len(t, v.Flights, 3)
+	current := c.Current()
+	v, ok = c.Version(current.Number)
+	assert.True(t, ok)
+	assert.Equal(t, current.Digest, v.Digest)
+}
+
+// TestFromFlights verifies that a repository over aggregated flights lists them by source and finds them by index.
+func TestFromFlights(t *testing.T) {
+	ctx := context.Background()
+	flights := append(createMockFlights(), createLateNightFlight())
+	multi := repo.FromFlights(flights)
+
+	all, err := multi.List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "3", "2", "N1"}, flightIDs(all))
+
+	f, err := multi.FindByID(ctx, "N1")
+	assert.NoError(t, err)
+	assert.Equal(t, "flights", f.Source())
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"aggregator/internal/textnorm"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createPassengerFlights generates bookings whose passenger names differ in accents, case and word order.
+func createPassengerFlights() domain.Flights {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	segs := []domain.Segment{domain.NewSegment("AF276", "CDG", "HND", day.Add(10*time.Hour), day.Add(23*time.Hour))}
+
+	f1 := domain.NewFlight("A10001", "confirmed", "Évariste Galois", segs, domain.NewTotal(975.00, "EUR"), "flights")
+	f2 := domain.NewFlight("B30001", "confirmed", "GALOIS  evariste", segs, domain.NewTotal(990.00, "EUR"), "flight_to_book")
+	f3 := domain.NewFlight("A10002", "confirmed", "Evarist Galoi", segs, domain.NewTotal(910.00, "EUR"), "flights")
+	f4 := domain.NewFlight("A10003", "confirmed", "Marie Curie", segs, domain.NewTotal(950.00, "EUR"), "flights")
+
+	return domain.Flights{*f1, *f2, *f3, *f4}
+}
+
+// TestTextnorm verifies folding, name keys and edit distances.
+func TestTextnorm(t *testing.T) {
+	println("=====================PASSENGER_UNIT_TEST====================")
+
+	assert.Equal(t, "evariste galois", textnorm.Fold("  Évariste-GALOIS "))
+	assert.Equal(t, "soren kierkegaard", textnorm.Fold("Søren KIERKEGAARD"))
+	assert.Equal(t, "strasse", textnorm.Fold("STRAßE"))
+	assert.Equal(t, "curie marie", textnorm.NameKey("Marie  CURÍE"))
+	assert.Equal(t, textnorm.NameKey("Curie Marie"), textnorm.NameKey("marie curie"))
+
+	assert.Equal(t, 3, textnorm.Levenshtein("kitten", "sitting"))
+	assert.Equal(t, 0, textnorm.Levenshtein("", ""))
+	assert.Equal(t, 1.0, textnorm.Similarity("", ""))
+	assert.InDelta(t, 0.8, textnorm.Similarity("curie", "curi"), 1e-9)
+}
+
+// TestPassengerScore verifies that exact matches score 1 and typos lower the score.
+func TestPassengerScore(t *testing.T) {
+	assert.Equal(t, 1.0, service.PassengerScore("galois évariste", "Évariste Galois"))
+	assert.Equal(t, 0.5, service.PassengerScore("curie", "Marie Curie"))
+	assert.InDelta(t, 0.867, service.PassengerScore("Evariste Galois", "Evarist Galoi"), 1e-9)
+	assert.Less(t, service.PassengerScore("Marie Curie", "Isaac Newton"), service.DefaultMinScore)
+	assert.Equal(t, 0.0, service.PassengerScore("", "Marie Curie"))
+}
+
+// TestParsePassengerSearch verifies the fuzzy and minScore params.
+func TestParsePassengerSearch(t *testing.T) {
+	s, err := service.ParsePassengerSearch("Marie Curie", url.Values{})
+	assert.NoError(t, err)
+	assert.False(t, s.Fuzzy)
+
+	s, err = service.ParsePassengerSearch("Marie Curie", url.Values{"fuzzy": {"true"}, "minScore": {"0.5"}})
+	assert.NoError(t, err)
+	assert.True(t, s.Fuzzy)
+	assert.Equal(t, 0.5, s.MinScore)
+
+	for _, values := range []url.Values{
+		{"fuzzy": {"maybe"}},
+		{"minScore": {"0.5"}},
+		{"fuzzy": {"true"}, "minScore": {"0"}},
+		{"fuzzy": {"true"}, "minScore": {"1.5"}},
+	} {
+		_, err := service.ParsePassengerSearch("Marie Curie", values)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, values.Encode())
+	}
+
+	_, err = service.ParsePassengerSearch(" - ", url.Values{})
+	assert.ErrorIs(t, err, service.ErrInvalidQuery)
+}
+
+// TestSearchPassengers verifies exact accent-insensitive matching through the repositories and ranked fuzzy matching.
+func TestSearchPassengers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("exact search ignores accents, case and word order", func(t *testing.T) {
+		r, err := repo.NewRepoFlightsFromReader(strings.NewReader(`[
+			{"bookingId": "A10001", "passengerName": "Évariste Galois", "flightNumber": "JL052",
+			 "departureTime": "2026-01-01T15:25:00Z", "arrivalTime": "2026-01-02T10:50:00Z", "price": 975, "currency": "EUR"},
+			{"bookingId": "A10002", "passengerName": "GALOIS  evariste", "flightNumber": "JL046",
+			 "departureTime": "2026-01-02T15:25:00Z", "arrivalTime": "2026-01-03T10:50:00Z", "price": 990, "currency": "EUR"},
+			{"bookingId": "A10003", "passengerName": "Evarist Galoi", "flightNumber": "AF276",
+			 "departureTime": "2026-01-01T10:00:00Z", "arrivalTime": "2026-01-01T23:00:00Z", "price": 910, "currency": "EUR"}
+		]`))
+		assert.NoError(t, err)
+
+		matches, err := service.SearchPassengers(ctx, repo.NewMulti(r), service.PassengerSearch{Name: "galois EVARISTE"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A10001", "A10002"}, flightIDs(matches.Flights()))
+		assert.Equal(t, 1.0, matches[0].Score)
+	})
+
+	t.Run("fuzzy search ranks by score", func(t *testing.T) {
+		mockRepo := new(MockFlightsRepository)
+		mockRepo.On("List", ctx).Return(createPassengerFlights(), nil)
+
+		matches, err := service.SearchPassengers(ctx, repo.NewMulti(mockRepo), service.PassengerSearch{
+			Name:     "Evariste Galoi",
+			Fuzzy:    true,
+			MinScore: service.DefaultMinScore,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A10002", "B30001", "A10001"}, flightIDs(matches.Flights()))
+		assert.Greater(t, matches[0].Score, matches[1].Score)
+		assert.Equal(t, matches[1].Score, matches[2].Score)
+
+		snapshots := matches.Snapshot(matches.Flights()[:1])
+		assert.Equal(t, "A10002", snapshots[0].ID)
+		assert.Equal(t, matches[0].Score, snapshots[0].Score)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("returns not found below the minimum score", func(t *testing.T) {
+		mockRepo := new(MockFlightsRepository)
+		mockRepo.On("List", ctx).Return(createPassengerFlights(), nil)
+
+		_, err := service.SearchPassengers(ctx, repo.NewMulti(mockRepo), service.PassengerSearch{
+			Name:     "Ada Lovelace",
+			Fuzzy:    true,
+			MinScore: service.DefaultMinScore,
+		})
+
+		assert.ErrorIs(t, err, domain.ErrFlightsNotFound)
+	})
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParsePrice verifies strict validation of client supplied prices.
+func TestParsePrice(t *testing.T) {
+	println("=====================PRICE_UNIT_TEST====================")
+
+	price, err := service.ParsePrice("850.5")
+	assert.NoError(t, err)
+	assert.Equal(t, 850.5, price)
+
+	for _, s := range []string{"abc", "", "-10", "NaN", "Inf"} {
+		_, err := service.ParsePrice(s)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, s)
+	}
+}
+
+// TestPriceRange verifies range, tolerance and currency-aware price matching.
+func TestPriceRange(t *testing.T) {
+	flights := createMockFlights()
+
+	t.Run("zero tolerance matches the exact price", func(t *testing.T) {
+		pr, err := service.AroundPrice(400, 0, "USD")
+		assert.NoError(t, err)
+
+		assert.Len(t, filterByPrice(flights, pr), 1)
+	})
+
+	t.Run("tolerance widens the range", func(t *testing.T) {
+		pr, err := service.AroundPrice(400, 25, "USD")
+		assert.NoError(t, err)
+
+		assert.Len(t, filterByPrice(flights, pr), 3)
+	})
+
+	t.Run("range with a single bound", func(t *testing.T) {
+		pr, err := service.ParsePriceRange(url.Values{"max": {"400"}, "currency": {"USD"}})
+		assert.NoError(t, err)
+
+		assert.Len(t, filterByPrice(flights, pr), 2)
+	})
+
+	t.Run("compares in the base currency by default", func(t *testing.T) {
+		pr, err := service.ParsePriceRange(url.Values{"max": {"380"}})
+		assert.NoError(t, err)
+		assert.Equal(t, service.BaseCurrency, pr.Currency)
+
+		// 400 USD is 370.37 EUR, below the bound its raw amount is above
+		assert.Len(t, filterByPrice(flights, pr), 2)
+
+		pr, err = service.AroundPrice(400, 0, "")
+		assert.NoError(t, err)
+		assert.Empty(t, filterByPrice(flights, pr), "400 USD is not 400 EUR")
+	})
+
+	t.Run("converts totals into the requested currency", func(t *testing.T) {
+		eur, err := service.ConvertAmount(500, "USD", "EUR")
+		assert.NoError(t, err)
+
+		pr, err := service.AroundPrice(eur, 0, "eur")
+		assert.NoError(t, err)
+
+		result := filterByPrice(flights, pr)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "1", result[0].ID())
+	})
+
+	t.Run("rejects invalid ranges", func(t *testing.T) {
+		_, err := service.ParsePriceRange(url.Values{})
+		assert.ErrorIs(t, err, service.ErrInvalidQuery)
+
+		_, err = service.ParsePriceRange(url.Values{"min": {"10"}, "max": {"5"}})
+		assert.ErrorIs(t, err, service.ErrInvalidQuery)
+
+		_, err = service.ParsePriceRange(url.Values{"min": {"10"}, "currency": {"XYZ"}})
+		assert.ErrorIs(t, err, service.ErrUnknownCurrency)
+
+		_, err = service.AroundPrice(100, 150, "")
+		assert.ErrorIs(t, err, service.ErrInvalidQuery)
+	})
+}
+
+// TestFindByPriceRange verifies that FindByPriceRange aggregates matches and reports when none are found.
+func TestFindByPriceRange(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("converts every total", func(t *testing.T) {
+		multi := repo.FromFlights(createMockFlights())
+
+		pr, _ := service.ParsePriceRange(url.Values{"min": {"350"}, "currency": {"EUR"}})
+		result, err := service.FindByPriceRange(ctx, multi, pr)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, flightIDs(filterByPrice(createMockFlights(), pr)), flightIDs(result))
+
+		pr, _ = service.ParsePriceRange(url.Values{"min": {"350"}})
+		result, err = service.FindByPriceRange(ctx, multi, pr)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, flightIDs(filterByPrice(createMockFlights(), pr)), flightIDs(result))
+
+		pr, _ = service.ParsePriceRange(url.Values{"max": {"300"}, "currency": {"USD"}})
+		result, err = service.FindByPriceRange(ctx, multi, pr)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, flightIDs(filterByPrice(createMockFlights(), pr)), flightIDs(result))
+		assert.NotEmpty(t, result)
+
+		pr, _ = service.ParsePriceRange(url.Values{"min": {"10000"}, "currency": {"EUR"}})
+		_, err = service.FindByPriceRange(ctx, multi, pr)
+		assert.ErrorIs(t, err, domain.ErrFlightsNotFound)
+	})
+}
+
+// filterByPrice returns the flights matching the price range.
+func filterByPrice(flights domain.Flights, pr service.PriceRange) domain.Flights {
+	var out domain.Flights
+	for _, f := range flights {
+		if pr.Match(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package test
+
+import (
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseQuery verifies that filter parameters are parsed into a typed query and invalid input is rejected.
+func TestParseQuery(t *testing.T) {
+	println("=====================QUERY_UNIT_TEST====================")
+
+	t.Run("parses every filter", func(t *testing.T) {
+		values := url.Values{
+			"minPrice":     {"100"},
+			"maxPrice":     {"900.5"},
+			"currency":     {"usd"},
+			"from":         {"jfk"},
+			"to":           {"LAX"},
+			"departAfter":  {"2026-01-01"},
+			"departBefore": {"2026-01-02T12:00:00Z"},
+			"carrier":      {"aa"},
+			"status":       {"Confirmed"},
+			"maxStops":     {"1"},
+			"maxDuration":  {"6h"},
+			"source":       {"source1"},
+		}
+
+		q, err := service.ParseQuery(values)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, *q.MinPrice)
+		assert.Equal(t, 900.5, *q.MaxPrice)
+		assert.Equal(t, "USD", q.Currency)
+		assert.Equal(t, "JFK", q.From)
+		assert.Equal(t, "AA", q.Carrier)
+		assert.Equal(t, "confirmed", q.Status)
+		assert.Equal(t, 1, *q.MaxStops)
+		assert.Equal(t, "6h0m0s", q.MaxDuration.String())
+	})
+
+	t.Run("rejects invalid input", func(t *testing.T) {
+		for _, values := range []url.Values{
+			{"minPrice": {"abc"}},
+			{"maxPrice": {"-1"}},
+			{"minPrice": {"10"}, "maxPrice": {"5"}},
+			{"from": {"PARIS"}},
+			{"departAfter": {"tomorrow"}},
+			{"maxStops": {"one"}},
+			{"maxDuration": {"long"}},
+			{"carrier": {"A"}},
+		} {
+			_, err := service.ParseQuery(values)
+			assert.ErrorIs(t, err, service.ErrInvalidQuery, values.Encode())
+		}
+	})
+}
+
+// TestQuery_Apply verifies that all filters of a query are combined when applied to flights.
+func TestQuery_Apply(t *testing.T) {
+	flights := createMockFlightsWithConnections()
+
+	t.Run("empty query keeps every flight", func(t *testing.T) {
+		assert.Len(t, service.Query{}.Apply(flights), 2)
+	})
+
+	t.Run("combines filters", func(t *testing.T) {
+		q, err := service.ParseQuery(url.Values{"from": {"JFK"}, "to": {"LAX"}, "maxPrice": {"500"}})
+		assert.NoError(t, err)
+
+		result := q.Apply(flights)
+
+		assert.Len(t, result, 1)
+		assert.Equal(t, "2", result[0].ID())
+	})
+
+	t.Run("converts totals into the currency of the price bounds", func(t *testing.T) {
+		// 400 USD and 600 USD are about 370 EUR and 556 EUR
+		eur, err := service.ParseQuery(url.Values{"minPrice": {"500"}})
+		assert.NoError(t, err)
+		result := eur.Apply(flights)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "1", result[0].ID())
+
+		usd, err := service.ParseQuery(url.Values{"maxPrice": {"500"}, "currency": {"USD"}})
+		assert.NoError(t, err)
+		result = usd.Apply(flights)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "2", result[0].ID())
+
+		_, err = service.ParseQuery(url.Values{"currency": {"XXX"}})
+		assert.ErrorIs(t, err, service.ErrUnknownCurrency)
+	})
+
+	t.Run("filters on stops, carrier and duration", func(t *testing.T) {
+		direct, _ := service.ParseQuery(url.Values{"maxStops": {"0"}})
+		assert.Len(t, direct.Apply(flights), 1)
+
+		carrier, _ := service.ParseQuery(url.Values{"carrier": {"AA"}})
+		result := carrier.Apply(flights)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "1", result[0].ID())
+
+		short, _ := service.ParseQuery(url.Values{"maxDuration": {"300"}})
+		assert.Len(t, short.Apply(flights), 1)
+	})
+}
+
+// TestFilterFlights verifies that FilterFlights applies the query to the aggregated flights.
+func TestFilterFlights(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := new(MockFlightsRepository)
+	mockRepo.On("List", ctx).Return(createMockFlights(), nil)
+
+	q, err := service.ParseQuery(url.Values{"source": {"source1"}})
+	assert.NoError(t, err)
+
+	result, err := service.FilterFlights(ctx, repo.NewMulti(mockRepo), q)
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/service"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReconcile verifies that fields on which providers disagree are reported with every sourced value.
+func TestReconcile(t *testing.T) {
+	println("=====================RECONCILIATION_UNIT_TEST====================")
+
+	t.Run("reports mismatching fields with their source", func(t *testing.T) {
+		report := service.Reconcile(createDuplicateFlights())
+
+		assert.Equal(t, 3, report.Bookings)
+		assert.Equal(t, 1, report.Compared)
+
+		fields := make(map[string]service.FieldMismatch)
+		for _, m := range report.Mismatches {
+			fields[m.Field] = m
+		}
+		assert.Len(t, fields, 3)
+		assert.Contains(t, fields, "total.amount")
+		assert.Contains(t, fields, "segments[0].depart")
+		assert.Contains(t, fields, "segments[0].arrive")
+
+		price := fields["total.amount"]
+		assert.Equal(t, "JL046", price.FlightNumber)
+		assert.Equal(t, []service.SourcedValue{
+			{Source: "flights", ID: "A10001", Value: "850.00"},
+			{Source: "flight_to_book", ID: "B30002", Value: "1020.00"},
+		}, price.Values)
+	})
+
+	t.Run("ignores bookings known by a single provider", func(t *testing.T) {
+		flights := createDuplicateFlights()
+
+		report := service.Reconcile(domain.Flights{flights[0], flights[2]})
+
+		assert.Equal(t, 0, report.Compared)
+		assert.Empty(t, report.Mismatches)
+	})
+
+	t.Run("ignores the same flight number on another day", func(t *testing.T) {
+		flights := createDuplicateFlights()
+		s := flights[1].Segments()[0]
+		later := domain.NewSegment(s.FlightNumber(), s.Departure(), s.Arrival(), s.DepartTime().AddDate(0, 0, 7), s.ArriveTime().AddDate(0, 0, 7))
+		other := domain.NewFlight(flights[1].ID(), flights[1].Status(), flights[1].PassengerName(), []domain.Segment{later},
+			flights[1].Total(), flights[1].Source())
+
+		report := service.Reconcile(domain.Flights{flights[0], *other})
+
+		assert.Equal(t, 0, report.Compared)
+		assert.Empty(t, report.Mismatches)
+	})
+
+	t.Run("writes one CSV row per sourced value", func(t *testing.T) {
+		report := service.Reconcile(createDuplicateFlights())
+
+		var buf bytes.Buffer
+		err := report.WriteCSV(&buf)
+
+		assert.NoError(t, err)
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		assert.Len(t, lines, 1+2*len(report.Mismatches))
+		assert.Equal(t, "passengerName,flightNumber,field,source,id,value", string(lines[0]))
+	})
+}
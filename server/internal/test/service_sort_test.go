@@ -0,0 +1,110 @@
+package test
+
+import (
+	"aggregator/internal/domain"
+	"aggregator/internal/repo"
+	"aggregator/internal/service"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseSort verifies parsing of multi-key sort specifications.
+func TestParseSort(t *testing.T) {
+	println("=====================SORT_UNIT_TEST====================")
+
+	spec, err := service.ParseSort("price:asc, Departure:DESC,time")
+	assert.NoError(t, err)
+	assert.Equal(t, service.SortSpec{
+		{Key: service.SortPrice},
+		{Key: service.SortDeparture, Desc: true},
+		{Key: service.SortDuration},
+	}, spec)
+	assert.Equal(t, "price:asc,departure:desc,duration:asc", spec.String())
+
+	for _, s := range []string{"", "speed", "price:up", "price,price:desc"} {
+		_, err := service.ParseSort(s)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, s)
+	}
+}
+
+// TestSortSpec_Sort verifies multi-key, bidirectional ordering with tie-breakers and placement of flights without segments.
+func TestSortSpec_Sort(t *testing.T) {
+	now := time.Now()
+
+	t.Run("sorts on several keys in both directions", func(t *testing.T) {
+		flights := createMockFlights()
+		extra := domain.NewFlight(
+			"4",
+			"confirmed",
+			"Extra",
+			[]domain.Segment{domain.NewSegment("BA1", "LHR", "JFK", now.Add(4*time.Hour), now.Add(11*time.Hour))},
+			domain.NewTotal(400.00, "USD"),
+			"source2",
+		)
+		flights = append(flights, *extra)
+
+		spec, _ := service.ParseSort("price:desc,departure:desc")
+		spec.Sort(flights)
+
+		assert.Equal(t, []string{"1", "4", "3", "2"}, flightIDs(flights))
+	})
+
+	t.Run("breaks ties on source then id", func(t *testing.T) {
+		flights := createMockFlights()
+
+		spec, _ := service.ParseSort("stops")
+		spec.Sort(flights)
+
+		assert.Equal(t, []string{"1", "3", "2"}, flightIDs(flights))
+	})
+
+	t.Run("places flights without segments last in both directions", func(t *testing.T) {
+		empty := domain.NewFlight("0", "confirmed", "", nil, domain.NewTotal(1, "USD"), "source0")
+
+		for _, s := range []string{"departure:asc", "departure:desc", "carrier:desc", "layover"} {
+			flights := append(domain.Flights{*empty}, createMockFlights()...)
+			spec, _ := service.ParseSort(s)
+			spec.Sort(flights)
+			assert.Equal(t, "0", flights[len(flights)-1].ID(), s)
+		}
+	})
+
+	t.Run("sorts on layover and arrival", func(t *testing.T) {
+		flights := createMockFlightsWithConnections()
+
+		spec, _ := service.ParseSort("layover:desc")
+		spec.Sort(flights)
+		assert.Equal(t, []string{"1", "2"}, flightIDs(flights))
+
+		spec, _ = service.ParseSort("arrival")
+		spec.Sort(flights)
+		assert.Equal(t, []string{"2", "1"}, flightIDs(flights))
+	})
+}
+
+// TestSortFlights verifies that SortFlights sorts the aggregated flights.
+func TestSortFlights(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := new(MockFlightsRepository)
+	mockRepo.On("List", ctx).Return(createMockFlights(), nil)
+
+	spec, _ := service.ParseSort("departure:desc")
+	sorted, err := service.SortFlights(ctx, repo.NewMulti(mockRepo), spec)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3", "1", "2"}, flightIDs(sorted))
+
+	mockRepo.AssertExpectations(t)
+}
+
+// TestTotalLayover checks the ground time computed between segments.
+func TestTotalLayover(t *testing.T) {
+	flights := createMockFlightsWithConnections()
+
+	assert.Equal(t, time.Hour, service.TotalLayover(flights[0]))
+	assert.Equal(t, time.Duration(0), service.TotalLayover(flights[1]))
+}
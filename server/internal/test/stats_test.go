@@ -0,0 +1,94 @@
+package test
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/domain"
+	"aggregator/internal/service"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createStatsFlights generates three direct CDG-HND bookings, one of them in USD, a connection to JFK,
+// a cancelled booking in an unknown currency and one without segments.
+func createStatsFlights() domain.Flights {
+	seg := func(number, from, to string, depart time.Time, hours int) domain.Segment {
+		return newTestSegment(number, from, to, depart, time.Duration(hours)*time.Hour)
+	}
+
+	return domain.Flights{
+		newTestBooking("A10001", "confirmed", "flights", domain.NewTotal(800, "EUR"), seg("AF276", "CDG", "HND", testDay, 14)),
+		newTestBooking("A10002", "Confirmed", "flights", domain.NewTotal(1080, "USD"), seg("JL046", "CDG", "HND", testDay, 13)),
+		newTestBooking("B30001", "confirmed", "flight_to_book", domain.NewTotal(1300, "EUR"), seg("AF276", "CDG", "HND", testDay, 14)),
+		newTestBooking("B30002", "confirmed", "flight_to_book", domain.NewTotal(600, "EUR"),
+			seg("LH1035", "CDG", "FRA", testDay, 1), seg("LH400", "FRA", "JFK", testDay.Add(3*time.Hour), 9)),
+		newTestBooking("B30003", "cancelled", "flight_to_book", domain.NewTotal(500, "XXX"), seg("AF006", "CDG", "JFK", testDay, 8)),
+		newTestBooking("B30004", "confirmed", "flight_to_book", domain.NewTotal(100, "EUR")),
+	}
+}
+
+// TestComputeStats verifies counts, price statistics per route and average durations.
+func TestComputeStats(t *testing.T) {
+	println("=====================STATS_UNIT_TEST====================")
+
+	stats := service.ComputeStats(createStatsFlights(), "")
+
+	assert.Equal(t, 6, stats.Bookings)
+	assert.Equal(t, map[string]int{"flights": 2, "flight_to_book": 4}, stats.BySource)
+	assert.Equal(t, map[string]int{"confirmed": 5, "cancelled": 1}, stats.ByStatus)
+	assert.Equal(t, map[string]int{"AF": 3, "JL": 1, "LH": 1}, stats.ByCarrier)
+	// A10001 and B30001 are on the same AF276
+	assert.Equal(t, 5, stats.PhysicalFlights)
+	assert.Equal(t, 1, stats.UnpricedBookings)
+
+	assert.Equal(t, []service.RouteStats{
+		{Route: "CDG-HND", From: "CDG", To: "HND", Bookings: 3,
+			Price: &service.PriceStats{Currency: "EUR", Min: 800, Avg: 1033.33, Median: 1000, Max: 1300}},
+		{Route: "CDG-JFK", From: "CDG", To: "JFK", Bookings: 2,
+			Price: &service.PriceStats{Currency: "EUR", Min: 600, Avg: 600, Median: 600, Max: 600}},
+	}, stats.Routes)
+
+	// (14 + 13 + 14 + 12 + 8) hours over five bookings with segments, and 2 hours of layover on the only connection
+	assert.Equal(t, 732.0, stats.AverageDurationMinutes)
+	assert.Equal(t, 120.0, stats.AverageLayoverMinutes)
+
+	inUSD := service.ComputeStats(createStatsFlights(), "USD")
+	assert.Equal(t, "USD", inUSD.Routes[0].Price.Currency)
+	assert.InDelta(t, 864, inUSD.Routes[0].Price.Min, 0.001)
+
+	empty := service.ComputeStats(nil, "")
+	assert.Zero(t, empty.Bookings)
+	assert.Empty(t, empty.Routes)
+	assert.Zero(t, empty.AverageDurationMinutes)
+}
+
+// TestParseStatsQuery verifies that /stats accepts the search filters and a price currency.
+func TestParseStatsQuery(t *testing.T) {
+	q, err := service.ParseStatsQuery(url.Values{"from": {"cdg"}, "status": {"confirmed"}})
+	assert.NoError(t, err)
+	assert.Equal(t, service.BaseCurrency, q.PriceCurrency)
+	assert.Equal(t, []string{"A10001", "A10002", "B30001", "B30002"}, flightIDs(q.Filters.Apply(createStatsFlights())))
+
+	q, err = service.ParseStatsQuery(url.Values{"priceCurrency": {"usd"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", q.PriceCurrency)
+
+	for _, values := range []url.Values{{"priceCurrency": {"XXX"}}, {"maxStops": {"-1"}}} {
+		_, err = service.ParseStatsQuery(values)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, values.Encode())
+	}
+}
+
+// TestGetStats verifies that statistics summarize the matching bookings of a catalogue version and report its number.
+func TestGetStats(t *testing.T) {
+	q, err := service.ParseStatsQuery(url.Values{"to": {"HND"}})
+	assert.NoError(t, err)
+
+	stats := service.GetStats(catalogue.Version{Number: 3, Flights: createStatsFlights()}, q)
+
+	assert.Equal(t, uint64(3), stats.Version)
+	assert.Equal(t, 3, stats.Bookings)
+	assert.Len(t, stats.Routes, 1)
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"aggregator/internal/catalogue"
+	"aggregator/internal/changes"
+	"aggregator/internal/domain"
+	"aggregator/internal/handler"
+	"aggregator/internal/service"
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// streamTypes returns the type of every event.
+func streamTypes(events []service.StreamEvent) []string {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		out = append(out, e.Type)
+	}
+	return out
+}
+
+// TestFeed_Notify verifies that recording a change wakes the waiting streams.
+func TestFeed_Notify(t *testing.T) {
+	println("=====================STREAM_UNIT_TEST====================")
+
+	feed := changes.New(10)
+	cat := catalogue.New()
+	cat.Subscribe(feed.Record)
+
+	notify := feed.Notify()
+	select {
+	case <-notify:
+		t.Fatal("notified before any change")
+	default:
+	}
+	cat.Update(domain.Flights{historyFlight("A10001", 1, 900, "EUR")})
+	select {
+	case <-notify:
+	default:
+		t.Fatal("not notified of the change")
+	}
+	assert.NotEqual(t, notify, feed.Notify())
+}
+
+// TestFlightStream verifies the events of a filtered stream as bookings enter, change and leave the filtered set.
+func TestFlightStream(t *testing.T) {
+	q, err := service.ParseQuery(url.Values{"maxPrice": {"850"}})
+	assert.NoError(t, err)
+	stream := service.NewFlightStream(q)
+
+	v1 := domain.Flights{historyFlight("A10001", 1, 900, "EUR"), historyFlight("A10002", 1, 800, "EUR")}
+	snapshot := stream.Snapshot(1, v1)
+	assert.Equal(t, service.EventSnapshot, snapshot.Type)
+	data := snapshot.Data.(service.StreamSnapshot)
+	assert.Equal(t, uint64(1), data.Version)
+	assert.Len(t, data.Flights, 1)
+	assert.Equal(t, "A10002", data.Flights[0].ID)
+
+	// A10001 gets cheap enough, A10002 too expensive, A10003 appears
+	v2 := domain.Flights{historyFlight("A10001", 1, 820, "EUR"), historyFlight("A10002", 1, 870, "EUR"), historyFlight("A10003", 2, 700, "EUR")}
+	change := changes.Diff(v1, v2)
+	change.Version = 2
+	events := stream.Apply(change)
+	assert.Equal(t, []string{"add", "add", "remove"}, streamTypes(events))
+	assert.Equal(t, "A10003", events[0].Data.(domain.FlightSnapshot).ID)
+	assert.Equal(t, "A10001", events[1].Data.(domain.FlightSnapshot).ID)
+	assert.Equal(t, changes.BookingRef{Source: "flights", ID: "A10002"}, events[2].Data)
+	assert.Equal(t, uint64(2), events[2].Version)
+
+	// a visible booking changing is updated, a hidden one leaving is not reported
+	v3 := domain.Flights{historyFlight("A10001", 1, 810, "EUR"), historyFlight("A10003", 2, 700, "EUR")}
+	events = stream.Apply(changes.Diff(v2, v3))
+	assert.Equal(t, []string{"update"}, streamTypes(events))
+
+	// after a resumption, the bookings the client holds are unknown
+	stream.Resume()
+	events = stream.Apply(changes.Diff(v3, domain.Flights{historyFlight("A10001", 1, 805, "EUR")}))
+	assert.Equal(t, []string{"update", "remove"}, streamTypes(events))
+}
+
+// firstStreamEvent connects to a flight stream with the given Last-Event-ID and returns the type and id of its first
+// event, the id being empty when the event does not carry one.
+func firstStreamEvent(t *testing.T, target, lastEventID string) (string, string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	assert.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return "", ""
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var event, id string
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() && lines.Text() != "" {
+		if v, ok := strings.CutPrefix(lines.Text(), "event: "); ok {
+			event = v
+		}
+		if v, ok := strings.CutPrefix(lines.Text(), "id: "); ok {
+			id = v
+		}
+	}
+	return event, id
+}
+
+// TestGetFlightsStream_LastEventID verifies that a reconnecting client resumes from its Last-Event-ID, and gets a new
+// snapshot when the id belongs to another epoch or its changes have left the change feed.
+func TestGetFlightsStream_LastEventID(t *testing.T) {
+	prevCatalogue, prevFeed := catalogue.Default, changes.Default
+	defer func() { catalogue.Default, changes.Default = prevCatalogue, prevFeed }()
+
+	// the feed keeps the changes of versions 3 and 4 only
+	catalogue.Default, changes.Default = catalogue.New(), changes.New(2)
+	catalogue.Default.Subscribe(changes.Default.Record)
+	for _, flights := range []domain.Flights{
+		{historyFlight("A10001", 1, 900, "EUR")},
+		{historyFlight("A10001", 1, 800, "EUR")},
+		{historyFlight("A10001", 1, 800, "EUR"), historyFlight("A10002", 1, 700, "EUR")},
+		{historyFlight("A10001", 1, 750, "EUR"), historyFlight("A10002", 1, 700, "EUR")},
+	} {
+		catalogue.Default.Update(flights)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler.GetFlightsStream))
+	defer server.Close()
+	epoch := changes.Default.Epoch()
+
+	for _, tc := range []struct {
+		name, lastEventID, event string
+	}{
+		{"new client", "", service.EventSnapshot},
+		{"valid id", epoch + "-3", service.EventUpdate},
+		{"id from an old epoch", "previous-3", service.EventSnapshot},
+		{"id that left the change feed", epoch + "-1", service.EventSnapshot},
+		{"malformed id", "garbage", service.EventSnapshot},
+	} {
+		event, id := firstStreamEvent(t, server.URL, tc.lastEventID)
+		assert.Equal(t, tc.event, event, tc.name)
+		assert.Equal(t, epoch+"-4", id, tc.name)
+	}
+}
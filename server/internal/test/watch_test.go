@@ -0,0 +1,191 @@
+package test
+
+import (
+	"aggregator/internal/service"
+	"aggregator/internal/ws"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseWatch verifies the validation and normalization of route and booking watches.
+func TestParseWatch(t *testing.T) {
+	println("=====================WATCH_UNIT_TEST====================")
+
+	w, err := service.ParseWatch(service.Watch{Route: " cdg-hnd ", Date: "2026-01-01"})
+	assert.NoError(t, err)
+	assert.Equal(t, "CDG-HND", w.Route)
+	assert.True(t, w.Match(historyFlight("A10001", 1, 900, "EUR")))
+	assert.False(t, w.Match(historyFlight("A10001", 2, 900, "EUR")))
+
+	w, err = service.ParseWatch(service.Watch{Booking: "A10001", Source: "flights"})
+	assert.NoError(t, err)
+	assert.True(t, w.Match(historyFlight("A10001", 2, 900, "EUR")))
+	assert.False(t, w.Match(historyFlight("A10002", 2, 900, "EUR")))
+
+	for _, invalid := range []service.Watch{
+		{},
+		{Route: "CDG-HND", Booking: "A10001"},
+		{Route: "CDGHND"},
+		{Route: "CDG-HND", Date: "2026-1-1"},
+		{Booking: "A10001", Date: "2026-01-01"},
+		{Route: "CDG-HND", Source: "flights"},
+	} {
+		_, err = service.ParseWatch(invalid)
+		assert.ErrorIs(t, err, service.ErrInvalidQuery, invalid)
+	}
+}
+
+// wsClient is the client side of a WebSocket connection, enough to exercise the server.
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialWS opens a WebSocket connection to a test server and checks the handshake answer.
+func dialWS(t *testing.T, server *httptest.Server) *wsClient {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	assert.NoError(t, err)
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	assert.NoError(t, err)
+
+	c := &wsClient{conn: conn, br: bufio.NewReader(conn)}
+	resp, err := http.ReadResponse(c.br, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	// the sample key and answer of RFC 6455
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	return c
+}
+
+// send writes a masked frame, as clients must.
+func (c *wsClient) send(t *testing.T, head byte, payload []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{head, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := c.conn.Write(frame)
+	assert.NoError(t, err)
+}
+
+// read returns the opcode and payload of the next frame sent by the server.
+func (c *wsClient) read(t *testing.T) (byte, []byte) {
+	var head [2]byte
+	_, err := io.ReadFull(c.br, head[:])
+	assert.NoError(t, err)
+	assert.Zero(t, head[1]&0x80, "server frames are not masked")
+	size := int(head[1] & 0x7F)
+	if size == 126 {
+		var ext [2]byte
+		_, err = io.ReadFull(c.br, ext[:])
+		assert.NoError(t, err)
+		size = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, size)
+	_, err = io.ReadFull(c.br, payload)
+	assert.NoError(t, err)
+	return head[0] & 0x0F, payload
+}
+
+// TestWebSocket verifies the handshake, fragmented messages, pings and the closing handshake.
+func TestWebSocket(t *testing.T) {
+	closed := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := ws.Upgrade(w, r, ws.Options{MaxSize: 16, WriteTimeout: time.Second, ReadTimeout: 200 * time.Millisecond})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, payload, err := conn.ReadMessage()
+			if err != nil {
+				closed <- err
+				return
+			}
+			_ = conn.WriteText(payload)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	c := dialWS(t, server)
+	defer c.conn.Close()
+
+	c.send(t, 0x01, []byte("hel"))
+	c.send(t, 0x89, []byte("ping"))
+	c.send(t, 0x80, []byte("lo"))
+	op, payload := c.read(t)
+	assert.Equal(t, []any{byte(0xA), "ping"}, []any{op, string(payload)})
+	op, payload = c.read(t)
+	assert.Equal(t, []any{byte(0x1), "hello"}, []any{op, string(payload)})
+
+	// a message above the limit closes the connection
+	c.send(t, 0x81, []byte("this message is too big"))
+	op, payload = c.read(t)
+	assert.Equal(t, byte(0x8), op)
+	assert.Equal(t, ws.CloseTooBig, int(binary.BigEndian.Uint16(payload)))
+
+	var closeErr *ws.CloseError
+	assert.True(t, errors.As(<-closed, &closeErr))
+	assert.Equal(t, ws.CloseTooBig, closeErr.Code)
+
+	// a client silent past the read timeout is disconnected
+	idle := dialWS(t, server)
+	defer idle.conn.Close()
+	op, payload = idle.read(t)
+	assert.Equal(t, byte(0x8), op)
+	assert.Equal(t, ws.CloseGoingAway, int(binary.BigEndian.Uint16(payload)))
+	assert.True(t, errors.As(<-closed, &closeErr))
+	assert.Equal(t, ws.CloseGoingAway, closeErr.Code)
+}
+
+// TestWebSocket_Origin verifies that browser pages may only connect from the server's origin or an allowed one.
+func TestWebSocket_Origin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := ws.Upgrade(w, r, ws.Options{MaxSize: 16, AllowedOrigins: []string{"https://app.example.com/"}})
+		if err != nil {
+			return
+		}
+		_ = conn.Close()
+	}))
+	defer server.Close()
+
+	for origin, want := range map[string]int{
+		"":                         http.StatusSwitchingProtocols,
+		server.URL:                 http.StatusSwitchingProtocols,
+		"https://app.example.com":  http.StatusSwitchingProtocols,
+		"https://evil.example.com": http.StatusForbidden,
+		"null":                     http.StatusForbidden,
+	} {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		assert.NoError(t, err)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err, origin)
+		assert.Equal(t, want, resp.StatusCode, origin)
+		_ = resp.Body.Close()
+	}
+}
@@ -0,0 +1,84 @@
+package textnorm
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// letterFold spells out letters that Unicode does not decompose into a base letter and a diacritic.
+var letterFold = strings.NewReplacer(
+	"ø", "o", "Ø", "o",
+	"ł", "l", "Ł", "l",
+	"đ", "d", "Đ", "d",
+	"æ", "ae", "Æ", "ae",
+	"œ", "oe", "Œ", "oe",
+	"ı", "i",
+)
+
+// Fold normalizes text for comparison: Unicode compatibility decomposition, diacritics stripped, case folded,
+// punctuation turned into spaces and whitespace collapsed. "  Évariste-GALOIS " becomes "evariste galois".
+func Fold(s string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(t, letterFold.Replace(s))
+	if err != nil {
+		stripped = s
+	}
+	folded := cases.Fold().String(stripped)
+	return strings.Join(strings.FieldsFunc(folded, isSeparator), " ")
+}
+
+// isSeparator reports whether r separates words once text is folded.
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Tokens returns the folded words of s.
+func Tokens(s string) []string {
+	return strings.Fields(Fold(s))
+}
+
+// NameKey returns a key that is equal for names differing only in case, diacritics, spacing or word order,
+// so that "Curie Marie" and "marie CURÍE" share the key "curie marie".
+func NameKey(s string) string {
+	tokens := Tokens(s)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// Levenshtein returns the edit distance between a and b, counted in runes.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// Similarity returns 1 minus the edit distance of a and b relative to the longest of them: 1 for equal strings,
+// 0 for strings with nothing in common.
+func Similarity(a, b string) float64 {
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(Levenshtein(a, b))/float64(longest)
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned for webhook URLs, or addresses they resolve to, on the loopback, private or link-local
+// networks of the server.
+var ErrForbiddenTarget = errors.New("webhook target not allowed")
+
+// allowPrivate lets webhooks reach private networks, for receivers running next to the server.
+var allowPrivate atomic.Bool
+
+// AllowPrivateNetworks lets webhooks reach loopback, private and link-local addresses, or forbids it again.
+// Anyone able to register a webhook could otherwise make the server call its internal services.
+func AllowPrivateNetworks(allow bool) {
+	allowPrivate.Store(allow)
+}
+
+// CheckURL validates the URL of a webhook receiver: an absolute http(s) URL whose host is neither "localhost" nor
+// an address of a forbidden network. Host names are only resolved when a delivery is sent, where the dialer of the
+// dispatcher checks the address again.
+func CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url %q must be an absolute http(s) URL", raw)
+	}
+	if allowPrivate.Load() {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+	}
+	return nil
+}
+
+// publicIP reports whether an address may be called by webhooks: any but loopback, private, link-local, unspecified
+// and multicast addresses.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified() && !ip.IsMulticast()
+}
+
+// newClient returns the HTTP client of a dispatcher. Its dialer checks every address it connects to, after name
+// resolution and on redirects too, so that a host name pointing to a private address is refused as well.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			if allowPrivate.Load() {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+			}
+			return nil
+		},
+	}
+	// no proxy: the dialer must see the address of the receiver
+	transport := &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 5 * time.Second, MaxIdleConnsPerHost: 2}
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
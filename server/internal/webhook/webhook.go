@@ -0,0 +1,358 @@
+package webhook
+
+import (
+	"aggregator/internal/jsonfile"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of a webhook request. The signature is "sha256=" followed by the hex HMAC-SHA256, keyed by the secret of the
+// receiver, of the timestamp, a dot and the body: receivers recompute it to authenticate the request, and reject
+// old timestamps to prevent replays.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	// StatusFailed marks a dead letter: every attempt failed.
+	StatusFailed = "failed"
+)
+
+// DefaultMaxAttempts is the number of attempts of a delivery before it becomes a dead letter.
+const DefaultMaxAttempts = 5
+
+// ErrDeliveryNotFound is returned when a delivery is not, or no longer, in the log.
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// Delivery is one webhook event sent to one URL, and the outcome of its attempts.
+type Delivery struct {
+	ID string `json:"id"`
+	// Owner is the id of the alert or endpoint the delivery was made for.
+	Owner string `json:"owner"`
+	// RedeliveryOf is the id of the delivery this one repeats, when sent by hand.
+	RedeliveryOf string          `json:"redeliveryOf,omitempty"`
+	Event        string          `json:"event"`
+	URL          string          `json:"url"`
+	Body         json.RawMessage `json:"body"`
+	Status       string          `json:"status"`
+	Attempts     int             `json:"attempts"`
+	StatusCode   int             `json:"statusCode,omitempty"`
+	LastError    string          `json:"lastError,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	UpdatedAt    time.Time       `json:"updatedAt"`
+	secret       string
+}
+
+// Options configures a Dispatcher; zero fields take their defaults.
+type Options struct {
+	// Client sends the requests; by default, a client refusing the addresses forbidden by CheckURL.
+	Client      *http.Client
+	MaxAttempts int
+	// Backoff is the wait before the given retry (1 for the second attempt); it defaults to 1s doubling up to 1 minute.
+	Backoff func(retry int) time.Duration
+	// LogSize is the number of deliveries kept, the oldest delivered ones being dropped first. Pending deliveries and
+	// dead letters are never dropped, so the log may grow past it.
+	LogSize int
+	// Concurrency is the number of requests sent at once.
+	Concurrency int
+}
+
+// Dispatcher sends signed webhook requests in the background, retrying failures with backoff, and keeps a log of the
+// latest deliveries in memory. When it has a file, the dead letters of the log are saved to it.
+type Dispatcher struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     func(int) time.Duration
+	logSize     int
+	slots       chan struct{}
+
+	mu         sync.RWMutex
+	deliveries map[string]*Delivery
+	// order lists the ids of the logged deliveries, oldest first.
+	order []string
+	// path is the JSON file the dead letters are saved to, if any.
+	path string
+	// deadListeners are told about every delivery becoming a dead letter.
+	deadListeners []func(Delivery)
+}
+
+// storedDelivery is a dead letter as saved to the file, with the secret needed to redeliver it.
+type storedDelivery struct {
+	Delivery
+	Secret string `json:"secret"`
+}
+
+// Default is the dispatcher of the server.
+var Default = New(Options{})
+
+// New creates a dispatcher.
+func New(o Options) *Dispatcher {
+	if o.Client == nil {
+		o.Client = newClient()
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultMaxAttempts
+	}
+	if o.Backoff == nil {
+		o.Backoff = func(retry int) time.Duration { return min(time.Second<<(retry-1), time.Minute) }
+	}
+	if o.LogSize <= 0 {
+		o.LogSize = 500
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	return &Dispatcher{
+		client:      o.Client,
+		maxAttempts: o.MaxAttempts,
+		backoff:     o.Backoff,
+		logSize:     o.LogSize,
+		slots:       make(chan struct{}, o.Concurrency),
+		deliveries:  make(map[string]*Delivery),
+	}
+}
+
+// Open loads the dead letters of a JSON file, if it exists, into the log of a new dispatcher saving them to it, so that
+// they can still be listed and redelivered after a restart.
+func Open(path string, o Options) (*Dispatcher, error) {
+	d := New(o)
+	d.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read dead letters: %w", err)
+	}
+	var list []storedDelivery
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("decode dead letters %s: %w", path, err)
+	}
+	for _, stored := range list {
+		delivery := stored.Delivery
+		delivery.secret = stored.Secret
+		d.deliveries[delivery.ID] = &delivery
+		d.order = append(d.order, delivery.ID)
+	}
+	d.trim()
+	return d, nil
+}
+
+// SubscribeDeadLetters registers a function called, in the background, with every delivery whose last attempt failed.
+func (d *Dispatcher) SubscribeDeadLetters(fn func(Delivery)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deadListeners = append(d.deadListeners, fn)
+}
+
+// Send logs a delivery of the payload, encoded as JSON, to the URL and sends it in the background.
+func (d *Dispatcher) Send(owner, event, url, secret string, payload any) (Delivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("encode webhook: %w", err)
+	}
+	return d.enqueue(&Delivery{Owner: owner, Event: event, URL: url, Body: body, secret: secret}), nil
+}
+
+// Redeliver sends a logged delivery again, whatever its outcome, as a new delivery with the same body.
+func (d *Dispatcher) Redeliver(id string) (Delivery, error) {
+	d.mu.RLock()
+	old, ok := d.deliveries[id]
+	if !ok {
+		d.mu.RUnlock()
+		return Delivery{}, fmt.Errorf("%w: %s", ErrDeliveryNotFound, id)
+	}
+	delivery := &Delivery{Owner: old.Owner, RedeliveryOf: old.ID, Event: old.Event, URL: old.URL, Body: old.Body, secret: old.secret}
+	d.mu.RUnlock()
+	return d.enqueue(delivery), nil
+}
+
+// enqueue logs a new delivery and starts sending it.
+func (d *Dispatcher) enqueue(delivery *Delivery) Delivery {
+	now := time.Now().UTC()
+	delivery.ID, delivery.Status, delivery.CreatedAt, delivery.UpdatedAt = NewID(), StatusPending, now, now
+
+	d.mu.Lock()
+	d.deliveries[delivery.ID] = delivery
+	d.order = append(d.order, delivery.ID)
+	d.trim()
+	copied := *delivery
+	d.mu.Unlock()
+
+	go d.run(delivery.ID)
+	return copied
+}
+
+// trim drops the oldest delivered deliveries above the log size. Dead letters stay until redelivered by hand, and in
+// the file, whatever the number of deliveries sent since. The caller holds the lock.
+func (d *Dispatcher) trim() {
+	for i := 0; len(d.order) > d.logSize && i < len(d.order); {
+		if d.deliveries[d.order[i]].Status != StatusDelivered {
+			i++
+			continue
+		}
+		delete(d.deliveries, d.order[i])
+		d.order = append(d.order[:i], d.order[i+1:]...)
+	}
+}
+
+// run attempts a delivery until it succeeds or runs out of attempts, waiting between attempts.
+func (d *Dispatcher) run(id string) {
+	for attempt := 1; ; attempt++ {
+		d.slots <- struct{}{}
+		code, err := d.attempt(id)
+		<-d.slots
+
+		d.mu.Lock()
+		delivery, ok := d.deliveries[id]
+		if !ok {
+			d.mu.Unlock()
+			return
+		}
+		delivery.Attempts++
+		delivery.StatusCode, delivery.UpdatedAt = code, time.Now().UTC()
+		switch {
+		case err == nil:
+			delivery.Status, delivery.LastError = StatusDelivered, ""
+		case attempt >= d.maxAttempts:
+			delivery.Status, delivery.LastError = StatusFailed, err.Error()
+		default:
+			delivery.LastError = err.Error()
+		}
+		done := delivery.Status != StatusPending
+		var listeners []func(Delivery)
+		if delivery.Status == StatusFailed {
+			if err := d.save(); err != nil {
+				fmt.Fprintln(os.Stderr, "webhook:", err)
+			}
+			listeners = d.deadListeners
+		}
+		copied := *delivery
+		d.mu.Unlock()
+
+		for _, fn := range listeners {
+			fn(copied)
+		}
+		if done {
+			return
+		}
+		time.Sleep(d.backoff(attempt))
+	}
+}
+
+// attempt sends a delivery once. Any answer other than 2xx is a failure.
+func (d *Dispatcher) attempt(id string) (int, error) {
+	d.mu.RLock()
+	delivery, ok := d.deliveries[id]
+	if !ok {
+		d.mu.RUnlock()
+		return 0, ErrDeliveryNotFound
+	}
+	event, url, body, secret := delivery.Event, delivery.URL, delivery.Body, delivery.secret
+	d.mu.RUnlock()
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Deliveries returns the logged deliveries, oldest first.
+func (d *Dispatcher) Deliveries() []Delivery {
+	return d.filter(func(*Delivery) bool { return true })
+}
+
+// DeadLetters returns the logged deliveries whose every attempt failed, oldest first.
+func (d *Dispatcher) DeadLetters() []Delivery {
+	return d.filter(func(delivery *Delivery) bool { return delivery.Status == StatusFailed })
+}
+
+// filter returns copies of the matching deliveries, oldest first.
+func (d *Dispatcher) filter(match func(*Delivery) bool) []Delivery {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := []Delivery{}
+	for _, id := range d.order {
+		if delivery := d.deliveries[id]; match(delivery) {
+			out = append(out, *delivery)
+		}
+	}
+	return out
+}
+
+// save writes the logged dead letters to the file, if any. The caller holds the lock.
+func (d *Dispatcher) save() error {
+	if d.path == "" {
+		return nil
+	}
+	list := []storedDelivery{}
+	for _, id := range d.order {
+		if delivery := d.deliveries[id]; delivery.Status == StatusFailed {
+			list = append(list, storedDelivery{Delivery: *delivery, Secret: delivery.secret})
+		}
+	}
+	if err := jsonfile.Write(d.path, list); err != nil {
+		return fmt.Errorf("save dead letters: %w", err)
+	}
+	return nil
+}
+
+// Sign returns the signature header value of a body sent at a Unix timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether a signature header value matches the body, in constant time.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// NewID returns a random identifier of 16 hex characters.
+func NewID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewSecret returns a random signing secret of 32 hex characters.
+func NewSecret() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
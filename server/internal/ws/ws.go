@@ -0,0 +1,337 @@
+package ws
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID is appended to the client key to compute Sec-WebSocket-Accept (RFC 6455, section 4.2.2).
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes (RFC 6455, section 5.2).
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close codes (RFC 6455, section 7.4.1).
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	ClosePolicyViolation = 1008
+	CloseTooBig          = 1009
+	CloseTryAgainLater   = 1013
+)
+
+// ErrHandshake is returned when a request is not a valid WebSocket opening handshake.
+var ErrHandshake = errors.New("websocket handshake")
+
+// CloseError is returned by ReadMessage once the peer closed the connection, or after a protocol error.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// Options are the limits of a connection and the pages allowed to open it.
+type Options struct {
+	// MaxSize is the largest message accepted from the client, in bytes; larger ones close the connection.
+	MaxSize int64
+	// WriteTimeout is how long a write may take before it fails; 0 waits forever.
+	WriteTimeout time.Duration
+	// ReadTimeout is how long the client may stay silent, pongs included, before the connection is closed; 0 waits forever.
+	ReadTimeout time.Duration
+	// AllowedOrigins are the origins, such as "https://app.example.com", of the browser pages that may connect besides
+	// those of the server itself; "*" allows any page.
+	AllowedOrigins []string
+}
+
+// Conn is a server side WebSocket connection. One goroutine may read while another writes.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	// maxSize is the largest message accepted from the client, in bytes.
+	maxSize int64
+	// readTimeout is how long the client may stay silent; every frame read extends the deadline.
+	readTimeout time.Duration
+
+	wmu          sync.Mutex
+	bw           *bufio.Writer
+	writeTimeout time.Duration
+	closeSent    bool
+}
+
+// Upgrade performs the opening handshake of a WebSocket request and takes over its connection, within the limits of
+// the options. On an invalid handshake it answers 400 (426 for an unsupported version, 403 for an origin that is not
+// allowed) and returns an error wrapping ErrHandshake.
+func Upgrade(w http.ResponseWriter, r *http.Request, opts Options) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	switch {
+	case r.Method != http.MethodGet:
+		return nil, handshakeError(w, http.StatusMethodNotAllowed, "method must be GET")
+	case !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket"):
+		return nil, handshakeError(w, http.StatusBadRequest, "not a websocket upgrade request")
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, handshakeError(w, http.StatusUpgradeRequired, "unsupported websocket version")
+	case key == "":
+		return nil, handshakeError(w, http.StatusBadRequest, "missing Sec-WebSocket-Key")
+	case !originAllowed(r, opts.AllowedOrigins):
+		return nil, handshakeError(w, http.StatusForbidden, "origin not allowed")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, handshakeError(w, http.StatusInternalServerError, "connection cannot be taken over")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrHandshake, err)
+	}
+
+	c := &Conn{conn: conn, br: rw.Reader, bw: rw.Writer, maxSize: opts.MaxSize, readTimeout: opts.ReadTimeout,
+		writeTimeout: opts.WriteTimeout}
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := c.flush([]byte(response)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("%w: %w", ErrHandshake, err)
+	}
+	return c, nil
+}
+
+// handshakeError answers a rejected handshake and returns the matching error.
+func handshakeError(w http.ResponseWriter, status int, msg string) error {
+	http.Error(w, msg, status)
+	return fmt.Errorf("%w: %s", ErrHandshake, msg)
+}
+
+// originAllowed reports whether the page a request comes from may open a connection. Browsers always send the Origin
+// header, so requests without one come from other clients; pages of the server itself and allowed origins are accepted,
+// so that another site cannot act with the cookies or network position of its visitors.
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerHas reports whether a comma separated header contains a token, ignoring case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, item := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next text or binary message, reassembling fragments. Pings are answered and pongs skipped.
+// Once the client closes the connection, breaks the protocol or stays silent past the read timeout, the connection is
+// closed and a *CloseError returned.
+func (c *Conn) ReadMessage() (text bool, payload []byte, err error) {
+	var message []byte
+	started := false
+	for {
+		fin, op, data, err := c.readFrame()
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			err = &CloseError{Code: CloseGoingAway, Reason: "idle timeout"}
+		}
+		if err != nil {
+			var ce *CloseError
+			if errors.As(err, &ce) {
+				_ = c.WriteClose(ce.Code, ce.Reason)
+			}
+			return false, nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.write(opPong, data); err != nil {
+				return false, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			ce := &CloseError{Code: CloseNormal}
+			if len(data) >= 2 {
+				ce.Code, ce.Reason = int(binary.BigEndian.Uint16(data)), string(data[2:])
+			}
+			_ = c.WriteClose(ce.Code, "")
+			return false, nil, ce
+		case opText, opBinary:
+			if started {
+				return false, nil, c.fail(CloseProtocolError, "new message inside a fragmented one")
+			}
+			started, text = true, op == opText
+		case opContinuation:
+			if !started {
+				return false, nil, c.fail(CloseProtocolError, "continuation without a message")
+			}
+		default:
+			return false, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+		if int64(len(message)+len(data)) > c.maxSize {
+			return false, nil, c.fail(CloseTooBig, "message too big")
+		}
+		message = append(message, data...)
+		if fin {
+			return text, message, nil
+		}
+	}
+}
+
+// fail closes the connection after a protocol error and returns the matching error.
+func (c *Conn) fail(code int, reason string) error {
+	_ = c.WriteClose(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// readFrame reads one frame and unmasks its payload. Client frames must be masked.
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	if c.readTimeout > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0F
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "reserved bits set"}
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "client frames must be masked"}
+	}
+
+	size := int64(head[1] & 0x7F)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = int64(binary.BigEndian.Uint64(ext[:]) & (1<<63 - 1))
+	}
+	if op >= opClose && (size > 125 || !fin) {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "invalid control frame"}
+	}
+	if size > c.maxSize {
+		return false, 0, nil, &CloseError{Code: CloseTooBig, Reason: "message too big"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteText sends a text message in one frame.
+func (c *Conn) WriteText(p []byte) error {
+	return c.write(opText, p)
+}
+
+// Ping sends a ping frame, which clients answer with a pong.
+func (c *Conn) Ping() error {
+	return c.write(opPing, nil)
+}
+
+// WriteClose sends a close frame, once; the connection accepts no other message afterwards.
+func (c *Conn) WriteClose(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	return c.write(opClose, append(payload, reason...))
+}
+
+// write sends one unmasked frame, as servers do.
+func (c *Conn) write(op byte, p []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	if op == opClose {
+		c.closeSent = true
+	}
+
+	frame := []byte{0x80 | op}
+	switch n := len(p); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = binary.BigEndian.AppendUint16(append(frame, 126), uint16(n))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, 127), uint64(n))
+	}
+	return c.flush(append(frame, p...))
+}
+
+// flush writes bytes within the write timeout. The caller holds the write lock.
+func (c *Conn) flush(p []byte) error {
+	if c.writeTimeout > 0 {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	if _, err := c.bw.Write(p); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
+// Close closes the underlying connection without a closing handshake.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
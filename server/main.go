@@ -1,11 +1,22 @@
 package main
 
 import (
+	"aggregator/internal/alerts"
+	"aggregator/internal/catalogue"
+	"aggregator/internal/changes"
+	"aggregator/internal/cli"
 	"aggregator/internal/config"
 	"aggregator/internal/handler"
 	"aggregator/internal/health"
+	"aggregator/internal/history"
+	"aggregator/internal/hooks"
+	"aggregator/internal/schedule"
+	"aggregator/internal/search"
+	"aggregator/internal/webhook"
+	"context"
 	"fmt"
 	"net/http"
+	"os"
 )
 
 // withCORS is a middleware that enables CORS support for the provided HTTP handler.
@@ -26,9 +37,93 @@ func withCORS(h http.Handler) http.Handler {
 }
 
 // main initializes the server, loads configuration, defines HTTP routes, and starts listening for incoming requests.
+// When command line arguments are given, it runs the matching CLI command instead of the server.
 func main() {
 	config.Load()
 
+	if len(os.Args) > 1 {
+		if err := cli.Run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The search index follows the catalogue, which is refreshed in the background
+	catalogue.Default.Subscribe(search.Default.Rebuild)
+
+	// Price history is recorded on every catalogue change, and kept in memory when no file is configured
+	if config.HISTORY_FILE != "" {
+		store, err := history.Open(config.HISTORY_FILE, config.HISTORY_RETENTION)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		history.Default = store
+	} else {
+		history.Default = history.New(config.HISTORY_RETENTION)
+	}
+	catalogue.Default.Subscribe(history.Default.Record)
+
+	// The change feed keeps the diff of the latest catalogue versions
+	changes.Default = changes.New(config.CHANGES_CAPACITY)
+	catalogue.Default.Subscribe(changes.Default.Record)
+
+	// Price alerts are evaluated on every catalogue change and notified by webhook
+	webhook.AllowPrivateNetworks(config.WEBHOOK_ALLOW_PRIVATE_NETWORKS)
+	webhookOptions := webhook.Options{MaxAttempts: config.WEBHOOK_MAX_ATTEMPTS}
+	if config.DEAD_LETTERS_FILE != "" {
+		d, err := webhook.Open(config.DEAD_LETTERS_FILE, webhookOptions)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		webhook.Default = d
+	} else {
+		webhook.Default = webhook.New(webhookOptions)
+	}
+	if config.ALERTS_FILE != "" {
+		store, err := alerts.Open(config.ALERTS_FILE, webhook.Default)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		alerts.Default = store
+	} else {
+		alerts.Default = alerts.New(webhook.Default)
+	}
+	catalogue.Default.Subscribe(alerts.Default.Record)
+	webhook.Default.SubscribeDeadLetters(alerts.Default.Rearm)
+
+	// Booking status, schedule and price changes found by the change feed are sent to the registered webhook endpoints
+	hooks.Default = hooks.New(webhook.Default)
+	if config.WEBHOOKS_FILE != "" {
+		store, err := hooks.Open(config.WEBHOOKS_FILE, webhook.Default)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		hooks.Default = store
+	}
+	changes.Default.Subscribe(hooks.Default.Record)
+
+	// Retimed bookings are kept for the schedule change report
+	if config.SCHEDULE_FILE != "" {
+		tracker, err := schedule.Open(config.SCHEDULE_FILE)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		schedule.Default = tracker
+	}
+	catalogue.Default.Subscribe(schedule.Default.Record)
+
+	// Requests read the catalogue, refreshed in the background so that no request waits for the providers
+	if config.REFRESH_INTERVAL > 0 {
+		go handler.RefreshPeriodically(context.Background(), config.REFRESH_INTERVAL)
+	}
+
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/health", health.HealthHandler)
@@ -37,8 +132,22 @@ func main() {
 	mux.HandleFunc("/flights/number/", handler.GetFlightByNumber)
 	mux.HandleFunc("/flights/passengerName/", handler.GetFlightsByPassenger)
 	mux.HandleFunc("/flights/destination", handler.GetFlightsByDestination)
+	mux.HandleFunc("/flights/price", handler.GetFlightsByPrice)
 	mux.HandleFunc("/flights/price/", handler.GetFlightsByPrice)
 	mux.HandleFunc("/flights/sorted", handler.GetFlightsSorted)
+	mux.HandleFunc("/flights/merged", handler.GetFlightsMerged)
+	mux.HandleFunc("/flights/stream", handler.GetFlightsStream)
+	mux.HandleFunc("/watch", handler.GetWatch)
+	mux.HandleFunc("/reports/reconciliation", handler.GetReconciliationReport)
+	mux.HandleFunc("/reports/schedule-changes", handler.GetScheduleChangesReport)
+	mux.HandleFunc("/search", handler.GetSearch)
+	mux.HandleFunc("/stats", handler.GetStats)
+	mux.HandleFunc("/changes", handler.GetChanges)
+	mux.HandleFunc("/routes/", handler.GetRoutes)
+	mux.HandleFunc("/alerts", handler.HandleAlerts)
+	mux.HandleFunc("/alerts/", handler.HandleAlerts)
+	mux.HandleFunc("/webhooks", handler.HandleWebhooks)
+	mux.HandleFunc("/webhooks/", handler.HandleWebhooks)
 
 	fmt.Println("Server running on :" + config.SERVER_PORT)
 	if err := http.ListenAndServe(":"+config.SERVER_PORT, withCORS(mux)); err != nil {